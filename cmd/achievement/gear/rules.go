@@ -0,0 +1,89 @@
+package gear
+
+import (
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/event"
+	"sort"
+	"time"
+)
+
+// Rule describes a badge and the events after which it is checked.
+type Rule struct {
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Events      []string `json:"-"`
+	// Streak is the number of consecutive logged days the rule needs.
+	Streak int `json:"-"`
+}
+
+const (
+	CodeFirstLog      = "first-log"
+	CodeProteinTarget = "protein-target"
+	CodeLost5Kg       = "lost-5kg"
+	CodeFirstBlog     = "first-blog"
+
+	// WeightLossGoal is how many kg CodeLost5Kg needs.
+	WeightLossGoal = 5
+)
+
+// Rules lists every badge, in display order.
+var Rules = []Rule{
+	{Code: CodeFirstLog, Name: "First Bite", Description: "Logged your first meal.", Events: []string{event.DiaryLogged}},
+	{Code: "streak-3", Name: "Warming Up", Description: "Logged food 3 days in a row.", Events: []string{event.DiaryLogged}, Streak: 3},
+	{Code: "streak-7", Name: "One Week Strong", Description: "Logged food 7 days in a row.", Events: []string{event.DiaryLogged}, Streak: 7},
+	{Code: "streak-30", Name: "Habit Formed", Description: "Logged food 30 days in a row.", Events: []string{event.DiaryLogged}, Streak: 30},
+	{Code: CodeProteinTarget, Name: "Protein Pro", Description: "Reached your daily protein target.", Events: []string{event.DiaryLogged}},
+	{Code: CodeLost5Kg, Name: "First 5 kg", Description: "Lost your first 5 kg.", Events: []string{event.WeightLogged}},
+	{Code: CodeFirstBlog, Name: "Storyteller", Description: "Published your first blog post.", Events: []string{event.BlogPublished}},
+}
+
+// Find returns the rule with code.
+func Find(code string) (Rule, bool) {
+	for _, r := range Rules {
+		if r.Code == code {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Streaks counts runs of consecutive dates (YYYY-MM-DD). The current streak
+// may end yesterday, since today may not be logged yet.
+func Streaks(dates []string, today string) (current int, longest int) {
+	sorted := append([]string{}, dates...)
+	sort.Strings(sorted)
+
+	logged := map[string]bool{}
+	run := 0
+	var prev time.Time
+	for _, d := range sorted {
+		day, err := time.Parse(authGear.DateLayout, d)
+		if err != nil || logged[d] {
+			continue
+		}
+		logged[d] = true
+		if run > 0 && day.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		prev = day
+	}
+
+	day, err := time.Parse(authGear.DateLayout, today)
+	if err != nil {
+		return 0, longest
+	}
+	if !logged[today] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for logged[day.Format(authGear.DateLayout)] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, longest
+}
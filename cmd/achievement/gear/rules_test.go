@@ -0,0 +1,115 @@
+package gear
+
+import (
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/event"
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	known := map[string]bool{event.DiaryLogged: true, event.WeightLogged: true, event.BlogPublished: true, event.BlogDeleted: true}
+
+	codes := map[string]bool{}
+	lastStreak := 0
+	for _, rule := range Rules {
+		if rule.Code == "" || rule.Name == "" || rule.Description == "" {
+			t.Errorf("rule %+v is missing its code, name or description", rule)
+		}
+		if codes[rule.Code] {
+			t.Errorf("code %q is used twice", rule.Code)
+		}
+		codes[rule.Code] = true
+
+		if len(rule.Events) == 0 {
+			t.Errorf("rule %q is never checked", rule.Code)
+		}
+		for _, e := range rule.Events {
+			if !known[e] {
+				t.Errorf("rule %q listens to unknown event %q", rule.Code, e)
+			}
+		}
+
+		if rule.Streak > 0 {
+			if len(rule.Events) != 1 || rule.Events[0] != event.DiaryLogged {
+				t.Errorf("streak rule %q must be checked after diary logs", rule.Code)
+			}
+			if rule.Streak <= lastStreak {
+				t.Errorf("streak rule %q (%d days) is listed after a longer one", rule.Code, rule.Streak)
+			}
+			lastStreak = rule.Streak
+		}
+	}
+
+	for _, code := range []string{CodeFirstLog, CodeProteinTarget, CodeLost5Kg, CodeFirstBlog} {
+		rule, ok := Find(code)
+		if !ok || rule.Code != code {
+			t.Errorf("Find(%q) = %+v, %v", code, rule, ok)
+		}
+	}
+	if _, ok := Find("streak-2"); ok {
+		t.Error("Find found an unknown code")
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	tests := []struct {
+		name    string
+		dates   []string
+		today   string
+		current int
+		longest int
+	}{
+		{name: "nothing logged", dates: nil, today: "2026-03-05", current: 0, longest: 0},
+		{name: "logged today", dates: []string{"2026-03-03", "2026-03-04", "2026-03-05"}, today: "2026-03-05", current: 3, longest: 3},
+		{name: "today not logged yet", dates: []string{"2026-03-03", "2026-03-04"}, today: "2026-03-05", current: 2, longest: 2},
+		{name: "missed yesterday", dates: []string{"2026-03-02", "2026-03-03"}, today: "2026-03-05", current: 0, longest: 2},
+		{
+			name:    "gap day",
+			dates:   []string{"2026-03-01", "2026-03-02", "2026-03-03", "2026-03-05", "2026-03-06"},
+			today:   "2026-03-06",
+			current: 2,
+			longest: 3,
+		},
+		{name: "unordered with duplicates", dates: []string{"2026-03-05", "2026-03-03", "2026-03-04", "2026-03-04"}, today: "2026-03-05", current: 3, longest: 3},
+		{name: "across months", dates: []string{"2026-02-27", "2026-02-28", "2026-03-01"}, today: "2026-03-01", current: 3, longest: 3},
+		{name: "across years", dates: []string{"2025-12-31", "2026-01-01"}, today: "2026-01-02", current: 2, longest: 2},
+		{name: "across a daylight saving change", dates: []string{"2026-03-07", "2026-03-08", "2026-03-09"}, today: "2026-03-09", current: 3, longest: 3},
+		{name: "invalid dates", dates: []string{"2026-03-04", "yesterday", "2026-03-05"}, today: "2026-03-05", current: 2, longest: 2},
+		{name: "invalid today", dates: []string{"2026-03-04", "2026-03-05"}, today: "", current: 0, longest: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := Streaks(tt.dates, tt.today)
+			if current != tt.current || longest != tt.longest {
+				t.Errorf("Streaks = %d, %d, want %d, %d", current, longest, tt.current, tt.longest)
+			}
+		})
+	}
+}
+
+// TestStreaksTimezone checks that the current streak ends at the user's
+// midnight, not at UTC's.
+func TestStreaksTimezone(t *testing.T) {
+	dates := []string{"2026-03-01", "2026-03-02"}
+
+	tests := []struct {
+		timezone string
+		at       time.Time
+		current  int
+	}{
+		// 23:30 on 3 March in Jakarta, so 2 March was yesterday
+		{timezone: "Asia/Jakarta", at: time.Date(2026, 3, 3, 16, 30, 0, 0, time.UTC), current: 2},
+		// 00:30 on 4 March in Jakarta while it is still 3 March in UTC
+		{timezone: "Asia/Jakarta", at: time.Date(2026, 3, 3, 17, 30, 0, 0, time.UTC), current: 0},
+		{timezone: "UTC", at: time.Date(2026, 3, 3, 17, 30, 0, 0, time.UTC), current: 2},
+		// 21:30 on 2 March in New York, which is already 3 March in UTC
+		{timezone: "America/New_York", at: time.Date(2026, 3, 3, 2, 30, 0, 0, time.UTC), current: 2},
+	}
+	for _, tt := range tests {
+		today := tt.at.In(authGear.LoadLocation(tt.timezone)).Format(authGear.DateLayout)
+		if current, _ := Streaks(dates, today); current != tt.current {
+			t.Errorf("%s at %v (%s): current = %d, want %d", tt.timezone, tt.at, today, current, tt.current)
+		}
+	}
+}
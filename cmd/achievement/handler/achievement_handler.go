@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"dietku-backend/cmd/achievement/gear"
+	"dietku-backend/cmd/achievement/repo"
+	authGear "dietku-backend/cmd/auth/gear"
+	diaryRepo "dietku-backend/cmd/diary/repo"
+	"dietku-backend/cmd/event"
+	"dietku-backend/cmd/log"
+	notificationGear "dietku-backend/cmd/notification/gear"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+type AchievementHandler struct {
+	repo      *repo.AchievementRepository
+	diaryRepo *diaryRepo.DiaryRepository
+	userRepo  *userRepo.UserRepository
+	notifier  *notificationGear.Notifier
+}
+
+func NewAchievementApi(e *echo.Echo, db *mongo.Database) *AchievementHandler {
+	a := &AchievementHandler{
+		repo:      repo.NewAchievementRepository(db),
+		diaryRepo: diaryRepo.NewDiaryRepository(db),
+		userRepo:  userRepo.NewUserRepository(db),
+		notifier:  notificationGear.NewNotifier(db),
+	}
+	if err := a.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create achievement indexes: %v", err)
+	}
+
+	event.Subscribe(event.DiaryLogged, a.onDiaryLogged)
+	event.Subscribe(event.WeightLogged, a.onWeightLogged)
+	event.Subscribe(event.BlogPublished, a.onBlogPublished)
+
+	e.GET("/api/user/:userId/badges", a.Badges)
+
+	aGroup := e.Group("")
+	aGroup.Use(authGear.IsLoggedIn(db))
+	{
+		aGroup.GET("/api/user/achievements", a.Achievements)
+	}
+	return a
+}
+
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+type BadgeStatus struct {
+	gear.Rule
+	Awarded   bool       `json:"awarded"`
+	AwardedAt *time.Time `json:"awardedAt,omitempty"`
+}
+
+type AchievementList struct {
+	Streak Streak        `json:"streak"`
+	Badges []BadgeStatus `json:"badges"`
+}
+
+// badgeStatuses pairs every rule with the user's badge for it, in rule order.
+// With awardedOnly the rules the user has not reached yet are left out.
+func badgeStatuses(badges repo.Badges, awardedOnly bool) []BadgeStatus {
+	awarded := map[string]time.Time{}
+	for _, b := range badges {
+		awarded[b.Code] = b.AwardedAt
+	}
+
+	statuses := []BadgeStatus{}
+	for _, rule := range gear.Rules {
+		status := BadgeStatus{Rule: rule}
+		if at, ok := awarded[rule.Code]; ok {
+			status.Awarded = true
+			status.AwardedAt = &at
+		} else if awardedOnly {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Achievements
+// @Tags Achievement
+// @Summary My Achievements
+// @Description Every badge with whether and when it was awarded, plus the diary logging streak.
+// @ID achievement-list
+// @Router /api/user/achievements [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *AchievementHandler) Achievements(c echo.Context) error {
+	tokenData := c.Get("me").(*authGear.UserClaims)
+
+	badges, err := h.repo.FindBadges(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting achievements.", c)
+	}
+
+	dates, err := h.diaryRepo.LoggedDates(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting diary.", c)
+	}
+
+	current, longest := gear.Streaks(dates, authGear.Today(tokenData.Location()))
+	return c.JSON(http.StatusOK, &AchievementList{
+		Streak: Streak{Current: current, Longest: longest},
+		Badges: badgeStatuses(*badges, false),
+	})
+}
+
+// Badges
+// @Tags Achievement
+// @Summary User Badges
+// @Description Public list of the badges a user has been awarded.
+// @ID achievement-badges
+// @Router /api/user/{userId}/badges [get]
+// @Param userId path string true "User ID"
+// @Produce json
+// @Success 200
+func (h *AchievementHandler) Badges(c echo.Context) error {
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id", c)
+	}
+
+	_, err = h.userRepo.FindOne(userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+
+	badges, err := h.repo.FindBadges(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting badges.", c)
+	}
+	return c.JSON(http.StatusOK, badgeStatuses(*badges, true))
+}
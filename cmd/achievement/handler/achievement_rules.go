@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"dietku-backend/cmd/achievement/gear"
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/event"
+	notificationRepo "dietku-backend/cmd/notification/repo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+func (h *AchievementHandler) award(userID primitive.ObjectID, code string, at time.Time) error {
+	awarded, err := h.repo.Award(userID, code, at)
+	if err != nil {
+		return err
+	}
+	if !awarded {
+		return nil
+	}
+
+	rule, _ := gear.Find(code)
+	_, err = h.notifier.Notify(userID, notificationRepo.TypeAchievement, "Badge earned: "+rule.Name, rule.Description, map[string]interface{}{
+		"code": code,
+	})
+	return err
+}
+
+// onDiaryLogged checks the logging and protein rules. Streak badges use the
+// longest streak, so back-filling old days counts as well.
+func (h *AchievementHandler) onDiaryLogged(e event.Event) error {
+	if err := h.award(e.UserID, gear.CodeFirstLog, e.At); err != nil {
+		return err
+	}
+
+	user, err := h.userRepo.FindOne(e.UserID)
+	if err != nil {
+		return err
+	}
+
+	dates, err := h.diaryRepo.LoggedDates(e.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, longest := gear.Streaks(dates, authGear.Today(authGear.LoadLocation(user.Timezone)))
+	for _, rule := range gear.Rules {
+		if rule.Streak > 0 && longest >= rule.Streak {
+			if err := h.award(e.UserID, rule.Code, e.At); err != nil {
+				return err
+			}
+		}
+	}
+
+	totals, err := h.diaryRepo.Totals(e.UserID, e.Date, e.Date)
+	if err != nil {
+		return err
+	}
+
+	target := user.NutritionTarget().ProteinGrams()
+	if len(totals.Days) > 0 && target > 0 && totals.Days[0].Nutrients.Protein >= target {
+		return h.award(e.UserID, gear.CodeProteinTarget, e.At)
+	}
+	return nil
+}
+
+// onWeightLogged compares the new weight with the first one we saw. Value
+// carries the weight before the change, which becomes the starting point when
+// no progress was recorded yet.
+func (h *AchievementHandler) onWeightLogged(e event.Event) error {
+	user, err := h.userRepo.FindOne(e.UserID)
+	if err != nil {
+		return err
+	}
+
+	progress, err := h.repo.FindProgress(e.UserID)
+	if err != nil {
+		return err
+	}
+
+	if progress.StartWeight == 0 {
+		progress.StartWeight = e.Value
+		if progress.StartWeight == 0 {
+			progress.StartWeight = user.Weight
+		}
+		progress.UpdatedAt = e.At
+		if err := h.repo.UpsertProgress(progress); err != nil {
+			return err
+		}
+	}
+
+	if user.Weight > 0 && progress.StartWeight-user.Weight >= gear.WeightLossGoal {
+		return h.award(e.UserID, gear.CodeLost5Kg, e.At)
+	}
+	return nil
+}
+
+func (h *AchievementHandler) onBlogPublished(e event.Event) error {
+	return h.award(e.UserID, gear.CodeFirstBlog, e.At)
+}
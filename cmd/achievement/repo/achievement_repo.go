@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Badge is an achievement awarded to a user. A unique index keeps one badge
+// per code and user.
+type Badge struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Code      string             `json:"code" bson:"code"`
+	AwardedAt time.Time          `json:"awardedAt" bson:"awardedAt"`
+}
+
+type Badges []Badge
+
+func DecodeAsBadges(cursor *mongo.Cursor) (*Badges, error) {
+	docs := Badges{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+// Progress keeps what the rules cannot derive from other collections, such as
+// the weight the user started from.
+type Progress struct {
+	UserID      primitive.ObjectID `json:"userId" bson:"userId"`
+	StartWeight float64            `json:"startWeight,omitempty" bson:"startWeight,omitempty"`
+	UpdatedAt   time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+type AchievementRepository struct {
+	coll         *mongo.Collection
+	progressColl *mongo.Collection
+}
+
+func NewAchievementRepository(db *mongo.Database) *AchievementRepository {
+	return &AchievementRepository{
+		coll:         db.Collection("badges"),
+		progressColl: db.Collection("achievement_progress"),
+	}
+}
+
+func (r *AchievementRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.progressColl.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *AchievementRepository) FindBadges(userID primitive.ObjectID) (*Badges, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "awardedAt", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsBadges(cursor)
+}
+
+// Award gives the badge to the user. It reports false when the user already
+// had it.
+func (r *AchievementRepository) Award(userID primitive.ObjectID, code string, at time.Time) (bool, error) {
+	_, err := r.coll.InsertOne(context.TODO(), &Badge{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Code:      code,
+		AwardedAt: at,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FindProgress returns the user's progress, or an empty one when nothing was
+// recorded yet.
+func (r *AchievementRepository) FindProgress(userID primitive.ObjectID) (*Progress, error) {
+	var d = &Progress{}
+	err := r.progressColl.FindOne(context.TODO(), bson.M{"userId": userID}).Decode(d)
+	if err == mongo.ErrNoDocuments {
+		return &Progress{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *AchievementRepository) UpsertProgress(progress *Progress) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.progressColl.ReplaceOne(context.TODO(), bson.M{"userId": progress.UserID}, progress, opts)
+	return err
+}
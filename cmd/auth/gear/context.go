@@ -19,6 +19,8 @@ type UserClaims struct {
 	Email     string             `json:"email" bson:"email"`
 	FirstName string             `json:"firstName" bson:"firstName"`
 	LastName  string             `json:"lastName" bson:"lastName"`
+	Timezone  string             `json:"timezone" bson:"timezone"`
+	Role      string             `json:"role" bson:"role"`
 }
 
 func GenerateToken(user *repo.User) (string, error) {
@@ -70,6 +72,37 @@ func IsLoggedIn(db *mongo.Database) echo.MiddlewareFunc {
 	}
 }
 
+// MaybeLoggedIn sets "me" like IsLoggedIn when a valid token is sent, but lets
+// anonymous requests through for endpoints that are public with extras for
+// signed in users.
+func MaybeLoggedIn(db *mongo.Database) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get("Authorization")
+			if header != "" {
+				loggedIn, err := CheckJWTClaims(db, header)
+				if err == nil {
+					c.Set("me", loggedIn)
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// IsAdmin lets signed in admins through and refuses everyone else.
+func IsAdmin(db *mongo.Database) echo.MiddlewareFunc {
+	isLoggedIn := IsLoggedIn(db)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return isLoggedIn(func(c echo.Context) error {
+			if me := c.Get("me").(*UserClaims); me.Role != repo.RoleAdmin {
+				return echo.ErrForbidden
+			}
+			return next(c)
+		})
+	}
+}
+
 func CheckJWTClaims(db *mongo.Database, header string) (*UserClaims, error) {
 	bearer := strings.Split(header, " ")
 	if len(bearer) != 2 {
@@ -105,6 +138,8 @@ func CheckJWTClaims(db *mongo.Database, header string) (*UserClaims, error) {
 			Email:     user.Email,
 			FirstName: user.FirstName,
 			LastName:  user.LastName,
+			Timezone:  user.Timezone,
+			Role:      user.Role,
 		}
 
 		return userClaims, nil
@@ -0,0 +1,22 @@
+package gear
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+)
+
+// QueryInt reads a positive integer query parameter of at most max, or
+// fallback when it is absent.
+func QueryInt(c echo.Context, name string, fallback int, max int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > max {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be between 1 and %d", name, max), c)
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+package gear
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpiredSignature = errors.New("signature expired")
+)
+
+// urlSigningKey is derived from the token key, so a signed URL cannot pass for
+// a token or the other way around.
+var urlSigningKey = func() []byte {
+	mac := hmac.New(sha256.New, mySigningKey)
+	mac.Write([]byte("signed-url"))
+	return mac.Sum(nil)
+}()
+
+func signature(path string, userID primitive.ObjectID, expires int64) string {
+	mac := hmac.New(sha256.New, urlSigningKey)
+	mac.Write([]byte(path + "\n" + userID.Hex() + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignURL returns path with a query granting the user in claims access until
+// ttl passes.
+func SignURL(path string, claims *UserClaims, ttl time.Duration) string {
+	expires := time.Now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("uid", claims.ID.Hex())
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", signature(path, claims.ID, expires))
+	return path + "?" + q.Encode()
+}
+
+// VerifyURL checks a query made by SignURL for path and returns the user it
+// was signed for.
+func VerifyURL(path string, query url.Values) (primitive.ObjectID, error) {
+	userID, err := primitive.ObjectIDFromHex(query.Get("uid"))
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidSignature
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidSignature
+	}
+
+	expected := signature(path, userID, expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("sig"))) {
+		return primitive.NilObjectID, ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return primitive.NilObjectID, ErrExpiredSignature
+	}
+	return userID, nil
+}
@@ -0,0 +1,63 @@
+package gear
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignURL(t *testing.T) {
+	claims := &UserClaims{ID: primitive.NewObjectID()}
+	signed := SignURL("/api/photos/1/file", claims, time.Minute)
+
+	path, rawQuery, _ := strings.Cut(signed, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := VerifyURL(path, query)
+	if err != nil || userID != claims.ID {
+		t.Fatalf("VerifyURL = %v, %v; want %v", userID, err, claims.ID)
+	}
+
+	if _, err := VerifyURL("/api/photos/2/file", query); err != ErrInvalidSignature {
+		t.Errorf("other path: err = %v, want ErrInvalidSignature", err)
+	}
+
+	other := url.Values{}
+	for k, v := range query {
+		other[k] = v
+	}
+	other.Set("uid", primitive.NewObjectID().Hex())
+	if _, err := VerifyURL(path, other); err != ErrInvalidSignature {
+		t.Errorf("other user: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestVerifyURLExpired(t *testing.T) {
+	claims := &UserClaims{ID: primitive.NewObjectID()}
+	signed := SignURL("/api/photos/1/file", claims, -time.Minute)
+
+	path, rawQuery, _ := strings.Cut(signed, "?")
+	query, _ := url.ParseQuery(rawQuery)
+	if _, err := VerifyURL(path, query); err != ErrExpiredSignature {
+		t.Fatalf("err = %v, want ErrExpiredSignature", err)
+	}
+}
+
+func TestSignURLDoesNotUseTokenKey(t *testing.T) {
+	userID := primitive.NewObjectID()
+	msg := "/api/photos/1/file\n" + userID.Hex() + "\n1700000000"
+
+	mac := hmac.New(sha256.New, mySigningKey)
+	mac.Write([]byte(msg))
+	if hex.EncodeToString(mac.Sum(nil)) == signature("/api/photos/1/file", userID, 1700000000) {
+		t.Fatal("URLs are signed with the token key")
+	}
+}
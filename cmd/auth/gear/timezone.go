@@ -0,0 +1,99 @@
+package gear
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+const (
+	DateLayout      = "2006-01-02"
+	DefaultTimezone = "Asia/Jakarta"
+)
+
+var ErrInvalidDate = errors.New("invalid date")
+
+// Location returns the user's time zone, falling back to DefaultTimezone when
+// none is set or it can no longer be loaded.
+func (u *UserClaims) Location() *time.Location {
+	return LoadLocation(u.Timezone)
+}
+
+// LoadLocation loads the time zone name, with the same fallbacks as
+// UserClaims.Location. It is used where only the stored user is at hand.
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today returns the current date in loc formatted with DateLayout.
+func Today(loc *time.Location) string {
+	return time.Now().In(loc).Format(DateLayout)
+}
+
+// ParseDay resolves a date path value ("today" or YYYY-MM-DD) into the start of
+// that day in loc and the start of the following day. Using the next midnight
+// rather than adding 24h keeps days across DST changes correct.
+func ParseDay(value string, loc *time.Location) (date string, start time.Time, end time.Time, err error) {
+	if value == "" || value == "today" {
+		value = Today(loc)
+	}
+	start, err = time.ParseInLocation(DateLayout, value, loc)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, ErrInvalidDate
+	}
+	end = start.AddDate(0, 0, 1)
+	return start.Format(DateLayout), start, end, nil
+}
+
+// IsValidTimezone reports whether name is a loadable IANA time zone.
+func IsValidTimezone(name string) bool {
+	if name == "" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// DateRange is the days from From to To, both included, with the start of
+// each in the user's time zone.
+type DateRange struct {
+	From      string
+	To        string
+	FromStart time.Time
+	ToStart   time.Time
+}
+
+// ParseRange reads the from and to query values into a DateRange in loc. To
+// defaults to today and from to defaultDays days ending at to; ranges longer
+// than maxDays days are refused.
+func ParseRange(c echo.Context, loc *time.Location, defaultDays int, maxDays int) (*DateRange, error) {
+	to, toStart, _, err := ParseDay(c.QueryParam("to"), loc)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, use YYYY-MM-DD", c)
+	}
+
+	fromStart := toStart.AddDate(0, 0, -(defaultDays - 1))
+	if c.QueryParam("from") != "" {
+		_, fromStart, _, err = ParseDay(c.QueryParam("from"), loc)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, use YYYY-MM-DD", c)
+		}
+	}
+
+	if fromStart.After(toStart) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "From must not be after to", c)
+	}
+	if !fromStart.AddDate(0, 0, maxDays).After(toStart) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The range covers at most %d days", maxDays), c)
+	}
+	return &DateRange{From: fromStart.Format(DateLayout), To: to, FromStart: fromStart, ToStart: toStart}, nil
+}
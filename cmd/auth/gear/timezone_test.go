@@ -0,0 +1,51 @@
+package gear
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		from, to string
+		status   int
+	}{
+		{name: "default days ending at to", query: "to=2026-03-10", from: "2026-03-04", to: "2026-03-10"},
+		{name: "single day", query: "from=2026-03-10&to=2026-03-10", from: "2026-03-10", to: "2026-03-10"},
+		{name: "exactly max days", query: "from=2026-03-01&to=2026-03-07", from: "2026-03-01", to: "2026-03-07"},
+		{name: "one day over max", query: "from=2026-03-01&to=2026-03-08", status: http.StatusBadRequest},
+		{name: "from after to", query: "from=2026-03-11&to=2026-03-10", status: http.StatusBadRequest},
+		{name: "invalid from", query: "from=2026-3-1&to=2026-03-10", status: http.StatusBadRequest},
+		{name: "invalid to", query: "to=tomorrow", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil), httptest.NewRecorder())
+
+			r, err := ParseRange(c, loc, 7, 7)
+			if tt.status != 0 {
+				he, ok := err.(*echo.HTTPError)
+				if !ok || he.Code != tt.status {
+					t.Fatalf("ParseRange(%s) = %+v, %v; want status %d", tt.query, r, err, tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRange(%s): %v", tt.query, err)
+			}
+			if r.From != tt.from || r.To != tt.to {
+				t.Errorf("ParseRange(%s) = %s..%s, want %s..%s", tt.query, r.From, r.To, tt.from, tt.to)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package handler
 import (
 	"context"
 	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/log"
+	notificationGear "dietku-backend/cmd/notification/gear"
+	notificationRepo "dietku-backend/cmd/notification/repo"
 	"dietku-backend/cmd/user/repo"
 	"dietku-backend/config"
 	"encoding/json"
@@ -19,14 +22,16 @@ import (
 )
 
 type AuthHandler struct {
-	repo *repo.UserRepository
-	conf *config.Config
+	repo     *repo.UserRepository
+	notifier *notificationGear.Notifier
+	conf     *config.Config
 }
 
 func NewAuthHandler(e *echo.Echo, db *mongo.Database, conf *config.Config) {
 	h := &AuthHandler{
-		repo: repo.NewUserRepository(db),
-		conf: conf,
+		repo:     repo.NewUserRepository(db),
+		notifier: notificationGear.NewNotifier(db),
+		conf:     conf,
 	}
 	e.POST("/api/login", h.Login)
 	e.POST("/api/register", h.Register)
@@ -111,6 +116,7 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+	h.welcome(u)
 
 	inserted, err := h.repo.FindOne(u.ID)
 	if err != nil {
@@ -120,6 +126,15 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	return c.JSON(http.StatusOK, inserted)
 }
 
+// welcome greets a new user. A failure only costs the greeting, so it does not
+// fail the registration.
+func (h *AuthHandler) welcome(u *repo.User) {
+	_, err := h.notifier.Notify(u.ID, notificationRepo.TypeWelcome, "Welcome to Dietku, "+u.FirstName+"!", "Set your targets and log your first meal to get started.", nil)
+	if err != nil {
+		log.Errorf("failed to notify new user %s: %v", u.ID.Hex(), err)
+	}
+}
+
 func (h *AuthHandler) loginGoogle(c echo.Context) error {
 	var oauthConfGl = &oauth2.Config{
 		ClientID:     h.conf.GoogleClientID,
@@ -211,6 +226,7 @@ func (h *AuthHandler) callbackGoogle(c echo.Context) error {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server exception: "+err.Error()).SetInternal(err)
 		}
+		h.welcome(user)
 
 		u = user
 	}
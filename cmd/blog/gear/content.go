@@ -0,0 +1,91 @@
+package gear
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+
+	// excerptLength is the longest excerpt in runes, ellipsis excluded.
+	excerptLength = 200
+	// wordsPerMinute is an average silent reading speed.
+	wordsPerMinute = 200
+)
+
+func IsValidFormat(format string) bool {
+	return format == FormatPlain || format == FormatMarkdown
+}
+
+// Rendered is what is derived from a post's source on save.
+type Rendered struct {
+	HTML        string
+	Excerpt     string
+	ReadingTime int
+}
+
+// Render turns content written in format into sanitized HTML, with the start
+// of its text as excerpt and the minutes it takes to read.
+func Render(content string, format string) Rendered {
+	var raw string
+	if format == FormatMarkdown {
+		raw = RenderMarkdown(content)
+	} else {
+		raw = RenderPlain(content)
+	}
+
+	safe := Sanitize(raw)
+	text := Text(safe)
+	words := len(strings.Fields(text))
+	return Rendered{
+		HTML:        safe,
+		Excerpt:     Excerpt(text, excerptLength),
+		ReadingTime: (words + wordsPerMinute - 1) / wordsPerMinute,
+	}
+}
+
+// Text returns the text of an HTML fragment, with whitespace between blocks
+// and images replaced by their alt text.
+func Text(fragment string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.TextToken:
+			b.WriteString(tok.Data)
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			if tok.DataAtom == atom.Img && tt != html.EndTagToken {
+				b.WriteString(" " + attr(tok, "alt") + " ")
+			} else if !inline[tok.DataAtom] {
+				b.WriteString(" ")
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+var inline = map[atom.Atom]bool{
+	atom.A: true, atom.Strong: true, atom.B: true, atom.Em: true, atom.I: true, atom.Del: true, atom.Code: true,
+}
+
+// Excerpt shortens text to at most max runes, cutting at a word boundary and
+// marking the cut with an ellipsis.
+func Excerpt(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	cut := string(runes[:max])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
@@ -0,0 +1,135 @@
+package gear
+
+import "strings"
+
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+
+	// maxDiffLines bounds the quadratic time of comparing the changed middle
+	// of two texts; longer ones are compared as a single replaced block.
+	maxDiffLines = 3000
+)
+
+// Change is one run of lines that are equal in both texts, only in the new
+// one (insert) or only in the old one (delete).
+type Change struct {
+	Op    string   `json:"op"`
+	Lines []string `json:"lines"`
+}
+
+// Diff compares old and new line by line through their longest common
+// subsequence and returns the changes turning old into new. The lines both
+// start and end with are set aside first, and the rest is compared with
+// Hirschberg's algorithm, which needs memory linear in the length of the texts.
+func Diff(old string, new string) []Change {
+	a, b := splitLines(old), splitLines(new)
+
+	changes := []Change{}
+	add := func(op string, lines ...string) {
+		if len(lines) == 0 {
+			return
+		}
+		if n := len(changes); n > 0 && changes[n-1].Op == op {
+			changes[n-1].Lines = append(changes[n-1].Lines, lines...)
+			return
+		}
+		changes = append(changes, Change{Op: op, Lines: append([]string{}, lines...)})
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	add(OpEqual, a[:prefix]...)
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA) > maxDiffLines || len(midB) > maxDiffLines {
+		add(OpDelete, midA...)
+		add(OpInsert, midB...)
+	} else {
+		diffLines(midA, midB, add)
+	}
+	add(OpEqual, a[len(a)-suffix:]...)
+	return changes
+}
+
+// diffLines adds the changes turning a into b, splitting a in half and b where
+// the halves' common subsequences together are longest, then recursing.
+func diffLines(a []string, b []string, add func(op string, lines ...string)) {
+	switch {
+	case len(a) == 0:
+		add(OpInsert, b...)
+		return
+	case len(b) == 0:
+		add(OpDelete, a...)
+		return
+	case len(a) == 1:
+		for j := range b {
+			if b[j] == a[0] {
+				add(OpInsert, b[:j]...)
+				add(OpEqual, a[0])
+				add(OpInsert, b[j+1:]...)
+				return
+			}
+		}
+		add(OpDelete, a[0])
+		add(OpInsert, b...)
+		return
+	}
+
+	mid := len(a) / 2
+	head := lcsLengths(a[:mid], b, false)
+	tail := lcsLengths(a[mid:], b, true)
+
+	split, best := 0, -1
+	for j := 0; j <= len(b); j++ {
+		if n := head[j] + tail[len(b)-j]; n > best {
+			split, best = j, n
+		}
+	}
+
+	diffLines(a[:mid], b[:split], add)
+	diffLines(a[mid:], b[split:], add)
+}
+
+// lcsLengths returns, for every j, the length of the longest common
+// subsequence of a and the first j lines of b, or with reverse the last j
+// lines of a and b. Only two rows of the usual table are kept.
+func lcsLengths(a []string, b []string, reverse bool) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		x := a[i]
+		if reverse {
+			x = a[len(a)-1-i]
+		}
+		for j := 1; j <= len(b); j++ {
+			y := b[j-1]
+			if reverse {
+				y = b[len(b)-j]
+			}
+			switch {
+			case x == y:
+				cur[j] = prev[j-1] + 1
+			case prev[j] >= cur[j-1]:
+				cur[j] = prev[j]
+			default:
+				cur[j] = cur[j-1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
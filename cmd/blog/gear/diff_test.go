@@ -0,0 +1,124 @@
+package gear
+
+import (
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds the old and new texts from changes.
+func apply(changes []Change) (string, string, int) {
+	var old, new []string
+	equal := 0
+	for _, c := range changes {
+		if c.Op != OpInsert {
+			old = append(old, c.Lines...)
+		}
+		if c.Op != OpDelete {
+			new = append(new, c.Lines...)
+		}
+		if c.Op == OpEqual {
+			equal += len(c.Lines)
+		}
+	}
+	return strings.Join(old, "\n"), strings.Join(new, "\n"), equal
+}
+
+// lcsLength is the textbook quadratic table the diff has to agree with.
+func lcsLength(a []string, b []string) int {
+	t := make([][]int, len(a)+1)
+	for i := range t {
+		t[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				t[i][j] = t[i+1][j+1] + 1
+			} else {
+				t[i][j] = max(t[i+1][j], t[i][j+1])
+			}
+		}
+	}
+	return t[0][0]
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     []Change
+	}{
+		{"", "", []Change{}},
+		{"a\nb", "a\nb", []Change{{OpEqual, []string{"a", "b"}}}},
+		{"", "a", []Change{{OpInsert, []string{"a"}}}},
+		{"a\nb\nc", "a\nc", []Change{{OpEqual, []string{"a"}}, {OpDelete, []string{"b"}}, {OpEqual, []string{"c"}}}},
+		{"a\nb\nc", "a\nx\nc", []Change{{OpEqual, []string{"a"}}, {OpDelete, []string{"b"}}, {OpInsert, []string{"x"}}, {OpEqual, []string{"c"}}}},
+		{"a\r\nb", "a\nb", []Change{{OpEqual, []string{"a", "b"}}}},
+	}
+	for _, tt := range tests {
+		got := Diff(tt.old, tt.new)
+		if len(got) != len(tt.want) {
+			t.Errorf("Diff(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Op != tt.want[i].Op || strings.Join(got[i].Lines, "\n") != strings.Join(tt.want[i].Lines, "\n") {
+				t.Errorf("Diff(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDiffIsALongestCommonSubsequence(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	text := func() string {
+		lines := make([]string, rnd.Intn(30))
+		for i := range lines {
+			lines[i] = strconv.Itoa(rnd.Intn(5))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for n := 0; n < 500; n++ {
+		old, new := text(), text()
+		changes := Diff(old, new)
+		gotOld, gotNew, equal := apply(changes)
+		if gotOld != old || gotNew != new {
+			t.Fatalf("Diff(%q, %q) rebuilds %q and %q", old, new, gotOld, gotNew)
+		}
+		if want := lcsLength(splitLines(old), splitLines(new)); equal != want {
+			t.Fatalf("Diff(%q, %q) keeps %d lines, want %d", old, new, equal, want)
+		}
+		for i := 1; i < len(changes); i++ {
+			if changes[i].Op == changes[i-1].Op {
+				t.Fatalf("Diff(%q, %q) has two %s runs in a row", old, new, changes[i].Op)
+			}
+		}
+	}
+}
+
+func TestDiffLongTexts(t *testing.T) {
+	lines := make([]string, maxDiffLines)
+	for i := range lines {
+		lines[i] = "line " + strconv.Itoa(i)
+	}
+	old := strings.Join(lines, "\n")
+	lines[10], lines[maxDiffLines-10] = "changed", "changed"
+	new := strings.Join(lines, "\n")
+
+	changes := Diff(old, new)
+	if _, _, equal := apply(changes); equal != maxDiffLines-2 {
+		t.Errorf("kept %d lines, want %d", equal, maxDiffLines-2)
+	}
+
+	// a full table for these would take maxDiffLines² ints
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	Diff(old, new)
+	runtime.ReadMemStats(&after)
+	if allocs := after.TotalAlloc - before.TotalAlloc; allocs > 8<<20 {
+		t.Errorf("diff of %d lines allocates %d bytes", maxDiffLines, allocs)
+	}
+}
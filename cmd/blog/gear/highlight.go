@@ -0,0 +1,136 @@
+package gear
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// snippetRadius is how many runes a snippet keeps around the first match.
+	snippetRadius = 80
+	markOpen      = "<mark>"
+	markClose     = "</mark>"
+)
+
+// stem cuts a term down to what its inflected forms share, so "diets" also
+// marks "diet" and "dieting"; Mongo matches those through stemming.
+func stem(term string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if strings.HasSuffix(term, suffix) && len([]rune(term))-len([]rune(suffix)) >= 3 {
+			return strings.TrimSuffix(term, suffix)
+		}
+	}
+	return term
+}
+
+func matches(word string, stems []string) bool {
+	for _, s := range stems {
+		if strings.HasPrefix(word, s) {
+			return true
+		}
+	}
+	return false
+}
+
+type token struct {
+	start, end int
+	match      bool
+}
+
+// tokenize finds the words of text as rune offsets and marks those matching
+// a stem.
+func tokenize(text []rune, stems []string) []token {
+	tokens := []token{}
+	for i := 0; i < len(text); {
+		if !unicode.IsLetter(text[i]) && !unicode.IsDigit(text[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && (unicode.IsLetter(text[j]) || unicode.IsDigit(text[j])) {
+			j++
+		}
+		word := strings.ToLower(string(text[i:j]))
+		tokens = append(tokens, token{start: i, end: j, match: matches(word, stems)})
+		i = j
+	}
+	return tokens
+}
+
+// mark escapes text[from:to] as HTML and wraps the matching words in <mark>.
+func mark(text []rune, tokens []token, from int, to int) string {
+	var b strings.Builder
+	pos := from
+	for _, t := range tokens {
+		if !t.match || t.start < from || t.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(text[pos:t.start])))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(string(text[t.start:t.end])))
+		b.WriteString(markClose)
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(string(text[pos:to])))
+	return b.String()
+}
+
+// Highlight returns the whole of text, HTML escaped, with every word matching
+// terms wrapped in <mark>.
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return mark(runes, tokenize(runes, stems(terms)), 0, len(runes))
+}
+
+// Snippet returns an HTML escaped excerpt of text around the first match of
+// terms, with the matches wrapped in <mark> and cuts shown as "…". Without a
+// match it is the start of text.
+func Snippet(text string, terms []string) string {
+	runes := []rune(text)
+	tokens := tokenize(runes, stems(terms))
+
+	center := 0
+	for _, t := range tokens {
+		if t.match {
+			center = t.start
+			break
+		}
+	}
+
+	from := center - snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	to := from + 2*snippetRadius
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	// do not cut words in half
+	for _, t := range tokens {
+		if t.start < from && t.end > from {
+			from = t.end
+		}
+		if t.start < to && t.end > to {
+			to = t.start
+		}
+	}
+
+	snippet := strings.TrimSpace(mark(runes, tokens, from, to))
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func stems(terms []string) []string {
+	s := make([]string, len(terms))
+	for i, t := range terms {
+		s[i] = stem(strings.ToLower(t))
+	}
+	return s
+}
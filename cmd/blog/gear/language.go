@@ -0,0 +1,78 @@
+package gear
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	LanguageIndonesian = "id"
+	LanguageEnglish    = "en"
+)
+
+var stopwords = map[string]map[string]bool{
+	LanguageIndonesian: set("yang", "dan", "di", "ke", "dari", "ini", "itu", "untuk", "dengan", "pada", "adalah", "akan", "tidak", "juga", "atau", "ada", "dalam", "bisa", "saya", "kita", "anda", "karena", "agar", "lebih", "sudah", "oleh", "sebagai", "setiap", "bagi", "para"),
+	LanguageEnglish:    set("the", "and", "of", "to", "in", "is", "it", "for", "with", "on", "that", "this", "are", "be", "as", "at", "by", "or", "your", "you", "from", "an", "a", "can", "will", "not", "have", "has", "was", "more"),
+}
+
+func set(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+func IsValidLanguage(lang string) bool {
+	_, ok := stopwords[lang]
+	return ok
+}
+
+// Words splits text into lower case words.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// DetectLanguage guesses whether text is Indonesian or English by counting
+// stop words. Ties, including text without any, count as Indonesian.
+func DetectLanguage(text string) string {
+	id, en := 0, 0
+	for _, w := range Words(text) {
+		if stopwords[LanguageIndonesian][w] {
+			id++
+		}
+		if stopwords[LanguageEnglish][w] {
+			en++
+		}
+	}
+	if en > id {
+		return LanguageEnglish
+	}
+	return LanguageIndonesian
+}
+
+// SearchLanguage maps lang to the language of the Mongo text index. Mongo
+// cannot stem Indonesian, so it is indexed without stemming or stop words.
+func SearchLanguage(lang string) string {
+	if lang == LanguageEnglish {
+		return "english"
+	}
+	return "none"
+}
+
+// Terms returns the words of a search query without the stop words of lang,
+// which Mongo only drops for languages it knows.
+func Terms(query string, lang string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, w := range Words(query) {
+		if stopwords[lang][w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
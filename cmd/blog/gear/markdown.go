@@ -0,0 +1,423 @@
+package gear
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxNesting bounds how deep block quotes and lists, and links and emphasis
+// within a line, may nest; deeper markers are rendered as text.
+const maxNesting = 8
+
+var (
+	headingLine = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	ruleLine    = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceLine   = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([A-Za-z0-9_+-]*)")
+	bulletItem  = regexp.MustCompile(`^ {0,3}([-*+])[ \t]+(.*)$`)
+	orderedItem = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+(.*)$`)
+	quoteLine   = regexp.MustCompile(`^ {0,3}>[ \t]?(.*)$`)
+	blankLines  = regexp.MustCompile(`\n[ \t]*\n\s*`)
+)
+
+// RenderMarkdown turns Markdown into HTML. It covers what posts need:
+// headings, paragraphs, emphasis, links, images, code, block quotes, lists and
+// rules. Raw HTML is not supported and comes out escaped; the result is still
+// meant to go through Sanitize.
+func RenderMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), 0)
+	return b.String()
+}
+
+func renderBlocks(b *strings.Builder, lines []string, depth int) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>")
+			b.WriteString(renderLines(paragraph))
+			b.WriteString("</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case fenceLine.MatchString(line):
+			flush()
+			m := fenceLine.FindStringSubmatch(line)
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code")
+			if m[2] != "" {
+				b.WriteString(` class="language-` + strings.ToLower(m[2]) + `"`)
+			}
+			b.WriteString(">")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>\n")
+
+		case headingLine.MatchString(line):
+			flush()
+			m := headingLine.FindStringSubmatch(line)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+
+		case ruleLine.MatchString(line):
+			flush()
+			b.WriteString("<hr>\n")
+
+		case depth < maxNesting && quoteLine.MatchString(line):
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				m := quoteLine.FindStringSubmatch(lines[i])
+				if m == nil {
+					break
+				}
+				quoted = append(quoted, m[1])
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted, depth+1)
+			b.WriteString("</blockquote>\n")
+
+		case depth < maxNesting && (bulletItem.MatchString(line) || orderedItem.MatchString(line)):
+			flush()
+			i = renderList(b, lines, i, depth) - 1
+
+		default:
+			paragraph = append(paragraph, trimmed)
+			// renderLines needs the trailing spaces of a hard break
+			if strings.HasSuffix(line, "  ") {
+				paragraph[len(paragraph)-1] += "  "
+			}
+		}
+	}
+	flush()
+}
+
+// renderList renders the list starting at lines[start] and returns the index
+// of the first line after it. Lines indented below an item belong to it.
+func renderList(b *strings.Builder, lines []string, start int, depth int) int {
+	ordered := orderedItem.MatchString(lines[start])
+	item := bulletItem
+	tag := "ul"
+	if ordered {
+		item, tag = orderedItem, "ol"
+	}
+
+	b.WriteString("<" + tag + ">\n")
+	i := start
+	for i < len(lines) {
+		m := item.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		body := []string{m[2]}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// a blank line only continues the item when indented text follows
+				if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "  ") {
+					body = append(body, "")
+					continue
+				}
+				break
+			}
+			if strings.HasPrefix(line, "  ") {
+				body = append(body, strings.TrimPrefix(strings.TrimPrefix(line, "  "), "  "))
+				continue
+			}
+			if item.MatchString(line) || bulletItem.MatchString(line) || orderedItem.MatchString(line) {
+				break
+			}
+			// lazy continuation of the item's paragraph
+			body = append(body, strings.TrimSpace(line))
+		}
+
+		var inner strings.Builder
+		renderBlocks(&inner, body, depth+1)
+		content := strings.TrimSuffix(inner.String(), "\n")
+		// a single paragraph item is written without its <p>
+		if strings.HasPrefix(content, "<p>") && strings.Count(content, "<p>") == 1 && strings.HasSuffix(content, "</p>") {
+			content = strings.TrimSuffix(strings.TrimPrefix(content, "<p>"), "</p>")
+		}
+		b.WriteString("<li>" + content + "</li>\n")
+
+		// skip the blank lines between items of the same list
+		next := i
+		for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+			next++
+		}
+		if next < len(lines) && item.MatchString(lines[next]) {
+			i = next
+		}
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderLines renders the lines of a paragraph, turning lines ending in two
+// spaces or a backslash into hard breaks.
+func renderLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		hard := strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")
+		line = strings.TrimRight(line, " ")
+		if strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\") {
+			line = strings.TrimSuffix(line, "\\")
+		}
+		b.WriteString(renderInline(line))
+		if i < len(lines)-1 {
+			if hard {
+				b.WriteString("<br>")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+var (
+	linkTail = regexp.MustCompile(`^\(\s*(<[^<>\n]*>|[^\s()]*(?:\([^\s()]*\)[^\s()]*)*)(?:\s+"([^"]*)")?\s*\)`)
+	autolink = regexp.MustCompile(`^<((?:https?://|mailto:)[^\s<>]+)>`)
+)
+
+// maxLinkTail bounds how far past its "]" the (url "title") of a link may
+// reach.
+const maxLinkTail = 2048
+
+// emphasisMarkers are tried in order, so doubled markers win over single ones.
+var emphasisMarkers = []struct{ marker, tag string }{
+	{"**", "strong"}, {"__", "strong"}, {"~~", "del"}, {"*", "em"}, {"_", "em"},
+}
+
+// spans renders the spans of one line. Where each bracket, backtick run and
+// emphasis marker may close is found in a single pass up front, so rendering
+// never searches the rest of the line again and takes linear time.
+type spans struct {
+	s string
+	b strings.Builder
+	// brackets maps each "[" to its "]"
+	brackets map[int]int
+	// ticks lists, by length, where the runs of backticks start
+	ticks map[int][]int
+	// closers lists, for each of emphasisMarkers, where it can close a span
+	closers [][]int
+}
+
+func newSpans(s string) *spans {
+	in := &spans{s: s, brackets: map[int]int{}, ticks: map[int][]int{}, closers: make([][]int, len(emphasisMarkers))}
+
+	var open []int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			open = append(open, i)
+		case ']':
+			if n := len(open); n > 0 {
+				in.brackets[open[n-1]] = i
+				open = open[:n-1]
+			}
+		}
+	}
+
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+		in.ticks[run] = append(in.ticks[run], i)
+		i += run
+	}
+
+	// a closing marker follows text, a closing _ is not followed by a letter,
+	// and single markers must not be half of a double one
+	for d, m := range emphasisMarkers {
+		for i := 1; i < len(s); i++ {
+			if !strings.HasPrefix(s[i:], m.marker) || s[i-1] == ' ' {
+				continue
+			}
+			after := i + len(m.marker)
+			if m.marker[0] == '_' && after < len(s) && isWordByte(s[after]) {
+				continue
+			}
+			if len(m.marker) == 1 && strings.HasPrefix(s[i:], m.marker+m.marker) {
+				continue
+			}
+			in.closers[d] = append(in.closers[d], i)
+		}
+	}
+	return in
+}
+
+// renderInline renders the spans of one line. Unmatched markers are text.
+func renderInline(s string) string {
+	in := newSpans(s)
+	in.render(0, len(s), 0)
+	return in.b.String()
+}
+
+// render renders s[from:to], a span nested depth links or emphasis deep.
+func (in *spans) render(from int, to int, depth int) {
+	s, b := in.s, &in.b
+	text := func(t string) { b.WriteString(html.EscapeString(t)) }
+
+	for i := from; i < to; {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < to && strings.IndexByte("\\`*_{}[]()#+-.!~<>|\"'", s[i+1]) >= 0:
+			text(s[i+1 : i+2])
+			i += 2
+			continue
+
+		case c == '`':
+			run := len(s[i:to]) - len(strings.TrimLeft(s[i:to], "`"))
+			if end, ok := in.closingTicks(i+run, run, to); ok {
+				code := strings.TrimSpace(s[i+run : end])
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i = end + run
+				continue
+			}
+			text(s[i : i+run])
+			i += run
+			continue
+
+		case c == '!' && i+1 < to && s[i+1] == '[':
+			if labelEnd, url, title, end, ok := in.link(i+1, to); ok {
+				b.WriteString(`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(plainText(s[i+2:labelEnd])) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + html.EscapeString(title) + `"`)
+				}
+				b.WriteString(">")
+				i = end
+				continue
+			}
+
+		case c == '[' && depth < maxNesting:
+			if labelEnd, url, title, end, ok := in.link(i, to); ok {
+				b.WriteString(`<a href="` + html.EscapeString(url) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + html.EscapeString(title) + `"`)
+				}
+				b.WriteString(">")
+				in.render(i+1, labelEnd, depth+1)
+				b.WriteString("</a>")
+				i = end
+				continue
+			}
+
+		case c == '<':
+			if m := autolink.FindStringSubmatch(s[i:to]); m != nil {
+				b.WriteString(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(strings.TrimPrefix(m[1], "mailto:")) + "</a>")
+				i += len(m[0])
+				continue
+			}
+
+		case depth < maxNesting && (c == '*' || c == '~' || (c == '_' && (i == from || !isWordByte(s[i-1])))):
+			if innerFrom, innerTo, end, tag, ok := in.emphasis(i, to); ok {
+				b.WriteString("<" + tag + ">")
+				in.render(innerFrom, innerTo, depth+1)
+				b.WriteString("</" + tag + ">")
+				i = end
+				continue
+			}
+		}
+
+		text(s[i : i+1])
+		i++
+	}
+}
+
+// closingTicks finds the first run of exactly run backticks starting at or
+// after from and ending by to.
+func (in *spans) closingTicks(from int, run int, to int) (int, bool) {
+	starts := in.ticks[run]
+	k := sort.SearchInts(starts, from)
+	if k == len(starts) || starts[k]+run > to {
+		return 0, false
+	}
+	return starts[k], true
+}
+
+// link parses "[label](url "title")" starting at the "[" at open and returns
+// where the label ends and the link does.
+func (in *spans) link(open int, to int) (labelEnd int, url, title string, end int, ok bool) {
+	closing, found := in.brackets[open]
+	if !found || closing >= to {
+		return 0, "", "", 0, false
+	}
+	tail := in.s[closing+1 : min(to, closing+1+maxLinkTail)]
+	m := linkTail.FindStringSubmatch(tail)
+	if m == nil {
+		return 0, "", "", 0, false
+	}
+	url = strings.TrimSuffix(strings.TrimPrefix(m[1], "<"), ">")
+	return closing, url, m[2], closing + 1 + len(m[0]), true
+}
+
+// emphasis parses a strong (** or __), emphasised (* or _) or struck through
+// (~~) span starting at i and returns the bounds of its content and its end.
+func (in *spans) emphasis(i int, to int) (innerFrom, innerTo, end int, tag string, ok bool) {
+	s := in.s
+	for d, m := range emphasisMarkers {
+		if !strings.HasPrefix(s[i:to], m.marker) {
+			continue
+		}
+		rest := s[i+len(m.marker) : to]
+		if rest == "" || rest[0] == ' ' || strings.HasPrefix(rest, m.marker) {
+			continue
+		}
+		innerFrom = i + len(m.marker)
+		closers := in.closers[d]
+		k := sort.SearchInts(closers, innerFrom+1)
+		if k < len(closers) && closers[k]+len(m.marker) <= to {
+			return innerFrom, closers[k], closers[k] + len(m.marker), m.tag, true
+		}
+	}
+	return 0, 0, 0, "", false
+}
+
+// isWordByte tells letters and digits, around which _ does not emphasise, so
+// snake_case_names stay as they are.
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// plainText drops the markers from inline Markdown, for image alt text.
+func plainText(s string) string {
+	return strings.NewReplacer("*", "", "_", "", "`", "", "~", "", "[", "", "]", "").Replace(s)
+}
+
+// RenderPlain renders plain text as HTML: blank lines separate paragraphs and
+// other line breaks are kept.
+func RenderPlain(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var b strings.Builder
+	for _, paragraph := range blankLines.Split(strings.TrimSpace(src), -1) {
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
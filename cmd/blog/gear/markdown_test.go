@@ -0,0 +1,157 @@
+package gear
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdownInline(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"**bold** and *em*", "<p><strong>bold</strong> and <em>em</em></p>\n"},
+		{"__bold__ _em_ ~~gone~~", "<p><strong>bold</strong> <em>em</em> <del>gone</del></p>\n"},
+		{"snake_case_name", "<p>snake_case_name</p>\n"},
+		{"a * b * c", "<p>a * b * c</p>\n"},
+		{"*unclosed", "<p>*unclosed</p>\n"},
+		{"`a*b*` and ``a`b``", "<p><code>a*b*</code> and <code>a`b</code></p>\n"},
+		{"``not closed`", "<p>``not closed`</p>\n"},
+		{`\*literal\*`, "<p>*literal*</p>\n"},
+		{"[a *b*](/x \"t\")", `<p><a href="/x" title="t">a <em>b</em></a></p>` + "\n"},
+		{"[[a](/in)](/out)", `<p><a href="/out"><a href="/in">a</a></a></p>` + "\n"},
+		{"[a] [b](/b)", `<p>[a] <a href="/b">b</a></p>` + "\n"},
+		{"![an *image*](/i.png)", `<p><img src="/i.png" alt="an image"></p>` + "\n"},
+		{"<https://example.com>", `<p><a href="https://example.com">https://example.com</a></p>` + "\n"},
+		{"# Title *x*", "<h1>Title <em>x</em></h1>\n"},
+	}
+	for _, tt := range tests {
+		if got := RenderMarkdown(tt.src); got != tt.want {
+			t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMarkdownNestingIsBounded(t *testing.T) {
+	src := strings.Repeat("[", maxNesting+2) + "a" + strings.Repeat("](/u)", maxNesting+2)
+	got := RenderMarkdown(src)
+	if n := strings.Count(got, "<a "); n != maxNesting {
+		t.Errorf("rendered %d nested links, want %d: %s", n, maxNesting, got)
+	}
+}
+
+func TestRenderMarkdownXSS(t *testing.T) {
+	tests := []struct{ name, src string }{
+		{"javascript link", "[click](javascript:alert(1))"},
+		{"javascript link with case and spaces", "[click]( JaVaScRiPt:alert(1) )"},
+		{"javascript image", "![x](javascript:alert(1))"},
+		{"data image", "![x](data:text/html;base64,PHNjcmlwdD4=)"},
+		{"vbscript link", "[x](vbscript:msgbox)"},
+		{"raw script", "<script>alert(1)</script>"},
+		{"raw handler", `<img src=x onerror="alert(1)">`},
+		{"raw iframe", `<iframe src="https://evil.example"></iframe>`},
+		{"quote breaking href", `[x](/a"onmouseover="alert(1))`},
+		{"quote breaking title", `[x](/a "t"onmouseover=alert(1))`},
+		{"quote breaking alt", `![a" onerror="alert(1)](/i.png)`},
+		{"quote breaking code class", "```js\" onclick=\"alert(1)\n1\n```"},
+		{"autolink with javascript", "<javascript:alert(1)>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.src, FormatMarkdown).HTML
+			if err := unsafeMarkup(got); err != "" {
+				t.Errorf("Render(%q) = %q: %s", tt.src, got, err)
+			}
+		})
+	}
+}
+
+// unsafeMarkup describes the first element, attribute or URL of fragment a
+// browser could run script from, or returns "" when there is none. Escaped
+// markup is text and harmless.
+func unsafeMarkup(fragment string) string {
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return ""
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		allowed, ok := allowedTags[tok.DataAtom]
+		if !ok {
+			return "element " + tok.Data
+		}
+		for _, a := range tok.Attr {
+			if a.Key == "rel" && tok.DataAtom == atom.A {
+				continue
+			}
+			if !slices.Contains(allowed, a.Key) {
+				return "attribute " + a.Key + " on " + tok.Data
+			}
+			if (a.Key == "href" || a.Key == "src") && !safeURL(a.Val, a.Key == "href") {
+				return "URL " + a.Val
+			}
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct{ name, src, want string }{
+		{"keeps allowed markup", "<p><strong>a</strong></p>", "<p><strong>a</strong></p>"},
+		{"drops unknown tags, keeps text", "<div><span>a</span></div>", "a"},
+		{"drops scripts with content", "a<script>alert(1)</script>b", "ab"},
+		{"drops event handlers", `<p onclick="x()">a</p>`, "<p>a</p>"},
+		{"drops javascript links", `<a href="javascript:alert(1)">a</a>`, `<a rel="nofollow noopener noreferrer">a</a>`},
+		{"drops protocol relative links", `<a href="//evil.example">a</a>`, `<a rel="nofollow noopener noreferrer">a</a>`},
+		{"keeps safe links", `<a href="https://x.example/?a=1&b=2">a</a>`, `<a href="https://x.example/?a=1&amp;b=2" rel="nofollow noopener noreferrer">a</a>`},
+		{"drops images with unsafe sources", `<img src="data:image/png;base64,AA">`, ""},
+		{"escapes attribute values", `<img src="/a.png" alt="&quot;><script>">`, `<img src="/a.png" alt="&#34;&gt;&lt;script&gt;">`},
+		{"escapes text", "<p>1 &lt; 2</p>", "<p>1 &lt; 2</p>"},
+		{"drops bad code classes", `<code class="x y">a</code>`, "<code>a</code>"},
+		{"closes open elements", "<p><em>a", "<p><em>a</em></p>"},
+		{"drops stray end tags", "a</p></em>", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.src); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenderMarkdownPathological renders inputs of the largest allowed size
+// that made the old renderer search the rest of the line from every marker.
+func TestRenderMarkdownPathological(t *testing.T) {
+	tests := []struct{ name, src string }{
+		{"underscore emphasis", strings.Repeat("_a_", 30000)},
+		{"unclosed stars", strings.Repeat("*a ", 33000)},
+		{"unclosed brackets", strings.Repeat("[a", 50000)},
+		{"nested links", strings.Repeat("[", 19999) + "a" + strings.Repeat("](u)", 19999)},
+		{"backtick runs", strings.Repeat("`", 450) + strings.Repeat("a`", 45000)},
+		{"growing backtick runs", growingRuns(100000)},
+		{"unclosed link tails", strings.Repeat("[a](b", 20000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			Render(tt.src, FormatMarkdown)
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("rendering %d bytes took %v", len(tt.src), d)
+			}
+		})
+	}
+}
+
+// growingRuns returns runs of 1, 2, 3... backticks, none of them closed.
+func growingRuns(size int) string {
+	var b strings.Builder
+	for n := 1; b.Len() < size; n++ {
+		b.WriteString(strings.Repeat("`", n) + "a")
+	}
+	return b.String()
+}
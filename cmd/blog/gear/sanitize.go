@@ -0,0 +1,192 @@
+package gear
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// allowedTags maps every element that survives Sanitize to the attributes it
+// may keep. Everything else is dropped, leaving its text behind.
+var allowedTags = map[atom.Atom][]string{
+	atom.P:          nil,
+	atom.Br:         nil,
+	atom.Hr:         nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Strong:     nil,
+	atom.B:          nil,
+	atom.Em:         nil,
+	atom.I:          nil,
+	atom.Del:        nil,
+	atom.Code:       {"class"},
+	atom.Pre:        nil,
+	atom.Blockquote: nil,
+	atom.Ul:         nil,
+	atom.Ol:         nil,
+	atom.Li:         nil,
+	atom.A:          {"href", "title"},
+	atom.Img:        {"src", "alt", "title"},
+}
+
+// droppedTags lose their content as well, which is never meant to be read.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+	atom.Svg:      true,
+	atom.Math:     true,
+}
+
+var codeClass = regexp.MustCompile(`^language-[a-z0-9_+-]{1,30}$`)
+
+// linkRel keeps posts from passing on ranking or window.opener.
+const linkRel = "nofollow noopener noreferrer"
+
+// Sanitize keeps only the allowlisted elements and attributes of an HTML
+// fragment. Links may only point to http(s), mailto or relative URLs and
+// images to http(s) or relative ones. The result is well formed: stray end
+// tags are dropped and open elements closed.
+func Sanitize(fragment string) string {
+	var b strings.Builder
+	var open []atom.Atom
+	skip := 0
+
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF, or input too broken to go on with
+			break
+		}
+		tok := z.Token()
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.DataAtom] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			attrs, ok := allowedTags[tok.DataAtom]
+			if !ok || tok.DataAtom == 0 {
+				continue
+			}
+			if tok.DataAtom == atom.Img && !safeURL(attr(tok, "src"), false) {
+				continue
+			}
+
+			b.WriteString("<" + tok.DataAtom.String())
+			for _, name := range attrs {
+				value := attr(tok, name)
+				if value == "" || !safeAttr(tok.DataAtom, name, value) {
+					continue
+				}
+				b.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+			}
+			if tok.DataAtom == atom.A {
+				b.WriteString(` rel="` + linkRel + `"`)
+			}
+			b.WriteString(">")
+			if !isVoid(tok.DataAtom) {
+				open = append(open, tok.DataAtom)
+			}
+
+		case html.EndTagToken:
+			if droppedTags[tok.DataAtom] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			// close whatever is still open inside the element, ignore the
+			// end tag when it was never opened
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tok.DataAtom {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j].String() + ">")
+				}
+				open = open[:i]
+				break
+			}
+
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i].String() + ">")
+	}
+	return b.String()
+}
+
+func attr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func safeAttr(tag atom.Atom, name string, value string) bool {
+	switch name {
+	case "href":
+		return safeURL(value, true)
+	case "src":
+		return safeURL(value, false)
+	case "class":
+		return tag == atom.Code && codeClass.MatchString(value)
+	}
+	return true
+}
+
+// safeURL accepts absolute http(s) URLs, relative ones and, for links,
+// mailto. Anything else, javascript: and data: included, is refused.
+func safeURL(value string, link bool) bool {
+	if value == "" {
+		return false
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return link
+	case "":
+		// "//host/path" would be absolute with the page's scheme
+		return !strings.HasPrefix(value, "//") && !strings.ContainsAny(value, "\\")
+	}
+	return false
+}
+
+func isVoid(tag atom.Atom) bool {
+	return tag == atom.Br || tag == atom.Hr || tag == atom.Img
+}
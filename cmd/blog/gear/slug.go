@@ -0,0 +1,26 @@
+package gear
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify turns a category name into its slug: lower case ASCII letters and
+// digits separated by single hyphens, so "Diet", " diet " and "DIET" agree.
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hyphen = true
+		}
+	}
+	return b.String()
+}
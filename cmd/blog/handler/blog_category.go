@@ -0,0 +1,267 @@
+package handler
+
+import (
+	blogGear "dietku-backend/cmd/blog/gear"
+	"dietku-backend/cmd/blog/repo"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MigrateCategories turns the free-form categories of older blogs into
+// category slugs. A value is matched to a category by slug or by either name,
+// ignoring case, spacing and a plural s; values matching none become new
+// categories named after them. It returns how many blogs were changed.
+func (h *BlogHandler) MigrateCategories() (int64, error) {
+	values, err := h.repo.Categories()
+	if err != nil {
+		return 0, err
+	}
+	categories, err := h.categoryRepo.FindAll()
+	if err != nil {
+		return 0, err
+	}
+
+	known := map[string]string{}
+	for _, category := range *categories {
+		known[blogGear.Slugify(category.NameID)] = category.Slug
+		known[blogGear.Slugify(category.NameEN)] = category.Slug
+	}
+	for _, category := range *categories {
+		known[category.Slug] = category.Slug
+	}
+
+	// shorter values first, so "diet" is there before "diets" looks for it
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) < len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	var changed int64
+	for _, value := range values {
+		key := blogGear.Slugify(value)
+		if key == "" {
+			continue
+		}
+
+		slug, ok := known[key]
+		if !ok && strings.HasSuffix(key, "s") {
+			slug, ok = known[strings.TrimSuffix(key, "s")]
+		}
+		if !ok {
+			order, err := h.categoryRepo.NextOrder()
+			if err != nil {
+				return changed, err
+			}
+			name := strings.TrimSpace(value)
+			_, err = h.categoryRepo.InsertOne(&repo.Category{
+				ID:        primitive.NewObjectID(),
+				Slug:      key,
+				NameID:    name,
+				NameEN:    name,
+				Order:     order,
+				CreatedAt: h.clock.Now(),
+			})
+			if err != nil {
+				return changed, err
+			}
+			slug = key
+			known[key] = key
+		}
+
+		if value != slug {
+			n, err := h.repo.RenameCategory(value, slug)
+			if err != nil {
+				return changed, err
+			}
+			changed += n
+		}
+	}
+	return changed, nil
+}
+
+type CategoryView struct {
+	repo.Category
+	PostCount int64 `json:"postCount"`
+}
+
+// Categories
+// @Tags Blog
+// @Summary Get Blog Categories
+// @Description Every category in display order, with the number of published posts in it.
+// @ID blog-categories
+// @Router /api/blog/categories [get]
+// @Produce json
+// @Success 200
+func (h *BlogHandler) Categories(c echo.Context) error {
+	categories, err := h.categoryRepo.FindAll()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting categories.", c)
+	}
+	counts, err := h.repo.CountByCategory()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting categories.", c)
+	}
+
+	views := make([]CategoryView, len(*categories))
+	for i, category := range *categories {
+		views[i] = CategoryView{Category: category, PostCount: counts[category.Slug]}
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// CreateCategory
+// @Tags Blog
+// @Summary Create Blog Category
+// @Description Admins only. The slug defaults to one made of the English name; the order to after the last category.
+// @ID blog-categories-create
+// @Router /api/blog/categories [post]
+// @Accept json
+// @Param body body CategoryForm true "category body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) CreateCategory(c echo.Context) error {
+	form, err := NewCategoryForm(c)
+	if err != nil {
+		return err
+	}
+
+	category := &repo.Category{
+		ID:          primitive.NewObjectID(),
+		Slug:        form.Slug,
+		NameID:      form.NameID,
+		NameEN:      form.NameEN,
+		Description: form.Description,
+		CreatedAt:   h.clock.Now(),
+	}
+	if form.Order != nil {
+		category.Order = *form.Order
+	} else {
+		category.Order, err = h.categoryRepo.NextOrder()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating category.", c)
+		}
+	}
+
+	_, err = h.categoryRepo.InsertOne(category)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Category slug already exists", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating category.", c)
+	}
+	return c.JSON(http.StatusOK, category)
+}
+
+func (h *BlogHandler) findCategory(c echo.Context) (*repo.Category, error) {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid category id", c)
+	}
+
+	category, err := h.categoryRepo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Category not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting category.", c)
+	}
+	return category, nil
+}
+
+// UpdateCategory
+// @Tags Blog
+// @Summary Update Blog Category
+// @Description Admins only. Changing the slug moves every post in the category to the new slug.
+// @ID blog-categories-update
+// @Router /api/blog/categories/{id} [put]
+// @Accept json
+// @Param id path string true "Category ID"
+// @Param body body CategoryForm true "category body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) UpdateCategory(c echo.Context) error {
+	category, err := h.findCategory(c)
+	if err != nil {
+		return err
+	}
+
+	form, err := NewUpdateCategoryForm(c)
+	if err != nil {
+		return err
+	}
+
+	oldSlug := category.Slug
+	if form.Slug != "" {
+		category.Slug = form.Slug
+	}
+	if form.NameID != "" {
+		category.NameID = form.NameID
+	}
+	if form.NameEN != "" {
+		category.NameEN = form.NameEN
+	}
+	if form.Description != "" {
+		category.Description = form.Description
+	}
+	if form.Order != nil {
+		category.Order = *form.Order
+	}
+	now := h.clock.Now()
+	category.UpdatedAt = &now
+
+	docs, err := h.categoryRepo.UpdateOne(category)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Category slug already exists", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating category.", c)
+	}
+
+	if docs.Slug != oldSlug {
+		if _, err := h.repo.RenameCategory(oldSlug, docs.Slug); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while moving posts to the new slug.", c)
+		}
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// DeleteCategory
+// @Tags Blog
+// @Summary Delete Blog Category
+// @Description Admins only. Categories still holding posts cannot be deleted.
+// @ID blog-categories-delete
+// @Router /api/blog/categories/{id} [delete]
+// @Param id path string true "Category ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) DeleteCategory(c echo.Context) error {
+	category, err := h.findCategory(c)
+	if err != nil {
+		return err
+	}
+
+	used, err := h.repo.CountCategory(category.Slug)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting category.", c)
+	}
+	if used > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Category is used by %d posts", used), c)
+	}
+
+	docs, err := h.categoryRepo.DeleteOne(category.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting category.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
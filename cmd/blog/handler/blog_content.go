@@ -0,0 +1,42 @@
+package handler
+
+import (
+	blogGear "dietku-backend/cmd/blog/gear"
+	"dietku-backend/cmd/blog/repo"
+)
+
+// setContent sets the source and format of blog and renders it. An empty
+// format keeps the current one, plain for posts that never had one.
+func setContent(blog *repo.Blog, content string, format string) {
+	if format == "" {
+		format = blog.Format
+	}
+	if format == "" {
+		format = blogGear.FormatPlain
+	}
+
+	rendered := blogGear.Render(content, format)
+	blog.Content = content
+	blog.Format = format
+	blog.ContentHTML = rendered.HTML
+	blog.Excerpt = rendered.Excerpt
+	blog.ReadingTime = rendered.ReadingTime
+}
+
+// RenderLegacy renders the posts written before content was rendered on save,
+// taking their content as plain text.
+func (h *BlogHandler) RenderLegacy() (int, error) {
+	blogs, err := h.repo.FindUnrendered()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range *blogs {
+		blog := &(*blogs)[i]
+		setContent(blog, blog.Content, blogGear.FormatPlain)
+		if err := h.repo.SaveRendered(blog); err != nil {
+			return i, err
+		}
+	}
+	return len(*blogs), nil
+}
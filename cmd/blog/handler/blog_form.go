@@ -1,17 +1,34 @@
 package handler
 
 import (
+	"dietku-backend/cmd/auth/gear"
+	blogGear "dietku-backend/cmd/blog/gear"
+	"dietku-backend/cmd/blog/repo"
+	"fmt"
 	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// BlogForm is the body of create and update. Categories are given by slug and
+// must exist. Format is plain (the default) or markdown. Media lists the ids of
+// library images the post uses; on update it replaces the list when given.
+// Status and ScheduledAt are only read on creation, afterwards use the publish
+// endpoints.
 type BlogForm struct {
-	Header   string   `json:"header" bson:"header"`
-	Content  string   `json:"content" bson:"content"`
-	Category []string `json:"category" bson:"category"`
+	Header      string               `json:"header" bson:"header"`
+	Content     string               `json:"content" bson:"content"`
+	Format      string               `json:"format" bson:"format"`
+	Media       []primitive.ObjectID `json:"media" bson:"media"`
+	Category    []string             `json:"category" bson:"category"`
+	Language    string               `json:"language" bson:"language"`
+	Status      string               `json:"status" bson:"status"`
+	ScheduledAt *time.Time           `json:"scheduledAt" bson:"scheduledAt"`
 }
 
-func NewBlogForm(c echo.Context) (*BlogForm, error) {
+func NewBlogForm(c echo.Context, categories *repo.CategoryRepository) (*BlogForm, error) {
 	form := new(BlogForm)
 	if err := c.Bind(form); err != nil {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
@@ -25,13 +42,291 @@ func NewBlogForm(c echo.Context) (*BlogForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Content is required.")
 	}
 
+	if err := validateContent(form.Content); err != nil {
+		return nil, err
+	}
+
+	if err := validateFormat(form.Format); err != nil {
+		return nil, err
+	}
+
+	if err := validateMedia(form.Media); err != nil {
+		return nil, err
+	}
+
+	category, err := resolveCategories(categories, form.Category)
+	if err != nil {
+		return nil, err
+	}
+	form.Category = category
+
+	if err := validateLanguage(form.Language); err != nil {
+		return nil, err
+	}
+
+	switch form.Status {
+	case "", repo.StatusDraft, repo.StatusPublished:
+	case repo.StatusScheduled:
+		if form.ScheduledAt == nil || !form.ScheduledAt.After(time.Now()) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "ScheduledAt must be in the future.")
+		}
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Status must be draft, published or scheduled.")
+	}
+
 	return form, nil
 }
 
-func NewUpdateBlogForm(c echo.Context) (*BlogForm, error) {
+func NewUpdateBlogForm(c echo.Context, categories *repo.CategoryRepository) (*BlogForm, error) {
 	form := new(BlogForm)
 	if err := c.Bind(form); err != nil {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
 	}
+
+	if err := validateContent(form.Content); err != nil {
+		return nil, err
+	}
+
+	if err := validateFormat(form.Format); err != nil {
+		return nil, err
+	}
+
+	if err := validateMedia(form.Media); err != nil {
+		return nil, err
+	}
+
+	category, err := resolveCategories(categories, form.Category)
+	if err != nil {
+		return nil, err
+	}
+	form.Category = category
+
+	if err := validateLanguage(form.Language); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+type PublishForm struct {
+	ScheduledAt *time.Time `json:"scheduledAt"`
+}
+
+func NewPublishForm(c echo.Context) (*PublishForm, error) {
+	form := new(PublishForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+	return form, nil
+}
+
+// maxContentLength bounds the size of a post, which is rendered, sanitized
+// and diffed on every save.
+const maxContentLength = 100000
+
+func validateContent(content string) error {
+	if len(content) > maxContentLength {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Content must be at most %d bytes.", maxContentLength))
+	}
+	return nil
+}
+
+func validateFormat(format string) error {
+	if format != "" && !blogGear.IsValidFormat(format) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Format must be plain or markdown.")
+	}
+	return nil
+}
+
+// resolveCategories turns the given categories into their slugs, making sure
+// each one exists.
+func resolveCategories(categories *repo.CategoryRepository, values []string) ([]string, error) {
+	if len(values) == 0 {
+		return values, nil
+	}
+
+	all, err := categories.FindAll()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting categories.")
+	}
+	known := map[string]bool{}
+	for _, category := range *all {
+		known[category.Slug] = true
+	}
+
+	slugs := []string{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		slug := blogGear.Slugify(v)
+		if !known[slug] {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown category: %s.", v))
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs, nil
+}
+
+// maxMedia bounds the images of one post.
+const maxMedia = 50
+
+func validateMedia(media []primitive.ObjectID) error {
+	if len(media) > maxMedia {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("A post can use at most %d media.", maxMedia))
+	}
+	return nil
+}
+
+// validateLanguage accepts "id", "en" or nothing, in which case the language
+// is guessed from the content.
+func validateLanguage(lang string) error {
+	if lang != "" && !blogGear.IsValidLanguage(lang) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Language must be id or en.")
+	}
+	return nil
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// NewListQuery reads the listing parameters shared by every blog listing:
+// limit, sort, cursor, status, author, category (repeated or comma separated), match
+// and the from/to creation dates (YYYY-MM-DD, inclusive, Jakarta time).
+func NewListQuery(c echo.Context) (*repo.ListQuery, error) {
+	limit, err := gear.QueryInt(c, "limit", defaultPageSize, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	q := &repo.ListQuery{Limit: int64(limit), Sort: repo.SortNewest}
+
+	if v := c.QueryParam("sort"); v != "" {
+		if v != repo.SortNewest && v != repo.SortOldest {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Sort must be newest or oldest.")
+		}
+		q.Sort = v
+	}
+
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := repo.DecodeCursor(v)
+		if err != nil || cursor.Sort != q.Sort {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor.")
+		}
+		q.After = cursor
+	}
+
+	if v := c.QueryParam("status"); v != "" {
+		if !repo.IsValidStatus(v) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Status must be draft, scheduled, published or archived.")
+		}
+		q.Status = v
+	}
+
+	if v := c.QueryParam("author"); v != "" {
+		author, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid author id.")
+		}
+		q.Author = &author
+	}
+
+	for _, v := range c.QueryParams()["category"] {
+		for _, category := range strings.Split(v, ",") {
+			if category = blogGear.Slugify(category); category != "" {
+				q.Categories = append(q.Categories, category)
+			}
+		}
+	}
+
+	switch c.QueryParam("match") {
+	case "", "any":
+	case "all":
+		q.MatchAll = true
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Match must be any or all.")
+	}
+
+	loc := gear.LoadLocation("")
+	if v := c.QueryParam("from"); v != "" {
+		_, start, _, err := gear.ParseDay(v, loc)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid from, use YYYY-MM-DD.")
+		}
+		q.From = &start
+	}
+	if v := c.QueryParam("to"); v != "" {
+		_, _, end, err := gear.ParseDay(v, loc)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid to, use YYYY-MM-DD.")
+		}
+		q.To = &end
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "From must not be after to.")
+	}
+	return q, nil
+}
+
+const maxCategoryDescription = 500
+
+// CategoryForm is the body of creating and updating a category. The slug may
+// only hold lower case letters, digits and hyphens.
+type CategoryForm struct {
+	Slug        string `json:"slug"`
+	NameID      string `json:"nameId"`
+	NameEN      string `json:"nameEn"`
+	Description string `json:"description"`
+	Order       *int   `json:"order"`
+}
+
+func bindCategoryForm(c echo.Context) (*CategoryForm, error) {
+	form := new(CategoryForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Slug = strings.TrimSpace(form.Slug)
+	form.NameID = strings.TrimSpace(form.NameID)
+	form.NameEN = strings.TrimSpace(form.NameEN)
+	form.Description = strings.TrimSpace(form.Description)
+
+	if form.Slug != "" && form.Slug != blogGear.Slugify(form.Slug) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Slug may only contain lower case letters, digits and single hyphens.")
+	}
+	if len([]rune(form.Description)) > maxCategoryDescription {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Description must not exceed %d characters.", maxCategoryDescription))
+	}
+	return form, nil
+}
+
+func NewCategoryForm(c echo.Context) (*CategoryForm, error) {
+	form, err := bindCategoryForm(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.NameID == "" || form.NameEN == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "NameId and nameEn are required.")
+	}
+	if form.Slug == "" {
+		form.Slug = blogGear.Slugify(form.NameEN)
+	}
+	if form.Slug == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Slug is required.")
+	}
+	return form, nil
+}
+
+func NewUpdateCategoryForm(c echo.Context) (*CategoryForm, error) {
+	form, err := bindCategoryForm(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.Slug == "" && form.NameID == "" && form.NameEN == "" && form.Description == "" && form.Order == nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Nothing to update")
+	}
 	return form, nil
 }
@@ -1,56 +1,175 @@
 package handler
 
 import (
+	"context"
 	"dietku-backend/cmd/auth/gear"
+	blogGear "dietku-backend/cmd/blog/gear"
 	"dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/event"
+	"dietku-backend/cmd/log"
+	mediaRepo "dietku-backend/cmd/media/repo"
+	"dietku-backend/cmd/worker"
 	"errors"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
-	"time"
 )
 
 type BlogHandler struct {
-	repo *repo.BlogRepository
+	repo         *repo.BlogRepository
+	revisionRepo *repo.RevisionRepository
+	reactionRepo *repo.ReactionRepository
+	bookmarkRepo *repo.BookmarkRepository
+	mediaRepo    *mediaRepo.MediaRepository
+	categoryRepo *repo.CategoryRepository
+	clock        worker.Clock
 }
 
 func NewBlogApi(e *echo.Echo, db *mongo.Database) *BlogHandler {
 	b := &BlogHandler{
-		repo: repo.NewBlogRepository(db),
+		repo:         repo.NewBlogRepository(db),
+		revisionRepo: repo.NewRevisionRepository(db),
+		reactionRepo: repo.NewReactionRepository(db),
+		bookmarkRepo: repo.NewBookmarkRepository(db),
+		mediaRepo:    mediaRepo.NewMediaRepository(db),
+		categoryRepo: repo.NewCategoryRepository(db),
+		clock:        worker.RealClock{},
 	}
+	if err := b.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create blog indexes: %v", err)
+	}
+	if err := b.revisionRepo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create blog revision indexes: %v", err)
+	}
+	if err := b.reactionRepo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create blog reaction indexes: %v", err)
+	}
+	if err := b.bookmarkRepo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create blog bookmark indexes: %v", err)
+	}
+	if err := b.categoryRepo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create blog category indexes: %v", err)
+	}
+	if migrated, err := b.repo.MigrateStatus(); err != nil {
+		log.Errorf("failed to migrate blog statuses: %v", err)
+	} else if migrated > 0 {
+		log.Infof("published %d blogs without a status", migrated)
+	}
+	if renamed, err := b.MigrateCategories(); err != nil {
+		log.Errorf("failed to migrate blog categories: %v", err)
+	} else if renamed > 0 {
+		log.Infof("normalized the categories of %d blogs", renamed)
+	}
+	if rendered, err := b.RenderLegacy(); err != nil {
+		log.Errorf("failed to render blogs: %v", err)
+	} else if rendered > 0 {
+		log.Infof("rendered %d blogs without a format", rendered)
+	}
+
+	w := &worker.Worker{
+		Name:     publisherName,
+		Interval: publisherInterval,
+		Clock:    b.clock,
+		Lock:     worker.NewLock(db),
+		Job:      b.PublishDue,
+	}
+	w.Start(context.Background())
+
 	bGroup := e.Group("")
 	{
-		bGroup.GET("/api/blog", b.Blogs)
-		bGroup.GET("/api/blog/:id", b.Blog)
-		bGroup.GET("/api/blog/user/:userId", b.BlogsByUser)
-		bGroup.GET("/api/blog/category/:category", b.BlogsByCategory)
+		bGroup.GET("/api/blog", b.Blogs, gear.MaybeLoggedIn(db))
+		bGroup.GET("/api/blog/search", b.Search, gear.MaybeLoggedIn(db))
+		bGroup.GET("/api/blog/categories", b.Categories)
+		bGroup.GET("/api/blog/:id", b.Blog, gear.MaybeLoggedIn(db))
+		bGroup.GET("/api/blog/user/:userId", b.BlogsByUser, gear.MaybeLoggedIn(db))
+		bGroup.GET("/api/blog/category/:category", b.BlogsByCategory, gear.MaybeLoggedIn(db))
+		bGroup.GET("/api/blog/:id/revisions", b.Revisions, gear.IsLoggedIn(db))
+		bGroup.GET("/api/blog/:id/revisions/diff", b.RevisionDiff, gear.IsLoggedIn(db))
+		bGroup.GET("/api/user/bookmarks", b.Bookmarks, gear.IsLoggedIn(db))
 
 		bGroup.POST("/api/blog", b.Create, gear.IsLoggedIn(db))
+		bGroup.POST("/api/blog/categories", b.CreateCategory, gear.IsAdmin(db))
+		bGroup.POST("/api/blog/:id/publish", b.Publish, gear.IsLoggedIn(db))
+		bGroup.POST("/api/blog/:id/unpublish", b.Unpublish, gear.IsLoggedIn(db))
+		bGroup.POST("/api/blog/:id/archive", b.Archive, gear.IsLoggedIn(db))
+		bGroup.POST("/api/blog/:id/revisions/:number/restore", b.RestoreRevision, gear.IsLoggedIn(db))
 
 		bGroup.PUT("/api/blog/:id", b.Update, gear.IsLoggedIn(db))
+		bGroup.PUT("/api/blog/categories/:id", b.UpdateCategory, gear.IsAdmin(db))
+		bGroup.PUT("/api/blog/:id/reactions/:type", b.React, gear.IsLoggedIn(db))
+		bGroup.PUT("/api/blog/:id/bookmark", b.Bookmark, gear.IsLoggedIn(db))
 
 		bGroup.DELETE("/api/blog/:id", b.Delete, gear.IsLoggedIn(db))
+		bGroup.DELETE("/api/blog/categories/:id", b.DeleteCategory, gear.IsAdmin(db))
+		bGroup.DELETE("/api/blog/:id/reactions/:type", b.Unreact, gear.IsLoggedIn(db))
+		bGroup.DELETE("/api/blog/:id/bookmark", b.Unbookmark, gear.IsLoggedIn(db))
 	}
 	return b
 }
 
+type BlogPage struct {
+	Items      repo.Blogs `json:"items"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+	Limit      int64      `json:"limit"`
+	// Total counts every match up to 10000; at that value it is only a floor.
+	Total int64 `json:"total"`
+}
+
+// list answers a listing with one page of q and the cursor of the next one.
+func (h *BlogHandler) list(c echo.Context, q *repo.ListQuery) error {
+	if me, ok := c.Get("me").(*gear.UserClaims); ok {
+		q.Viewer = &me.ID
+	}
+
+	blogs, err := h.repo.List(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+
+	total, err := h.repo.Count(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+
+	page := &BlogPage{Items: *blogs, Limit: q.Limit, Total: total}
+	if int64(len(page.Items)) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = repo.Cursor{Sort: q.Sort, At: last.CreatedBy.At, ID: last.ID}.Encode()
+	}
+
+	items := make([]*repo.Blog, len(page.Items))
+	for i := range page.Items {
+		items[i] = &page.Items[i]
+	}
+	h.decorate(c, items...)
+	return c.JSON(http.StatusOK, page)
+}
+
 // Blogs
 // @Tags Blog
 // @Summary Get All Blogs
+// @Description Pages through blogs with an opaque cursor; pass nextCursor back as cursor for the following page.
 // @ID blog
 // @Router /api/blog [get]
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param sort query string false "newest (default) or oldest"
+// @Param status query string false "draft, scheduled, published or archived; only published posts of other authors are listed"
+// @Param author query string false "Author user ID"
+// @Param category query []string false "Category slugs, repeated or comma separated"
+// @Param match query string false "any (default) or all categories"
+// @Param from query string false "Created on or after (YYYY-MM-DD)"
+// @Param to query string false "Created on or before (YYYY-MM-DD)"
 // @Produce json
 // @Success 200
 func (h *BlogHandler) Blogs(c echo.Context) error {
-	blogs, err := h.repo.FindAll()
+	q, err := NewListQuery(c)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusBadRequest, "Blogs not found!", c)
-		}
-		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+		return err
 	}
-	return c.JSON(http.StatusOK, blogs)
+	return h.list(c, q)
 }
 
 // Blog
@@ -75,6 +194,11 @@ func (h *BlogHandler) Blog(c echo.Context) error {
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
 	}
+
+	if !canSee(c, blog) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Blog not found!", c)
+	}
+	h.decorate(c, blog)
 	return c.JSON(http.StatusOK, blog)
 }
 
@@ -85,6 +209,10 @@ func (h *BlogHandler) Blog(c echo.Context) error {
 // @Router /api/blog/user/{userId} [get]
 // @Produce json
 // @Param userId path string true "User ID"
+// @Param status query string false "draft, scheduled, published or archived; drafts are only listed for their author"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param sort query string false "newest (default) or oldest"
 // @Success 200
 func (h *BlogHandler) BlogsByUser(c echo.Context) error {
 	userId := c.Param("userId")
@@ -93,14 +221,12 @@ func (h *BlogHandler) BlogsByUser(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id", c)
 	}
 
-	blogs, err := h.repo.FindByUser(oId)
+	q, err := NewListQuery(c)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusBadRequest, "Blogs not found!", c)
-		}
-		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+		return err
 	}
-	return c.JSON(http.StatusOK, blogs)
+	q.Author = &oId
+	return h.list(c, q)
 }
 
 // BlogsByCategory
@@ -110,23 +236,34 @@ func (h *BlogHandler) BlogsByUser(c echo.Context) error {
 // @Router /api/blog/category/{category} [get]
 // @Produce json
 // @Param category path string true "Category"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param sort query string false "newest (default) or oldest"
 // @Success 200
 func (h *BlogHandler) BlogsByCategory(c echo.Context) error {
-	category := c.Param("category")
-
-	blogs, err := h.repo.FindByCategory(category)
+	q, err := NewListQuery(c)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusBadRequest, "Blogs not found!", c)
-		}
-		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+		return err
+	}
+	q.Categories = []string{blogGear.Slugify(c.Param("category"))}
+	q.MatchAll = false
+	return h.list(c, q)
+}
+
+// setLanguage sets the language of blog, guessing it from the content when
+// lang is empty, and the text index language that goes with it.
+func setLanguage(blog *repo.Blog, lang string) {
+	if lang == "" {
+		lang = blogGear.DetectLanguage(blog.Header + " " + blog.Content)
 	}
-	return c.JSON(http.StatusOK, blogs)
+	blog.Language = lang
+	blog.SearchLanguage = blogGear.SearchLanguage(lang)
 }
 
 // Create
 // @Tags Blog
 // @Summary Create Blog
+// @Description Posts start as drafts unless status is published, or scheduled with a future scheduledAt. Content is plain text unless format is markdown; either way it is kept as written and rendered to sanitized HTML in contentHtml.
 // @ID blog-create
 // @Router /api/blog [post]
 // @Accept json
@@ -135,30 +272,45 @@ func (h *BlogHandler) BlogsByCategory(c echo.Context) error {
 // @Success 200
 // @Security ApiKeyAuth
 func (h *BlogHandler) Create(c echo.Context) error {
-	form, err := NewBlogForm(c)
+	form, err := NewBlogForm(c, h.categoryRepo)
 	if err != nil {
 		return err
 	}
 
 	tokenData := c.Get("me").(*gear.UserClaims)
+	now := h.clock.Now()
 
 	b := &repo.Blog{
 		ID:       primitive.NewObjectID(),
 		Header:   form.Header,
-		Content:  form.Content,
 		Category: form.Category,
 		CreatedBy: repo.By{
 			ID:       tokenData.ID,
 			Email:    tokenData.Email,
 			FullName: tokenData.FirstName + " " + tokenData.LastName,
-			At:       time.Now(),
+			At:       now,
 		},
+		Status: repo.StatusDraft,
+	}
+
+	setContent(b, form.Content, form.Format)
+	setLanguage(b, form.Language)
+	if err := h.setMedia(c, b, form.Media); err != nil {
+		return err
+	}
+	if form.Status == repo.StatusPublished || form.Status == repo.StatusScheduled {
+		publish(b, form.ScheduledAt, now)
 	}
 
 	_, err = h.repo.InsertOne(b)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating blog.", c)
 	}
+	h.snapshot(nil, b, b.CreatedBy, 0)
+	h.attach(b)
+	if b.Status == repo.StatusPublished {
+		event.Publish(event.Event{Type: event.BlogPublished, UserID: tokenData.ID, At: now})
+	}
 
 	docs, err := h.repo.FindOne(b.ID)
 	if err != nil {
@@ -199,35 +351,48 @@ func (h *BlogHandler) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to update this blog", c)
 	}
 
-	form, err := NewUpdateBlogForm(c)
+	form, err := NewUpdateBlogForm(c, h.categoryRepo)
 	if err != nil {
 		return err
 	}
 
-	if form.Header == "" && form.Content == "" && len(form.Category) == 0 {
+	if form.Header == "" && form.Content == "" && form.Format == "" && form.Media == nil && len(form.Category) == 0 && form.Language == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Nothing to update", c)
 	}
 
+	before := *blog
 	if form.Header != "" {
 		blog.Header = form.Header
 	}
-	if form.Content != "" {
-		blog.Content = form.Content
+	if form.Content != "" || form.Format != "" {
+		content := form.Content
+		if content == "" {
+			content = blog.Content
+		}
+		setContent(blog, content, form.Format)
 	}
 	if len(form.Category) > 0 {
 		blog.Category = form.Category
 	}
-
-	blog.UpdatedBy = &repo.By{
-		ID:       tokenData.ID,
-		Email:    tokenData.Email,
-		FullName: tokenData.FirstName + " " + tokenData.LastName,
+	if form.Language != "" {
+		blog.Language = form.Language
 	}
+	setLanguage(blog, blog.Language)
+	if form.Media != nil {
+		if err := h.setMedia(c, blog, form.Media); err != nil {
+			return err
+		}
+	}
+
+	editor := h.editor(c)
+	blog.UpdatedBy = &editor
 
 	docs, err := h.repo.UpdateOne(blog)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating blog.", c)
 	}
+	h.snapshot(&before, docs, editor, 0)
+	h.attach(docs)
 	return c.JSON(http.StatusOK, docs)
 }
 
@@ -265,5 +430,7 @@ func (h *BlogHandler) Delete(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting blog.", c)
 	}
+	// lets the media library collect the images only this post used
+	event.Publish(event.Event{Type: event.BlogDeleted, UserID: tokenData.ID, At: h.clock.Now()})
 	return c.JSON(http.StatusOK, docs)
 }
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/log"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+)
+
+// setMedia sets the images blog uses, after checking they are all in its
+// author's library.
+func (h *BlogHandler) setMedia(c echo.Context, blog *repo.Blog, ids []primitive.ObjectID) error {
+	media := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			media = append(media, id)
+		}
+	}
+
+	if len(media) > 0 {
+		owned, err := h.mediaRepo.CountOwned(blog.CreatedBy.ID, media)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting media.", c)
+		}
+		if owned != int64(len(media)) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Media not found!", c)
+		}
+	}
+
+	blog.Media = media
+	return nil
+}
+
+// restoreMedia sets the images blog used in revision, leaving out those
+// removed from the library since. Revisions without a snapshot of the images
+// keep the current ones.
+func (h *BlogHandler) restoreMedia(c echo.Context, blog *repo.Blog, revision *repo.Revision) error {
+	if revision.Media == nil {
+		return nil
+	}
+	blog.Media = []primitive.ObjectID{}
+	if len(revision.Media) == 0 {
+		return nil
+	}
+
+	owned, err := h.mediaRepo.FindOwnedIDs(blog.CreatedBy.ID, revision.Media)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting media.", c)
+	}
+	kept := map[primitive.ObjectID]bool{}
+	for _, id := range owned {
+		kept[id] = true
+	}
+	for _, id := range revision.Media {
+		if kept[id] {
+			blog.Media = append(blog.Media, id)
+		}
+	}
+	return nil
+}
+
+// attach marks the images of a saved blog as used, so they are collected once
+// no post uses them anymore. Failures are logged only: the blog is saved.
+func (h *BlogHandler) attach(blog *repo.Blog) {
+	if len(blog.Media) == 0 {
+		return
+	}
+	if err := h.mediaRepo.Attach(blog.Media, h.clock.Now()); err != nil {
+		log.Errorf("failed to attach media of blog %s: %v", blog.ID.Hex(), err)
+	}
+}
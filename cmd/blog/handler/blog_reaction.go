@@ -0,0 +1,309 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/log"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+// maxBookmarkPage bounds the skip of the bookmark listing.
+const maxBookmarkPage = 1000
+
+// decorate fills in the reaction counts of every type and, for a signed in
+// user, what they did to each blog. A failure only leaves Me out.
+func (h *BlogHandler) decorate(c echo.Context, blogs ...*repo.Blog) {
+	ids := make([]primitive.ObjectID, len(blogs))
+	for i, blog := range blogs {
+		counts := make(map[string]int, len(repo.ReactionTypes))
+		for _, typ := range repo.ReactionTypes {
+			counts[typ] = blog.Reactions[typ]
+		}
+		blog.Reactions = counts
+		ids[i] = blog.ID
+	}
+
+	me, ok := c.Get("me").(*gear.UserClaims)
+	if !ok || len(blogs) == 0 {
+		return
+	}
+
+	reactions, err := h.reactionRepo.FindByUser(me.ID, ids)
+	if err != nil {
+		log.Errorf("failed to get reactions of user %s: %v", me.ID.Hex(), err)
+		return
+	}
+	bookmarked, err := h.bookmarkRepo.Bookmarked(me.ID, ids)
+	if err != nil {
+		log.Errorf("failed to get bookmarks of user %s: %v", me.ID.Hex(), err)
+		return
+	}
+
+	for _, blog := range blogs {
+		blog.Me = &repo.Interaction{Reactions: []string{}, Bookmarked: bookmarked[blog.ID]}
+		for _, typ := range repo.ReactionTypes {
+			for _, mine := range reactions[blog.ID] {
+				if mine == typ {
+					blog.Me.Reactions = append(blog.Me.Reactions, typ)
+				}
+			}
+		}
+	}
+}
+
+// visibleBlog loads the blog of the id parameter when the signed in user can
+// see it.
+func (h *BlogHandler) visibleBlog(c echo.Context) (*repo.Blog, error) {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid blog id", c)
+	}
+
+	blog, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Blog not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+
+	if !canSee(c, blog) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Blog not found!", c)
+	}
+	return blog, nil
+}
+
+// reacted answers a reaction or bookmark change with the blog as it is now.
+func (h *BlogHandler) reacted(c echo.Context, id primitive.ObjectID) error {
+	blog, err := h.repo.FindOne(id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+	h.decorate(c, blog)
+	return c.JSON(http.StatusOK, blog)
+}
+
+// React
+// @Tags Blog
+// @Summary React To Blog
+// @Description Adds a reaction of the signed in user. Reacting again with the same type changes nothing. Types: like 👍, love ❤️, clap 👏, wow 😮, sad 😢, angry 😠.
+// @ID blog-react
+// @Router /api/blog/{id}/reactions/{type} [put]
+// @Param id path string true "Blog ID"
+// @Param type path string true "like, love, clap, wow, sad or angry"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) React(c echo.Context) error {
+	typ := c.Param("type")
+	if !repo.IsValidReaction(typ) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Type must be like, love, clap, wow, sad or angry", c)
+	}
+
+	blog, err := h.visibleBlog(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	added, err := h.reactionRepo.Add(blog.ID, tokenData.ID, typ, h.clock.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while reacting to blog.", c)
+	}
+	if added {
+		if err := h.repo.IncReaction(blog.ID, typ, 1); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while reacting to blog.", c)
+		}
+	}
+	return h.reacted(c, blog.ID)
+}
+
+// Unreact
+// @Tags Blog
+// @Summary Remove Blog Reaction
+// @Description Takes back a reaction of the signed in user; nothing happens when there is none.
+// @ID blog-unreact
+// @Router /api/blog/{id}/reactions/{type} [delete]
+// @Param id path string true "Blog ID"
+// @Param type path string true "like, love, clap, wow, sad or angry"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) Unreact(c echo.Context) error {
+	typ := c.Param("type")
+	if !repo.IsValidReaction(typ) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Type must be like, love, clap, wow, sad or angry", c)
+	}
+
+	blog, err := h.visibleBlog(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	removed, err := h.reactionRepo.Remove(blog.ID, tokenData.ID, typ)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while removing reaction.", c)
+	}
+	if removed {
+		if err := h.repo.IncReaction(blog.ID, typ, -1); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while removing reaction.", c)
+		}
+	}
+	return h.reacted(c, blog.ID)
+}
+
+// Bookmark
+// @Tags Blog
+// @Summary Bookmark Blog
+// @Description Saves the post for later; bookmarking it again changes nothing.
+// @ID blog-bookmark
+// @Router /api/blog/{id}/bookmark [put]
+// @Param id path string true "Blog ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) Bookmark(c echo.Context) error {
+	blog, err := h.visibleBlog(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	added, err := h.bookmarkRepo.Add(tokenData.ID, blog.ID, h.clock.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while bookmarking blog.", c)
+	}
+	if added {
+		if err := h.repo.IncBookmarkCount(blog.ID, 1); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while bookmarking blog.", c)
+		}
+	}
+	return h.reacted(c, blog.ID)
+}
+
+// Unbookmark
+// @Tags Blog
+// @Summary Remove Blog Bookmark
+// @Description Also works on posts no longer visible, which are answered with 204 instead of the post.
+// @ID blog-unbookmark
+// @Router /api/blog/{id}/bookmark [delete]
+// @Param id path string true "Blog ID"
+// @Produce json
+// @Success 200
+// @Success 204
+// @Security ApiKeyAuth
+func (h *BlogHandler) Unbookmark(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid blog id", c)
+	}
+
+	// a bookmark can be removed even when the post is no longer visible
+	tokenData := c.Get("me").(*gear.UserClaims)
+	removed, err := h.bookmarkRepo.Remove(tokenData.ID, oId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while removing bookmark.", c)
+	}
+	if removed {
+		if err := h.repo.IncBookmarkCount(oId, -1); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while removing bookmark.", c)
+		}
+	}
+
+	blog, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.NoContent(http.StatusNoContent)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+	if !canSee(c, blog) {
+		return c.NoContent(http.StatusNoContent)
+	}
+	h.decorate(c, blog)
+	return c.JSON(http.StatusOK, blog)
+}
+
+type BookmarkedBlog struct {
+	repo.Blog
+	BookmarkedAt time.Time `json:"bookmarkedAt"`
+}
+
+type BookmarkPage struct {
+	Items []BookmarkedBlog `json:"items"`
+	Page  int              `json:"page"`
+	Limit int              `json:"limit"`
+	// Total counts bookmarks of posts that are no longer visible too.
+	Total int64 `json:"total"`
+}
+
+// Bookmarks
+// @Tags Blog
+// @Summary Get My Bookmarks
+// @Description Bookmarked posts, most recently bookmarked first. Posts deleted or taken offline since are left out.
+// @ID blog-bookmarks
+// @Router /api/user/bookmarks [get]
+// @Param page query int false "Page, starting at 1"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) Bookmarks(c echo.Context) error {
+	page, err := gear.QueryInt(c, "page", 1, maxBookmarkPage)
+	if err != nil {
+		return err
+	}
+	limit, err := gear.QueryInt(c, "limit", defaultPageSize, maxPageSize)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	bookmarks, err := h.bookmarkRepo.FindByUser(tokenData.ID, int64(page-1)*int64(limit), int64(limit))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting bookmarks.", c)
+	}
+	total, err := h.bookmarkRepo.Count(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting bookmarks.", c)
+	}
+
+	result := &BookmarkPage{Items: []BookmarkedBlog{}, Page: page, Limit: limit, Total: total}
+	if len(*bookmarks) == 0 {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	ids := make([]primitive.ObjectID, len(*bookmarks))
+	for i, b := range *bookmarks {
+		ids[i] = b.BlogID
+	}
+	blogs, err := h.repo.FindByIDs(ids)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting bookmarks.", c)
+	}
+	byID := make(map[primitive.ObjectID]repo.Blog, len(*blogs))
+	for _, blog := range *blogs {
+		byID[blog.ID] = blog
+	}
+
+	for _, b := range *bookmarks {
+		blog, ok := byID[b.BlogID]
+		if !ok || !canSee(c, &blog) {
+			continue
+		}
+		result.Items = append(result.Items, BookmarkedBlog{Blog: blog, BookmarkedAt: b.CreatedAt})
+	}
+
+	items := make([]*repo.Blog, len(result.Items))
+	for i := range result.Items {
+		items[i] = &result.Items[i].Blog
+	}
+	h.decorate(c, items...)
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	blogGear "dietku-backend/cmd/blog/gear"
+	"dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/log"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strconv"
+)
+
+// editor describes the signed in user as the author of a change.
+func (h *BlogHandler) editor(c echo.Context) repo.By {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	return repo.By{
+		ID:       tokenData.ID,
+		Email:    tokenData.Email,
+		FullName: tokenData.FirstName + " " + tokenData.LastName,
+		At:       h.clock.Now(),
+	}
+}
+
+// snapshot records after as a new revision. A blog written before revisions
+// existed first gets its state before the change recorded, so the change can
+// still be diffed. Failures are logged only: the blog itself is saved already.
+func (h *BlogHandler) snapshot(before *repo.Blog, after *repo.Blog, editor repo.By, restoredFrom int) {
+	if before != nil {
+		latest, err := h.revisionRepo.Latest(before.ID)
+		if err != nil {
+			log.Errorf("failed to get revisions of blog %s: %v", before.ID.Hex(), err)
+			return
+		}
+		if latest == 0 {
+			by := before.CreatedBy
+			if before.UpdatedBy != nil {
+				by = *before.UpdatedBy
+			}
+			if err := h.revisionRepo.InsertNext(repo.NewRevision(before, by)); err != nil {
+				log.Errorf("failed to store revision of blog %s: %v", before.ID.Hex(), err)
+				return
+			}
+		}
+	}
+
+	revision := repo.NewRevision(after, editor)
+	revision.RestoredFrom = restoredFrom
+	if err := h.revisionRepo.InsertNext(revision); err != nil {
+		log.Errorf("failed to store revision of blog %s: %v", after.ID.Hex(), err)
+	}
+}
+
+// revision loads revision number of blog from the named parameter.
+func (h *BlogHandler) revision(c echo.Context, blog *repo.Blog, value string) (*repo.Revision, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid revision number", c)
+	}
+
+	revision, err := h.revisionRepo.FindOne(blog.ID, number)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Revision not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting revision.", c)
+	}
+	return revision, nil
+}
+
+// Revisions
+// @Tags Blog
+// @Summary Get Blog Revisions
+// @Description Every saved version of the post, newest first.
+// @ID blog-revisions
+// @Router /api/blog/{id}/revisions [get]
+// @Param id path string true "Blog ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) Revisions(c echo.Context) error {
+	blog, err := h.ownBlog(c, "view the revisions of")
+	if err != nil {
+		return err
+	}
+
+	docs, err := h.revisionRepo.FindByBlog(blog.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting revisions.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+type CategoryDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type RevisionDiff struct {
+	From     int               `json:"from"`
+	To       int               `json:"to"`
+	Header   []blogGear.Change `json:"header"`
+	Content  []blogGear.Change `json:"content"`
+	Category CategoryDiff      `json:"category"`
+}
+
+func diffCategories(old []string, new []string) CategoryDiff {
+	diff := CategoryDiff{Added: []string{}, Removed: []string{}}
+	in := func(list []string, v string) bool {
+		for _, s := range list {
+			if s == v {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range new {
+		if !in(old, v) {
+			diff.Added = append(diff.Added, v)
+		}
+	}
+	for _, v := range old {
+		if !in(new, v) {
+			diff.Removed = append(diff.Removed, v)
+		}
+	}
+	return diff
+}
+
+// RevisionDiff
+// @Tags Blog
+// @Summary Diff Blog Revisions
+// @Description Line by line changes of header and content from one revision to another, plus added and removed categories.
+// @ID blog-revisions-diff
+// @Router /api/blog/{id}/revisions/diff [get]
+// @Param id path string true "Blog ID"
+// @Param from query int true "Old revision number"
+// @Param to query int true "New revision number"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) RevisionDiff(c echo.Context) error {
+	blog, err := h.ownBlog(c, "view the revisions of")
+	if err != nil {
+		return err
+	}
+
+	from, err := h.revision(c, blog, c.QueryParam("from"))
+	if err != nil {
+		return err
+	}
+	to, err := h.revision(c, blog, c.QueryParam("to"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &RevisionDiff{
+		From:     from.Number,
+		To:       to.Number,
+		Header:   blogGear.Diff(from.Header, to.Header),
+		Content:  blogGear.Diff(from.Content, to.Content),
+		Category: diffCategories(from.Category, to.Category),
+	})
+}
+
+// RestoreRevision
+// @Tags Blog
+// @Summary Restore Blog Revision
+// @Description Brings back the content and images of an older revision, saved as a new revision. Images deleted from the library since are left out.
+// @ID blog-revisions-restore
+// @Router /api/blog/{id}/revisions/{number}/restore [post]
+// @Param id path string true "Blog ID"
+// @Param number path int true "Revision number"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) RestoreRevision(c echo.Context) error {
+	blog, err := h.ownBlog(c, "restore")
+	if err != nil {
+		return err
+	}
+
+	revision, err := h.revision(c, blog, c.Param("number"))
+	if err != nil {
+		return err
+	}
+
+	before := *blog
+	editor := h.editor(c)
+	blog.Header = revision.Header
+	format := revision.Format
+	if format == "" {
+		// revisions older than formats were all plain text
+		format = blogGear.FormatPlain
+	}
+	setContent(blog, revision.Content, format)
+	blog.Category = revision.Category
+	setLanguage(blog, revision.Language)
+	if err := h.restoreMedia(c, blog, revision); err != nil {
+		return err
+	}
+	blog.UpdatedBy = &editor
+
+	docs, err := h.repo.UpdateOne(blog)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating blog.", c)
+	}
+	h.snapshot(&before, docs, editor, revision.Number)
+	h.attach(docs)
+	return c.JSON(http.StatusOK, docs)
+}
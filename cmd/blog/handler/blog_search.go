@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	blogGear "dietku-backend/cmd/blog/gear"
+	"dietku-backend/cmd/blog/repo"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+const (
+	maxQueryLength = 200
+	maxSearchPage  = 50
+)
+
+type Highlight struct {
+	Header  string `json:"header"`
+	Snippet string `json:"snippet"`
+}
+
+type SearchHit struct {
+	repo.SearchResult
+	Highlight Highlight `json:"highlight"`
+}
+
+type SearchPage struct {
+	Items    []SearchHit `json:"items"`
+	Terms    []string    `json:"terms"`
+	Language string      `json:"language"`
+	Page     int         `json:"page"`
+	Limit    int64       `json:"limit"`
+	Total    int64       `json:"total"`
+}
+
+// Search
+// @Tags Blog
+// @Summary Search Blogs
+// @Description Full-text search over header and content, most relevant first. Signed in authors also find their own unpublished posts. Highlights are HTML escaped with matches wrapped in <mark>. The query language decides stop words and stemming; it is guessed when lang is not given.
+// @ID blog-search
+// @Router /api/blog/search [get]
+// @Param q query string true "Search text"
+// @Param lang query string false "id or en"
+// @Param page query int false "Page, starting at 1"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param author query string false "Author user ID"
+// @Param category query []string false "Category slugs, repeated or comma separated"
+// @Param match query string false "any (default) or all categories"
+// @Produce json
+// @Success 200
+func (h *BlogHandler) Search(c echo.Context) error {
+	text := c.QueryParam("q")
+	if len(text) > maxQueryLength {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Query must not exceed %d characters.", maxQueryLength), c)
+	}
+
+	lang := c.QueryParam("lang")
+	if lang == "" {
+		lang = blogGear.DetectLanguage(text)
+	} else if !blogGear.IsValidLanguage(lang) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Lang must be id or en.", c)
+	}
+
+	terms := blogGear.Terms(text, lang)
+	if len(terms) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Query is required.", c)
+	}
+
+	page, err := gear.QueryInt(c, "page", 1, maxSearchPage)
+	if err != nil {
+		return err
+	}
+
+	list, err := NewListQuery(c)
+	if err != nil {
+		return err
+	}
+	if me, ok := c.Get("me").(*gear.UserClaims); ok {
+		list.Viewer = &me.ID
+	}
+
+	q := &repo.SearchQuery{
+		ListQuery: *list,
+		Terms:     terms,
+		Language:  blogGear.SearchLanguage(lang),
+		Skip:      int64(page-1) * list.Limit,
+	}
+
+	results, err := h.repo.Search(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while searching blog.", c)
+	}
+
+	total, err := h.repo.CountSearch(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while searching blog.", c)
+	}
+
+	blogs := make([]*repo.Blog, len(results))
+	for i := range results {
+		blogs[i] = &results[i].Blog
+	}
+	h.decorate(c, blogs...)
+
+	hits := make([]SearchHit, len(results))
+	for i, r := range results {
+		hits[i] = SearchHit{
+			SearchResult: r,
+			Highlight: Highlight{
+				Header:  blogGear.Highlight(r.Header, terms),
+				Snippet: blogGear.Snippet(r.Content, terms),
+			},
+		}
+	}
+
+	return c.JSON(http.StatusOK, &SearchPage{
+		Items:    hits,
+		Terms:    terms,
+		Language: lang,
+		Page:     page,
+		Limit:    list.Limit,
+		Total:    total,
+	})
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/event"
+	"dietku-backend/cmd/log"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const (
+	publisherName     = "blog-publisher"
+	publisherInterval = time.Minute
+)
+
+// canSee tells whether the requester may read blog: anyone once it is
+// published, otherwise only its author.
+func canSee(c echo.Context, blog *repo.Blog) bool {
+	if blog.Status == repo.StatusPublished {
+		return true
+	}
+	me, ok := c.Get("me").(*gear.UserClaims)
+	return ok && me.ID == blog.CreatedBy.ID
+}
+
+// publish makes blog public now, or schedules it when at is in the future.
+// Republishing keeps the original publishedAt.
+func publish(blog *repo.Blog, at *time.Time, now time.Time) {
+	if at != nil && at.After(now) {
+		blog.Status = repo.StatusScheduled
+		blog.ScheduledAt = at
+		return
+	}
+
+	blog.Status = repo.StatusPublished
+	blog.ScheduledAt = nil
+	if blog.PublishedAt == nil {
+		blog.PublishedAt = &now
+	}
+}
+
+// PublishDue publishes the scheduled posts due at now.
+func (h *BlogHandler) PublishDue(now time.Time) error {
+	due, err := h.repo.FindDue(now)
+	if err != nil {
+		return err
+	}
+
+	for i := range *due {
+		blog := &(*due)[i]
+		published, err := h.repo.PublishScheduled(blog)
+		if err != nil {
+			return err
+		}
+		if published {
+			log.Infof("published scheduled blog %s", blog.ID.Hex())
+			event.Publish(event.Event{Type: event.BlogPublished, UserID: blog.CreatedBy.ID, At: now})
+		}
+	}
+	return nil
+}
+
+// Publish
+// @Tags Blog
+// @Summary Publish Blog
+// @Description Publishes the post now, or schedules it when scheduledAt is in the future.
+// @ID blog-publish
+// @Router /api/blog/{id}/publish [post]
+// @Accept json
+// @Param id path string true "Blog ID"
+// @Param body body PublishForm false "publish body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) Publish(c echo.Context) error {
+	form, err := NewPublishForm(c)
+	if err != nil {
+		return err
+	}
+
+	blog, err := h.ownBlog(c, "publish")
+	if err != nil {
+		return err
+	}
+
+	previous, now := blog.Status, h.clock.Now()
+	publish(blog, form.ScheduledAt, now)
+	return h.saveStatus(c, blog, previous)
+}
+
+// Unpublish
+// @Tags Blog
+// @Summary Unpublish Blog
+// @Description Turns a published or scheduled post back into a draft.
+// @ID blog-unpublish
+// @Router /api/blog/{id}/unpublish [post]
+// @Param id path string true "Blog ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) Unpublish(c echo.Context) error {
+	blog, err := h.ownBlog(c, "unpublish")
+	if err != nil {
+		return err
+	}
+
+	previous := blog.Status
+	blog.Status = repo.StatusDraft
+	blog.ScheduledAt = nil
+	return h.saveStatus(c, blog, previous)
+}
+
+// Archive
+// @Tags Blog
+// @Summary Archive Blog
+// @Description Hides the post from everyone but its author without deleting it.
+// @ID blog-archive
+// @Router /api/blog/{id}/archive [post]
+// @Param id path string true "Blog ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BlogHandler) Archive(c echo.Context) error {
+	blog, err := h.ownBlog(c, "archive")
+	if err != nil {
+		return err
+	}
+
+	previous := blog.Status
+	blog.Status = repo.StatusArchived
+	blog.ScheduledAt = nil
+	return h.saveStatus(c, blog, previous)
+}
+
+// saveStatus stores the new status of blog, which was previous before. Only
+// becoming published counts as publishing, not publishing again.
+func (h *BlogHandler) saveStatus(c echo.Context, blog *repo.Blog, previous string) error {
+	docs, err := h.repo.UpdateOne(blog)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating blog.", c)
+	}
+	if previous != repo.StatusPublished && docs.Status == repo.StatusPublished {
+		event.Publish(event.Event{Type: event.BlogPublished, UserID: docs.CreatedBy.ID, At: h.clock.Now()})
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// ownBlog loads the blog of the id parameter, making sure it belongs to the
+// signed in user; action completes the error message.
+func (h *BlogHandler) ownBlog(c echo.Context, action string) (*repo.Blog, error) {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid blog id", c)
+	}
+
+	blog, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Blog not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	if tokenData.ID != blog.CreatedBy.ID {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to "+action+" this blog", c)
+	}
+	return blog, nil
+}
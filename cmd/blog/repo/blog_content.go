@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FindUnrendered returns the posts saved before content was rendered on save.
+func (r *BlogRepository) FindUnrendered() (*Blogs, error) {
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"format": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsBlogs(cursor)
+}
+
+// SaveRendered stores the format and rendered content of blog, leaving the
+// rest of it alone.
+func (r *BlogRepository) SaveRendered(blog *Blog) error {
+	update := bson.M{"$set": bson.M{
+		"format":      blog.Format,
+		"contentHtml": blog.ContentHTML,
+		"excerpt":     blog.Excerpt,
+		"readingTime": blog.ReadingTime,
+	}}
+	_, err := r.coll.UpdateOne(context.TODO(), bson.M{"_id": blog.ID}, update)
+	return err
+}
+
+// Categories returns every category value blogs use, in no particular order.
+func (r *BlogRepository) Categories() ([]string, error) {
+	values, err := r.coll.Distinct(context.TODO(), "category", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			categories = append(categories, s)
+		}
+	}
+	return categories, nil
+}
+
+// CountByCategory counts the published posts of every category.
+func (r *BlogRepository) CountByCategory() (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": StatusPublished, "isDeleted": bson.M{"$ne": true}}}},
+		{{Key: "$unwind", Value: "$category"}},
+		{{Key: "$group", Value: bson.M{"_id": "$category", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Category string `bson:"_id"`
+		Count    int64  `bson:"count"`
+	}
+	if err := cursor.All(context.TODO(), &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Category] = row.Count
+	}
+	return counts, nil
+}
+
+// CountCategory counts the blogs, deleted ones excluded, in a category.
+func (r *BlogRepository) CountCategory(category string) (int64, error) {
+	return r.coll.CountDocuments(context.TODO(), bson.M{"category": category, "isDeleted": bson.M{"$ne": true}})
+}
+
+// RenameCategory replaces the category from with to in every blog, deleted
+// ones included, and drops the duplicate when a blog already had to. The
+// blogs count as changed, so the feeds pick the new category up.
+func (r *BlogRepository) RenameCategory(from string, to string) (int64, error) {
+	filter := bson.M{"category": from}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"category": bson.M{"$reduce": bson.M{
+				"input":        "$category",
+				"initialValue": bson.A{},
+				"in": bson.M{"$let": bson.M{
+					"vars": bson.M{"c": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$this", from}}, to, "$$this"}}},
+					"in": bson.M{"$cond": bson.A{
+						bson.M{"$in": bson.A{"$$c", "$$value"}},
+						"$$value",
+						bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$c"}}},
+					}},
+				}},
+			}},
+			"changedAt": "$$NOW",
+		}},
+	}
+
+	result, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
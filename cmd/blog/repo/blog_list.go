@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+
+	// countLimit caps how far Count counts; beyond it the total is an
+	// estimate that only says "at least this many".
+	countLimit = 10000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last blog of a page. Blogs are ordered by creation time
+// and then id, so the pair is unique even when posts share a timestamp.
+type Cursor struct {
+	Sort string
+	At   time.Time
+	ID   primitive.ObjectID
+}
+
+// Encode makes the cursor opaque to clients, e.g. "bmV3ZXN0fDE3OTA4NDE2MDAxMjN8..."
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%s|%d|%s", c.Sort, c.At.UnixMilli(), c.ID.Hex())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	ms, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Sort: parts[0], At: time.UnixMilli(ms), ID: id}, nil
+}
+
+// ListQuery selects a page of blogs. Empty fields do not filter. With
+// MatchAll a blog needs every category, otherwise any one of them. Only
+// published posts are listed, except those of Viewer, the signed in user.
+type ListQuery struct {
+	Viewer     *primitive.ObjectID
+	Status     string
+	Author     *primitive.ObjectID
+	Categories []string
+	MatchAll   bool
+	From       *time.Time
+	To         *time.Time
+	Sort       string
+	After      *Cursor
+	Limit      int64
+}
+
+func (q *ListQuery) filter(withCursor bool) bson.M {
+	and := bson.A{bson.M{"isDeleted": bson.M{"$ne": true}}, visible(q.Viewer)}
+
+	if q.Status != "" {
+		and = append(and, bson.M{"status": q.Status})
+	}
+	and = append(and, q.scope()...)
+
+	if withCursor && q.After != nil {
+		op := "$lt"
+		if q.Sort == SortOldest {
+			op = "$gt"
+		}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"createdBy.at": bson.M{op: q.After.At}},
+			bson.M{"createdBy.at": q.After.At, "_id": bson.M{op: q.After.ID}},
+		}})
+	}
+	return bson.M{"$and": and}
+}
+
+// scope is the part of the filter on author, categories and creation dates,
+// which holds for a blog whatever its status.
+func (q *ListQuery) scope() bson.A {
+	and := bson.A{}
+	if q.Author != nil {
+		and = append(and, bson.M{"createdBy._id": *q.Author})
+	}
+	if len(q.Categories) > 0 {
+		op := "$in"
+		if q.MatchAll {
+			op = "$all"
+		}
+		and = append(and, bson.M{"category": bson.M{op: q.Categories}})
+	}
+	if q.From != nil {
+		and = append(and, bson.M{"createdBy.at": bson.M{"$gte": *q.From}})
+	}
+	if q.To != nil {
+		and = append(and, bson.M{"createdBy.at": bson.M{"$lt": *q.To}})
+	}
+	return and
+}
+
+// List returns the page of blogs after q.After. It reads one blog more than
+// q.Limit so the caller can tell whether another page follows.
+func (r *BlogRepository) List(q *ListQuery) (*Blogs, error) {
+	dir := -1
+	if q.Sort == SortOldest {
+		dir = 1
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdBy.at", Value: dir}, {Key: "_id", Value: dir}}).
+		SetLimit(q.Limit + 1)
+	cursor, err := r.coll.Find(context.TODO(), q.filter(true), opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsBlogs(cursor)
+}
+
+// Latest returns the q.Limit published blogs matching q that were published
+// last, newest first. Sort and cursor are ignored.
+func (r *BlogRepository) Latest(q *ListQuery) (*Blogs, error) {
+	filter := bson.M{"$and": append(bson.A{
+		bson.M{"isDeleted": bson.M{"$ne": true}},
+		bson.M{"status": StatusPublished},
+	}, q.scope()...)}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "publishedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(q.Limit)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsBlogs(cursor)
+}
+
+// LastChange returns when a blog matching q last changed in any way,
+// unpublishing and deletion included, or nil when none ever did. Status and
+// cursor are ignored.
+func (r *BlogRepository) LastChange(q *ListQuery) (*time.Time, error) {
+	filter := bson.M{"changedAt": bson.M{"$exists": true}}
+	if scope := q.scope(); len(scope) > 0 {
+		filter["$and"] = scope
+	}
+
+	var d = &Blog{}
+	opts := options.FindOne().SetSort(bson.D{{Key: "changedAt", Value: -1}}).SetProjection(bson.M{"changedAt": 1})
+	err := r.coll.FindOne(context.TODO(), filter, opts).Decode(d)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.ChangedAt, nil
+}
+
+// Count counts the blogs matching q regardless of the cursor, stopping at
+// countLimit.
+func (r *BlogRepository) Count(q *ListQuery) (int64, error) {
+	return r.coll.CountDocuments(context.TODO(), q.filter(false), options.Count().SetLimit(countLimit))
+}
@@ -2,10 +2,13 @@ package repo
 
 import (
 	"context"
+	mediaRepo "dietku-backend/cmd/media/repo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -16,14 +19,52 @@ type By struct {
 	At       time.Time          `json:"at" bson:"at"`
 }
 
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Blog is a post. Language is "id" or "en"; SearchLanguage is the matching
+// language of the text index, kept out of responses. Only published posts are
+// public; a scheduled post is published by the worker at ScheduledAt.
+// Content is the source as written in Format, plain or markdown; ContentHTML,
+// Excerpt and ReadingTime (in minutes) are rendered from it on save. Media are
+// the ids of the images the post uses from its author's library.
+// Reactions counts the reactions per type; Me is what the signed in user did
+// to the post and is never stored. ChangedAt is when the post was last saved,
+// its status changed or it was deleted, as the database clock has it.
 type Blog struct {
-	ID        primitive.ObjectID `json:"_id" bson:"_id"`
-	Header    string             `json:"header" bson:"header"`
-	Content   string             `json:"content" bson:"content"`
-	Category  []string           `json:"category" bson:"category"`
-	CreatedBy By                 `json:"createdBy" bson:"createdBy"`
-	UpdatedBy *By                `json:"updatedBy,omitempty" bson:"updatedBy,omitempty"`
-	IsDeleted bool               `json:"isDeleted" bson:"isDeleted"`
+	ID             primitive.ObjectID   `json:"_id" bson:"_id"`
+	Header         string               `json:"header" bson:"header"`
+	Content        string               `json:"content" bson:"content"`
+	Format         string               `json:"format" bson:"format"`
+	ContentHTML    string               `json:"contentHtml" bson:"contentHtml"`
+	Excerpt        string               `json:"excerpt" bson:"excerpt"`
+	ReadingTime    int                  `json:"readingTime" bson:"readingTime"`
+	Media          []primitive.ObjectID `json:"media" bson:"media"`
+	Category       []string             `json:"category" bson:"category"`
+	Language       string               `json:"language" bson:"language"`
+	SearchLanguage string               `json:"-" bson:"searchLanguage"`
+	Status         string               `json:"status" bson:"status"`
+	ScheduledAt    *time.Time           `json:"scheduledAt,omitempty" bson:"scheduledAt"`
+	PublishedAt    *time.Time           `json:"publishedAt,omitempty" bson:"publishedAt"`
+	CommentCount   int                  `json:"commentCount" bson:"commentCount"`
+	Reactions      map[string]int       `json:"reactions" bson:"reactions,omitempty"`
+	BookmarkCount  int                  `json:"bookmarkCount" bson:"bookmarkCount"`
+	Me             *Interaction         `json:"me,omitempty" bson:"-"`
+	CreatedBy      By                   `json:"createdBy" bson:"createdBy"`
+	UpdatedBy      *By                  `json:"updatedBy,omitempty" bson:"updatedBy,omitempty"`
+	ChangedAt      *time.Time           `json:"-" bson:"changedAt,omitempty"`
+	IsDeleted      bool                 `json:"isDeleted" bson:"isDeleted"`
+}
+
+// Interaction lists the reactions of the signed in user on a blog and whether
+// they bookmarked it.
+type Interaction struct {
+	Reactions  []string `json:"reactions"`
+	Bookmarked bool     `json:"bookmarked"`
 }
 
 type Blogs []Blog
@@ -47,12 +88,29 @@ func NewBlogRepository(db *mongo.Database) *BlogRepository {
 	}
 }
 
-func (r *BlogRepository) FindAll() (*Blogs, error) {
-	cursor, err := r.coll.Find(context.TODO(), bson.M{"isDeleted": bson.M{"$ne": true}})
-	if err != nil {
-		return nil, err
-	}
-	return DecodeAsBlogs(cursor)
+// EnsureIndexes backs the listing: all blogs, an author's and a category's,
+// each in creation order, and the weighted text index used by Search. The
+// media index finds the posts using an image; the publishedAt and changedAt
+// ones serve the feeds.
+func (r *BlogRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdBy.at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "createdBy._id", Value: 1}, {Key: "createdBy.at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "category", Value: 1}, {Key: "createdBy.at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "scheduledAt", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "publishedAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "changedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "media", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "header", Value: "text"}, {Key: "content", Value: "text"}},
+			Options: options.Index().
+				SetName("blog_text").
+				SetWeights(bson.M{"header": 10, "content": 2}).
+				SetDefaultLanguage("none").
+				SetLanguageOverride("searchLanguage"),
+		},
+	})
+	return err
 }
 
 func (r *BlogRepository) FindOne(id primitive.ObjectID) (*Blog, error) {
@@ -64,47 +122,166 @@ func (r *BlogRepository) FindOne(id primitive.ObjectID) (*Blog, error) {
 	return d, nil
 }
 
-func (r *BlogRepository) FindByCategory(category string) (*Blogs, error) {
-	cursor, err := r.coll.Find(context.TODO(), bson.M{"category": category, "isDeleted": bson.M{"$ne": true}})
+// FindByIDs returns the blogs with the given ids, in no particular order.
+func (r *BlogRepository) FindByIDs(ids []primitive.ObjectID) (*Blogs, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}, "isDeleted": bson.M{"$ne": true}}
+	cursor, err := r.coll.Find(context.TODO(), filter)
 	if err != nil {
 		return nil, err
 	}
 	return DecodeAsBlogs(cursor)
 }
 
-func (r *BlogRepository) FindByUser(userID primitive.ObjectID) (*Blogs, error) {
-	cursor, err := r.coll.Find(context.TODO(), bson.M{"createdBy._id": userID, "isDeleted": bson.M{"$ne": true}})
+// UsesMedia tells whether a blog that is not deleted uses the media, in its
+// media list or by a path to it in its content.
+func (r *BlogRepository) UsesMedia(id primitive.ObjectID) (bool, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"media": id},
+			bson.M{"content": mediaPaths([]primitive.ObjectID{id})},
+		},
+		"isDeleted": bson.M{"$ne": true},
+	}
+	err := r.coll.FindOne(context.TODO(), filter).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UsedMedia returns which of ids blogs that are not deleted use. The media
+// lists are looked up through their index; only the ids none lists are then
+// searched for as paths in the content, all in one query.
+func (r *BlogRepository) UsedMedia(ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	used := map[primitive.ObjectID]bool{}
+	if len(ids) == 0 {
+		return used, nil
+	}
+
+	filter := bson.M{"media": bson.M{"$in": ids}, "isDeleted": bson.M{"$ne": true}}
+	listed, err := r.coll.Distinct(context.TODO(), "media", filter)
 	if err != nil {
 		return nil, err
 	}
-	return DecodeAsBlogs(cursor)
+	for _, v := range listed {
+		if id, ok := v.(primitive.ObjectID); ok {
+			used[id] = true
+		}
+	}
+
+	rest := []primitive.ObjectID{}
+	for _, id := range ids {
+		if !used[id] {
+			rest = append(rest, id)
+		}
+	}
+	if len(rest) == 0 {
+		return used, nil
+	}
+
+	filter = bson.M{"content": mediaPaths(rest), "isDeleted": bson.M{"$ne": true}}
+	opts := options.Find().SetProjection(bson.M{"content": 1})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	blogs, err := DecodeAsBlogs(cursor)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range *blogs {
+		for _, id := range linkedMedia(rest, b.Content) {
+			used[id] = true
+		}
+	}
+	return used, nil
+}
+
+// mediaPaths matches content with a path to any of ids.
+func mediaPaths(ids []primitive.ObjectID) primitive.Regex {
+	paths := make([]string, len(ids))
+	for i, id := range ids {
+		paths[i] = regexp.QuoteMeta(mediaRepo.PathPrefix(id))
+	}
+	return primitive.Regex{Pattern: strings.Join(paths, "|")}
+}
+
+// linkedMedia returns which of ids content has a path to.
+func linkedMedia(ids []primitive.ObjectID, content string) []primitive.ObjectID {
+	linked := []primitive.ObjectID{}
+	for _, id := range ids {
+		if strings.Contains(content, mediaRepo.PathPrefix(id)) {
+			linked = append(linked, id)
+		}
+	}
+	return linked
 }
 
 func (r *BlogRepository) InsertOne(newBlog *Blog) (*mongo.InsertOneResult, error) {
 	return r.coll.InsertOne(context.TODO(), newBlog)
 }
 
+// counters are kept up to date with $inc by other packages, so UpdateOne must
+// not write back the possibly stale values of the blog it was given.
+var counters = []string{"commentCount", "reactions", "bookmarkCount"}
+
 func (r *BlogRepository) UpdateOne(blog *Blog) (*Blog, error) {
 	filter := bson.M{"_id": blog.ID}
 
+	raw, err := bson.Marshal(blog)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+	for _, field := range counters {
+		delete(set, field)
+	}
+	// set by the database below
+	delete(set, "changedAt")
+
 	update := bson.M{
-		"$set": blog,
+		"$set":         set,
+		"$currentDate": bson.M{"changedAt": true},
 	}
 
 	var d = &Blog{}
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	err = r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
 	if err != nil {
 		return nil, err
 	}
 	return d, nil
 }
 
+// IncCommentCount adds delta to the number of comments of a blog.
+func (r *BlogRepository) IncCommentCount(id primitive.ObjectID, delta int) error {
+	return r.inc(id, "commentCount", delta)
+}
+
+// IncReaction adds delta to the count of one reaction type of a blog.
+func (r *BlogRepository) IncReaction(id primitive.ObjectID, typ string, delta int) error {
+	return r.inc(id, "reactions."+typ, delta)
+}
+
+// IncBookmarkCount adds delta to the number of users who bookmarked a blog.
+func (r *BlogRepository) IncBookmarkCount(id primitive.ObjectID, delta int) error {
+	return r.inc(id, "bookmarkCount", delta)
+}
+
+func (r *BlogRepository) inc(id primitive.ObjectID, field string, delta int) error {
+	_, err := r.coll.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$inc": bson.M{field: delta}})
+	return err
+}
+
 func (r *BlogRepository) DeleteOne(id primitive.ObjectID) (*Blog, error) {
 	filter := bson.M{"_id": id}
 
 	update := bson.M{
-		"$set": bson.M{"isDeleted": true},
+		"$set":         bson.M{"isDeleted": true},
+		"$currentDate": bson.M{"changedAt": true},
 	}
 
 	var d = &Blog{}
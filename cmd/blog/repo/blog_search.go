@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+)
+
+// SearchQuery looks for Terms with the filters of ListQuery; its cursor and
+// sort are ignored since results are ordered by relevance.
+type SearchQuery struct {
+	ListQuery
+	Terms []string
+	// Language is the Mongo text search language, "english" or "none".
+	Language string
+	Skip     int64
+}
+
+type SearchResult struct {
+	Blog  `bson:",inline"`
+	Score float64 `json:"score" bson:"score"`
+}
+
+func (q *SearchQuery) filter() bson.M {
+	filter := q.ListQuery.filter(false)
+	filter["$text"] = bson.M{"$search": strings.Join(q.Terms, " "), "$language": q.Language}
+	return filter
+}
+
+// Search returns the blogs matching any of the terms, most relevant first.
+// Header matches weigh five times as much as content matches.
+func (r *BlogRepository) Search(q *SearchQuery) ([]SearchResult, error) {
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "createdBy.at", Value: -1}}).
+		SetSkip(q.Skip).
+		SetLimit(q.Limit)
+	cursor, err := r.coll.Find(context.TODO(), q.filter(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []SearchResult{}
+	if err := cursor.All(context.TODO(), &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *BlogRepository) CountSearch(q *SearchQuery) (int64, error) {
+	return r.coll.CountDocuments(context.TODO(), q.filter(), options.Count().SetLimit(countLimit))
+}
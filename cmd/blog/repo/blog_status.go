@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+var Statuses = []string{StatusDraft, StatusScheduled, StatusPublished, StatusArchived}
+
+func IsValidStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateStatus publishes the posts written before there were statuses, when
+// every post went public on creation.
+func (r *BlogRepository) MigrateStatus() (int64, error) {
+	filter := bson.M{"status": bson.M{"$exists": false}}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{"status": StatusPublished, "publishedAt": "$createdBy.at"}},
+	}
+
+	result, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// FindDue returns the scheduled posts whose time has come.
+func (r *BlogRepository) FindDue(now time.Time) (*Blogs, error) {
+	filter := bson.M{
+		"status":      StatusScheduled,
+		"scheduledAt": bson.M{"$lte": now},
+		"isDeleted":   bson.M{"$ne": true},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "scheduledAt", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsBlogs(cursor)
+}
+
+// PublishScheduled publishes a scheduled post as of its scheduled time, or
+// keeps its publishedAt when it was published before. It reports false when
+// the post was unscheduled or already published meanwhile.
+func (r *BlogRepository) PublishScheduled(blog *Blog) (bool, error) {
+	filter := bson.M{"_id": blog.ID, "status": StatusScheduled, "scheduledAt": blog.ScheduledAt}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"status":      StatusPublished,
+			"publishedAt": bson.M{"$ifNull": bson.A{"$publishedAt", blog.ScheduledAt}},
+			"scheduledAt": nil,
+			"changedAt":   "$$NOW",
+		}},
+	}
+
+	result, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// visible limits filter to published posts, plus every post of viewer when
+// someone is signed in.
+func visible(viewer *primitive.ObjectID) bson.M {
+	if viewer == nil {
+		return bson.M{"status": StatusPublished}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"status": StatusPublished},
+		bson.M{"createdBy._id": *viewer},
+	}}
+}
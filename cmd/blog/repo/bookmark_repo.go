@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type Bookmark struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	BlogID    primitive.ObjectID `json:"blogId" bson:"blogId"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+type Bookmarks []Bookmark
+
+func DecodeAsBookmarks(cursor *mongo.Cursor) (*Bookmarks, error) {
+	docs := Bookmarks{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type BookmarkRepository struct {
+	coll *mongo.Collection
+}
+
+func NewBookmarkRepository(db *mongo.Database) *BookmarkRepository {
+	return &BookmarkRepository{
+		coll: db.Collection("blog_bookmarks"),
+	}
+}
+
+// EnsureIndexes creates the unique index allowing one bookmark per user and
+// blog, and the one listing a user's bookmarks newest first.
+func (r *BookmarkRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "blogId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+func (r *BookmarkRepository) FindByUser(userID primitive.ObjectID, skip int64, limit int64) (*Bookmarks, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsBookmarks(cursor)
+}
+
+func (r *BookmarkRepository) Count(userID primitive.ObjectID) (int64, error) {
+	return r.coll.CountDocuments(context.TODO(), bson.M{"userId": userID})
+}
+
+// Bookmarked tells which of the blogs the user bookmarked.
+func (r *BookmarkRepository) Bookmarked(userID primitive.ObjectID, blogIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"userId": userID, "blogId": bson.M{"$in": blogIDs}})
+	if err != nil {
+		return nil, err
+	}
+	docs, err := DecodeAsBookmarks(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarked := map[primitive.ObjectID]bool{}
+	for _, d := range *docs {
+		bookmarked[d.BlogID] = true
+	}
+	return bookmarked, nil
+}
+
+// Add bookmarks the blog. It reports false when it was bookmarked already.
+func (r *BookmarkRepository) Add(userID primitive.ObjectID, blogID primitive.ObjectID, at time.Time) (bool, error) {
+	_, err := r.coll.InsertOne(context.TODO(), &Bookmark{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		BlogID:    blogID,
+		CreatedAt: at,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Remove drops the bookmark. It reports false when there was none.
+func (r *BookmarkRepository) Remove(userID primitive.ObjectID, blogID primitive.ObjectID) (bool, error) {
+	result, err := r.coll.DeleteOne(context.TODO(), bson.M{"userId": userID, "blogId": blogID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
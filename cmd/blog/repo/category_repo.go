@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Category is an entry of the blog taxonomy. Blogs refer to categories by
+// Slug; NameID and NameEN are the display names in Indonesian and English.
+// Categories are listed by Order.
+type Category struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id"`
+	Slug        string             `json:"slug" bson:"slug"`
+	NameID      string             `json:"nameId" bson:"nameId"`
+	NameEN      string             `json:"nameEn" bson:"nameEn"`
+	Description string             `json:"description" bson:"description"`
+	Order       int                `json:"order" bson:"order"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	IsDeleted   bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Categories []Category
+
+func DecodeAsCategories(cursor *mongo.Cursor) (*Categories, error) {
+	docs := Categories{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type CategoryRepository struct {
+	coll *mongo.Collection
+}
+
+func NewCategoryRepository(db *mongo.Database) *CategoryRepository {
+	return &CategoryRepository{
+		coll: db.Collection("blog_categories"),
+	}
+}
+
+// EnsureIndexes makes slugs unique among the categories that are not deleted,
+// so a deleted slug can be used again.
+func (r *CategoryRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"isDeleted": false}),
+	})
+	return err
+}
+
+// FindAll returns every category in display order.
+func (r *CategoryRepository) FindAll() (*Categories, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "order", Value: 1}, {Key: "slug", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"isDeleted": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsCategories(cursor)
+}
+
+func (r *CategoryRepository) FindOne(id primitive.ObjectID) (*Category, error) {
+	var d = &Category{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *CategoryRepository) FindBySlug(slug string) (*Category, error) {
+	var d = &Category{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"slug": slug, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NextOrder returns the order that puts a new category last.
+func (r *CategoryRepository) NextOrder() (int, error) {
+	var d = &Category{}
+	opts := options.FindOne().SetSort(bson.D{{Key: "order", Value: -1}}).SetProjection(bson.M{"order": 1})
+	err := r.coll.FindOne(context.TODO(), bson.M{"isDeleted": bson.M{"$ne": true}}, opts).Decode(d)
+	if err == mongo.ErrNoDocuments {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return d.Order + 1, nil
+}
+
+func (r *CategoryRepository) InsertOne(newCategory *Category) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newCategory)
+}
+
+func (r *CategoryRepository) UpdateOne(category *Category) (*Category, error) {
+	filter := bson.M{"_id": category.ID}
+
+	update := bson.M{
+		"$set": category,
+	}
+
+	var d = &Category{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *CategoryRepository) DeleteOne(id primitive.ObjectID) (*Category, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Category{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionClap  = "clap"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+// ReactionTypes lists the reactions in display order.
+var ReactionTypes = []string{ReactionLike, ReactionLove, ReactionClap, ReactionWow, ReactionSad, ReactionAngry}
+
+// ReactionEmoji is the emoji each reaction is shown as.
+var ReactionEmoji = map[string]string{
+	ReactionLike:  "👍",
+	ReactionLove:  "❤️",
+	ReactionClap:  "👏",
+	ReactionWow:   "😮",
+	ReactionSad:   "😢",
+	ReactionAngry: "😠",
+}
+
+func IsValidReaction(typ string) bool {
+	_, ok := ReactionEmoji[typ]
+	return ok
+}
+
+// Reaction is one user's reaction of one type on a blog. A user can give
+// several types to the same blog but each only once.
+type Reaction struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	BlogID    primitive.ObjectID `json:"blogId" bson:"blogId"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Type      string             `json:"type" bson:"type"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+type Reactions []Reaction
+
+func DecodeAsReactions(cursor *mongo.Cursor) (*Reactions, error) {
+	docs := Reactions{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type ReactionRepository struct {
+	coll *mongo.Collection
+}
+
+func NewReactionRepository(db *mongo.Database) *ReactionRepository {
+	return &ReactionRepository{
+		coll: db.Collection("blog_reactions"),
+	}
+}
+
+// EnsureIndexes creates the unique index that keeps a reaction from being
+// counted twice; led by userId it also finds a user's reactions on a page of
+// blogs.
+func (r *ReactionRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "blogId", Value: 1}, {Key: "type", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Add records the reaction. It reports false when the user had already
+// reacted with that type.
+func (r *ReactionRepository) Add(blogID primitive.ObjectID, userID primitive.ObjectID, typ string, at time.Time) (bool, error) {
+	_, err := r.coll.InsertOne(context.TODO(), &Reaction{
+		ID:        primitive.NewObjectID(),
+		BlogID:    blogID,
+		UserID:    userID,
+		Type:      typ,
+		CreatedAt: at,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Remove takes the reaction back. It reports false when there was none.
+func (r *ReactionRepository) Remove(blogID primitive.ObjectID, userID primitive.ObjectID, typ string) (bool, error) {
+	result, err := r.coll.DeleteOne(context.TODO(), bson.M{"blogId": blogID, "userId": userID, "type": typ})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// FindByUser returns the reaction types the user gave to each of the blogs.
+func (r *ReactionRepository) FindByUser(userID primitive.ObjectID, blogIDs []primitive.ObjectID) (map[primitive.ObjectID][]string, error) {
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"userId": userID, "blogId": bson.M{"$in": blogIDs}})
+	if err != nil {
+		return nil, err
+	}
+	docs, err := DecodeAsReactions(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[primitive.ObjectID][]string{}
+	for _, d := range *docs {
+		types[d.BlogID] = append(types[d.BlogID], d.Type)
+	}
+	return types, nil
+}
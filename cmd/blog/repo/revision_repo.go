@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Revision is a snapshot of a blog's content after a change. Numbers start at
+// 1 per blog; RestoredFrom is set when the revision restored an older one.
+// Media is nil in revisions stored before images were snapshotted.
+type Revision struct {
+	ID           primitive.ObjectID   `json:"_id" bson:"_id"`
+	BlogID       primitive.ObjectID   `json:"blogId" bson:"blogId"`
+	Number       int                  `json:"number" bson:"number"`
+	Header       string               `json:"header" bson:"header"`
+	Content      string               `json:"content" bson:"content"`
+	Format       string               `json:"format,omitempty" bson:"format,omitempty"`
+	Category     []string             `json:"category" bson:"category"`
+	Language     string               `json:"language" bson:"language"`
+	Media        []primitive.ObjectID `json:"media,omitempty" bson:"media"`
+	EditedBy     By                   `json:"editedBy" bson:"editedBy"`
+	RestoredFrom int                  `json:"restoredFrom,omitempty" bson:"restoredFrom,omitempty"`
+}
+
+type Revisions []Revision
+
+func DecodeAsRevisions(cursor *mongo.Cursor) (*Revisions, error) {
+	docs := Revisions{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+// NewRevision snapshots blog as edited by editor.
+func NewRevision(blog *Blog, editor By) *Revision {
+	return &Revision{
+		ID:       primitive.NewObjectID(),
+		BlogID:   blog.ID,
+		Header:   blog.Header,
+		Content:  blog.Content,
+		Format:   blog.Format,
+		Category: blog.Category,
+		Language: blog.Language,
+		Media:    append([]primitive.ObjectID{}, blog.Media...),
+		EditedBy: editor,
+	}
+}
+
+type RevisionRepository struct {
+	coll *mongo.Collection
+}
+
+func NewRevisionRepository(db *mongo.Database) *RevisionRepository {
+	return &RevisionRepository{
+		coll: db.Collection("blog_revisions"),
+	}
+}
+
+// EnsureIndexes numbers the revisions of each blog; the media index finds the
+// revisions using an image.
+func (r *RevisionRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "blogId", Value: 1}, {Key: "number", Value: -1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "media", Value: 1}}},
+	})
+	return err
+}
+
+// FindByBlog returns the revisions of a blog, newest first.
+func (r *RevisionRepository) FindByBlog(blogID primitive.ObjectID) (*Revisions, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "number", Value: -1}})
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"blogId": blogID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsRevisions(cursor)
+}
+
+func (r *RevisionRepository) FindOne(blogID primitive.ObjectID, number int) (*Revision, error) {
+	var d = &Revision{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"blogId": blogID, "number": number}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Latest returns the number of the newest revision of a blog, 0 when there is
+// none yet.
+func (r *RevisionRepository) Latest(blogID primitive.ObjectID) (int, error) {
+	var d = &Revision{}
+	opts := options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}}).SetProjection(bson.M{"number": 1})
+	err := r.coll.FindOne(context.TODO(), bson.M{"blogId": blogID}, opts).Decode(d)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return d.Number, nil
+}
+
+// InsertNext stores revision with the number after the blog's newest one. Two
+// concurrent edits may pick the same number; the unique index rejects the
+// second, which then takes the following number.
+func (r *RevisionRepository) InsertNext(revision *Revision) error {
+	const attempts = 3
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		var latest int
+		latest, err = r.Latest(revision.BlogID)
+		if err != nil {
+			return err
+		}
+
+		revision.Number = latest + 1
+		_, err = r.coll.InsertOne(context.TODO(), revision)
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// UsedMedia returns which of ids revisions use, in their media list or by a
+// path in their content, each with the blogs those revisions belong to.
+func (r *RevisionRepository) UsedMedia(ids []primitive.ObjectID) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+	used := map[primitive.ObjectID][]primitive.ObjectID{}
+	if len(ids) == 0 {
+		return used, nil
+	}
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"media": bson.M{"$in": ids}},
+		bson.M{"content": mediaPaths(ids)},
+	}}
+	opts := options.Find().SetProjection(bson.M{"blogId": 1, "media": 1, "content": 1})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := DecodeAsRevisions(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	for _, rev := range *revisions {
+		refs := linkedMedia(ids, rev.Content)
+		for _, id := range rev.Media {
+			if wanted[id] {
+				refs = append(refs, id)
+			}
+		}
+		for _, id := range refs {
+			used[id] = append(used[id], rev.BlogID)
+		}
+	}
+	return used, nil
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// unitCm is how many cm one of each accepted unit holds.
+var unitCm = map[string]float64{
+	"cm": 1,
+	"in": 2.54,
+}
+
+var poses = map[string]bool{
+	"front": true,
+	"side":  true,
+	"back":  true,
+}
+
+const maxCircumference = 300
+
+type MeasurementForm struct {
+	Date  string  `json:"date"`
+	Unit  string  `json:"unit"`
+	Waist float64 `json:"waist"`
+	Hip   float64 `json:"hip"`
+	Chest float64 `json:"chest"`
+	Arm   float64 `json:"arm"`
+	Thigh float64 `json:"thigh"`
+}
+
+func NewMeasurementForm(c echo.Context, loc *time.Location) (*MeasurementForm, error) {
+	form := new(MeasurementForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	date, err := validateDate(form.Date, loc)
+	if err != nil {
+		return nil, err
+	}
+	form.Date = date
+
+	form.Unit = strings.ToLower(strings.TrimSpace(form.Unit))
+	if form.Unit == "" {
+		form.Unit = "cm"
+	}
+	cm, ok := unitCm[form.Unit]
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unit must be cm or in.")
+	}
+
+	values := []float64{form.Waist, form.Hip, form.Chest, form.Arm, form.Thigh}
+	measured := false
+	for _, v := range values {
+		if v < 0 || v*cm > maxCircumference {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Measurements must be between 0 and 300 cm.")
+		}
+		measured = measured || v > 0
+	}
+	if !measured {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "At least one measurement is required.")
+	}
+	return form, nil
+}
+
+// Cm converts v from the form's unit into cm.
+func (f *MeasurementForm) Cm(v float64) float64 {
+	return v * unitCm[f.Unit]
+}
+
+type PhotoForm struct {
+	Date string `form:"date"`
+	Pose string `form:"pose"`
+	Note string `form:"note"`
+}
+
+func NewPhotoForm(c echo.Context, loc *time.Location) (*PhotoForm, error) {
+	form := &PhotoForm{
+		Date: c.FormValue("date"),
+		Pose: strings.ToLower(strings.TrimSpace(c.FormValue("pose"))),
+		Note: strings.TrimSpace(c.FormValue("note")),
+	}
+
+	date, err := validateDate(form.Date, loc)
+	if err != nil {
+		return nil, err
+	}
+	form.Date = date
+
+	if form.Pose != "" && !poses[form.Pose] {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Pose must be one of front, side or back.")
+	}
+
+	if len(form.Note) > 500 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Note must not exceed 500 characters.")
+	}
+	return form, nil
+}
+
+// validateDate defaults to today and rejects dates in the future.
+func validateDate(value string, loc *time.Location) (string, error) {
+	date, _, _, err := gear.ParseDay(value, loc)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD")
+	}
+	if date > gear.Today(loc) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Date cannot be in the future.")
+	}
+	return date, nil
+}
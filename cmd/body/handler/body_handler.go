@@ -0,0 +1,426 @@
+package handler
+
+import (
+	"bytes"
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/body/repo"
+	"dietku-backend/cmd/log"
+	"dietku-backend/cmd/storage"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHistoryDays = 90
+	maxHistoryDays     = 366
+	maxPhotoSize       = 10 << 20
+	// photoURLTTL is how long a signed photo URL stays valid.
+	photoURLTTL = 15 * time.Minute
+)
+
+// photoTypes maps the accepted sniffed content types to file extensions.
+var photoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type BodyHandler struct {
+	repo     *repo.BodyRepository
+	userRepo *userRepo.UserRepository
+	storage  storage.Storage
+}
+
+func NewBodyApi(e *echo.Echo, db *mongo.Database, store storage.Storage) *BodyHandler {
+	b := &BodyHandler{
+		repo:     repo.NewBodyRepository(db),
+		userRepo: userRepo.NewUserRepository(db),
+		storage:  store,
+	}
+	if err := b.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create body indexes: %v", err)
+	}
+
+	// photo files are fetched by <img> tags, so they are authorized by the
+	// signed URL rather than the Authorization header
+	e.GET("/api/photos/:id/file", b.PhotoFile, gear.MaybeLoggedIn(db))
+
+	bGroup := e.Group("")
+	bGroup.Use(gear.IsLoggedIn(db))
+	{
+		bGroup.GET("/api/measurements", b.Measurements)
+		bGroup.GET("/api/photos", b.Photos)
+
+		bGroup.POST("/api/measurements", b.CreateMeasurement)
+		bGroup.POST("/api/photos", b.UploadPhoto)
+
+		bGroup.DELETE("/api/measurements/:id", b.DeleteMeasurement)
+		bGroup.DELETE("/api/photos/:id", b.DeletePhoto)
+	}
+	return b
+}
+
+type MeasurementView struct {
+	repo.Measurement
+	WaistToHip    float64 `json:"waistToHip,omitempty"`
+	WaistToHeight float64 `json:"waistToHeight,omitempty"`
+}
+
+type PhotoView struct {
+	repo.Photo
+	URL string `json:"url"`
+}
+
+// measurementView adds the ratios that can be computed from m. Height comes
+// from the user's profile, in cm.
+func measurementView(m repo.Measurement, height float64) MeasurementView {
+	v := MeasurementView{Measurement: m}
+	if m.Waist > 0 && m.Hip > 0 {
+		v.WaistToHip = m.Waist / m.Hip
+	}
+	if m.Waist > 0 && height > 0 {
+		v.WaistToHeight = m.Waist / height
+	}
+	return v
+}
+
+func photoPath(id primitive.ObjectID) string {
+	return "/api/photos/" + id.Hex() + "/file"
+}
+
+func photoView(p repo.Photo, claims *gear.UserClaims) PhotoView {
+	return PhotoView{Photo: p, URL: gear.SignURL(photoPath(p.ID), claims, photoURLTTL)}
+}
+
+func (h *BodyHandler) height(c echo.Context, userID primitive.ObjectID) (float64, error) {
+	user, err := h.userRepo.FindOne(userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+	return user.Height, nil
+}
+
+// Measurements
+// @Tags Body
+// @Summary Get Body Measurements
+// @Description Measurements in cm between from and to (default the last 90 days), newest first, with waist-to-hip and waist-to-height ratios. Waist-to-height needs the height on the user's profile.
+// @ID body-measurements
+// @Router /api/measurements [get]
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BodyHandler) Measurements(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	r, err := gear.ParseRange(c, tokenData.Location(), defaultHistoryDays, maxHistoryDays)
+	if err != nil {
+		return err
+	}
+	from, to := r.From, r.To
+
+	height, err := h.height(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	docs, err := h.repo.FindMeasurements(tokenData.ID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting measurements.", c)
+	}
+
+	views := make([]MeasurementView, 0, len(*docs))
+	for _, m := range *docs {
+		views = append(views, measurementView(m, height))
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// CreateMeasurement
+// @Tags Body
+// @Summary Log Body Measurements
+// @Description Waist, hip, chest, arm and thigh in cm or in. Only the measured values are required; date defaults to today.
+// @ID body-measurement-create
+// @Router /api/measurements [post]
+// @Accept json
+// @Param body body MeasurementForm true "measurement body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BodyHandler) CreateMeasurement(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	form, err := NewMeasurementForm(c, tokenData.Location())
+	if err != nil {
+		return err
+	}
+
+	height, err := h.height(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	m := repo.Measurement{
+		ID:        primitive.NewObjectID(),
+		UserID:    tokenData.ID,
+		Date:      form.Date,
+		Unit:      form.Unit,
+		Waist:     form.Cm(form.Waist),
+		Hip:       form.Cm(form.Hip),
+		Chest:     form.Cm(form.Chest),
+		Arm:       form.Cm(form.Arm),
+		Thigh:     form.Cm(form.Thigh),
+		CreatedAt: time.Now(),
+	}
+
+	_, err = h.repo.InsertMeasurement(&m)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while saving measurements.", c)
+	}
+	return c.JSON(http.StatusOK, measurementView(m, height))
+}
+
+// DeleteMeasurement
+// @Tags Body
+// @Summary Delete Body Measurements
+// @ID body-measurement-delete
+// @Router /api/measurements/{id} [delete]
+// @Produce json
+// @Param id path string true "Measurement ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BodyHandler) DeleteMeasurement(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid measurement id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	m, err := h.repo.FindMeasurement(oId, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Measurement not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting measurement.", c)
+	}
+
+	docs, err := h.repo.DeleteMeasurement(m.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting measurement.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Photos
+// @Tags Body
+// @Summary Get Progress Photos
+// @Description Photos between from and to (default the last 90 days), each with a signed URL that expires after 15 minutes.
+// @ID body-photos
+// @Router /api/photos [get]
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BodyHandler) Photos(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	r, err := gear.ParseRange(c, tokenData.Location(), defaultHistoryDays, maxHistoryDays)
+	if err != nil {
+		return err
+	}
+	from, to := r.From, r.To
+
+	docs, err := h.repo.FindPhotos(tokenData.ID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting photos.", c)
+	}
+
+	views := make([]PhotoView, 0, len(*docs))
+	for _, p := range *docs {
+		views = append(views, photoView(p, tokenData))
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// UploadPhoto
+// @Tags Body
+// @Summary Upload Progress Photo
+// @Description JPEG, PNG or WebP up to 10 MB; the type is detected from the content. Photos are private to their owner.
+// @ID body-photo-upload
+// @Router /api/photos [post]
+// @Accept multipart/form-data
+// @Param photo formData file true "photo file"
+// @Param date formData string false "Date (YYYY-MM-DD), defaults to today"
+// @Param pose formData string false "front, side or back"
+// @Param note formData string false "Note"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BodyHandler) UploadPhoto(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	form, err := NewPhotoForm(c, tokenData.Location())
+	if err != nil {
+		return err
+	}
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Photo file is required.", c)
+	}
+	if file.Size > maxPhotoSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Photo must not exceed 10 MB.", c)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid photo file.", c)
+	}
+	defer src.Close()
+
+	// read one byte past the limit to catch files lying about their size
+	data, err := io.ReadAll(io.LimitReader(src, maxPhotoSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid photo file.", c)
+	}
+	if len(data) > maxPhotoSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Photo must not exceed 10 MB.", c)
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := photoTypes[contentType]
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Photo must be a JPEG, PNG or WebP image.", c)
+	}
+
+	id := primitive.NewObjectID()
+	p := repo.Photo{
+		ID:          id,
+		UserID:      tokenData.ID,
+		Date:        form.Date,
+		Pose:        form.Pose,
+		Note:        form.Note,
+		Key:         "photos/" + tokenData.ID.Hex() + "/" + id.Hex() + ext,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		CreatedAt:   time.Now(),
+	}
+
+	ctx := c.Request().Context()
+	if err := h.storage.Put(ctx, p.Key, bytes.NewReader(data), contentType); err != nil {
+		log.Errorf("failed to store photo %s: %v", p.Key, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while storing photo.", c)
+	}
+
+	_, err = h.repo.InsertPhoto(&p)
+	if err != nil {
+		if err := h.storage.Delete(ctx, p.Key); err != nil {
+			log.Errorf("failed to remove photo %s: %v", p.Key, err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while saving photo.", c)
+	}
+	return c.JSON(http.StatusOK, photoView(p, tokenData))
+}
+
+// PhotoFile
+// @Tags Body
+// @Summary Get Progress Photo File
+// @Description Serves the image for a signed URL returned by the photo endpoints. When a token is sent as well it must belong to the photo's owner.
+// @ID body-photo-file
+// @Router /api/photos/{id}/file [get]
+// @Produce image/jpeg,image/png,image/webp
+// @Param id path string true "Photo ID"
+// @Param uid query string true "Signed user ID"
+// @Param expires query int true "Expiry (unix seconds)"
+// @Param sig query string true "Signature"
+// @Success 200
+func (h *BodyHandler) PhotoFile(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid photo id", c)
+	}
+
+	userID, err := gear.VerifyURL(photoPath(oId), c.QueryParams())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, "Invalid or expired photo link.", c)
+	}
+	if me, ok := c.Get("me").(*gear.UserClaims); ok && me.ID != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "Invalid or expired photo link.", c)
+	}
+
+	p, err := h.repo.FindPhoto(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Photo not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting photo.", c)
+	}
+	if p.UserID != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "Invalid or expired photo link.", c)
+	}
+
+	file, err := h.storage.Get(c.Request().Context(), p.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Photo not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while reading photo.", c)
+	}
+	defer file.Close()
+
+	c.Response().Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(photoURLTTL.Seconds())))
+	return c.Stream(http.StatusOK, p.ContentType, file)
+}
+
+// DeletePhoto
+// @Tags Body
+// @Summary Delete Progress Photo
+// @Description Removes the photo and its file.
+// @ID body-photo-delete
+// @Router /api/photos/{id} [delete]
+// @Produce json
+// @Param id path string true "Photo ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *BodyHandler) DeletePhoto(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid photo id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	p, err := h.repo.FindPhoto(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Photo not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting photo.", c)
+	}
+	if p.UserID != tokenData.ID {
+		return echo.NewHTTPError(http.StatusBadRequest, "Photo not found!", c)
+	}
+
+	docs, err := h.repo.DeletePhoto(p.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting photo.", c)
+	}
+
+	if err := h.storage.Delete(c.Request().Context(), p.Key); err != nil {
+		log.Errorf("failed to remove photo %s: %v", p.Key, err)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
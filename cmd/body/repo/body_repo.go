@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Measurement is a set of body circumferences taken on one date. Values are
+// stored in cm; Unit keeps what the user entered. Zero means not measured.
+type Measurement struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Date      string             `json:"date" bson:"date"`
+	Unit      string             `json:"unit" bson:"unit"`
+	Waist     float64            `json:"waist,omitempty" bson:"waist,omitempty"`
+	Hip       float64            `json:"hip,omitempty" bson:"hip,omitempty"`
+	Chest     float64            `json:"chest,omitempty" bson:"chest,omitempty"`
+	Arm       float64            `json:"arm,omitempty" bson:"arm,omitempty"`
+	Thigh     float64            `json:"thigh,omitempty" bson:"thigh,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	IsDeleted bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Measurements []Measurement
+
+func DecodeAsMeasurements(cursor *mongo.Cursor) (*Measurements, error) {
+	docs := Measurements{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+// Photo is a progress picture. The file lives in storage under Key and is
+// only served to its owner through signed URLs.
+type Photo struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID      primitive.ObjectID `json:"userId" bson:"userId"`
+	Date        string             `json:"date" bson:"date"`
+	Pose        string             `json:"pose,omitempty" bson:"pose,omitempty"`
+	Note        string             `json:"note,omitempty" bson:"note,omitempty"`
+	Key         string             `json:"-" bson:"key"`
+	ContentType string             `json:"contentType" bson:"contentType"`
+	Size        int64              `json:"size" bson:"size"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	IsDeleted   bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Photos []Photo
+
+func DecodeAsPhotos(cursor *mongo.Cursor) (*Photos, error) {
+	docs := Photos{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type BodyRepository struct {
+	coll      *mongo.Collection
+	photoColl *mongo.Collection
+}
+
+func NewBodyRepository(db *mongo.Database) *BodyRepository {
+	return &BodyRepository{
+		coll:      db.Collection("body_measurements"),
+		photoColl: db.Collection("progress_photos"),
+	}
+}
+
+func (r *BodyRepository) EnsureIndexes() error {
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: -1}},
+	}
+	if _, err := r.coll.Indexes().CreateOne(context.TODO(), index); err != nil {
+		return err
+	}
+	_, err := r.photoColl.Indexes().CreateOne(context.TODO(), index)
+	return err
+}
+
+func (r *BodyRepository) rangeFilter(userID primitive.ObjectID, from string, to string) bson.M {
+	return bson.M{
+		"userId":    userID,
+		"date":      bson.M{"$gte": from, "$lte": to},
+		"isDeleted": bson.M{"$ne": true},
+	}
+}
+
+// FindMeasurements lists measurements between from and to (inclusive), newest
+// first.
+func (r *BodyRepository) FindMeasurements(userID primitive.ObjectID, from string, to string) (*Measurements, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "createdAt", Value: -1}})
+	cursor, err := r.coll.Find(context.TODO(), r.rangeFilter(userID, from, to), opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsMeasurements(cursor)
+}
+
+func (r *BodyRepository) FindMeasurement(id primitive.ObjectID, userID primitive.ObjectID) (*Measurement, error) {
+	var d = &Measurement{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *BodyRepository) InsertMeasurement(newMeasurement *Measurement) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newMeasurement)
+}
+
+func (r *BodyRepository) DeleteMeasurement(id primitive.ObjectID) (*Measurement, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Measurement{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *BodyRepository) FindPhotos(userID primitive.ObjectID, from string, to string) (*Photos, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "createdAt", Value: -1}})
+	cursor, err := r.photoColl.Find(context.TODO(), r.rangeFilter(userID, from, to), opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsPhotos(cursor)
+}
+
+func (r *BodyRepository) FindPhoto(id primitive.ObjectID) (*Photo, error) {
+	var d = &Photo{}
+	err := r.photoColl.FindOne(context.TODO(), bson.M{"_id": id, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *BodyRepository) InsertPhoto(newPhoto *Photo) (*mongo.InsertOneResult, error) {
+	return r.photoColl.InsertOne(context.TODO(), newPhoto)
+}
+
+func (r *BodyRepository) DeletePhoto(id primitive.ObjectID) (*Photo, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Photo{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.photoColl.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxCommentLength = 2000
+
+type CommentForm struct {
+	Content  string              `json:"content"`
+	ParentID *primitive.ObjectID `json:"parentId"`
+}
+
+func NewCommentForm(c echo.Context) (*CommentForm, error) {
+	form := new(CommentForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Content = strings.TrimSpace(form.Content)
+	if form.Content == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Content is required.")
+	}
+	if utf8.RuneCountInString(form.Content) > maxCommentLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Content must not exceed 2000 characters.")
+	}
+	return form, nil
+}
@@ -0,0 +1,522 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	blogRepo "dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/comment/repo"
+	"dietku-backend/cmd/log"
+	notificationGear "dietku-backend/cmd/notification/gear"
+	notificationRepo "dietku-backend/cmd/notification/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultThreads = 20
+	maxThreads     = 50
+	// excerptLength is how much of a comment a notification quotes.
+	excerptLength = 140
+)
+
+type CommentHandler struct {
+	repo     *repo.CommentRepository
+	blogRepo *blogRepo.BlogRepository
+	userRepo *userRepo.UserRepository
+	notifier *notificationGear.Notifier
+}
+
+func NewCommentApi(e *echo.Echo, db *mongo.Database) *CommentHandler {
+	h := &CommentHandler{
+		repo:     repo.NewCommentRepository(db),
+		blogRepo: blogRepo.NewBlogRepository(db),
+		userRepo: userRepo.NewUserRepository(db),
+		notifier: notificationGear.NewNotifier(db),
+	}
+	if err := h.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create comment indexes: %v", err)
+	}
+
+	cGroup := e.Group("")
+	{
+		cGroup.GET("/api/blog/:id/comments", h.Comments, gear.MaybeLoggedIn(db))
+
+		cGroup.POST("/api/blog/:id/comments", h.Create, gear.IsLoggedIn(db))
+		cGroup.POST("/api/blog/:id/comments/:commentId/hide", h.Hide, gear.IsLoggedIn(db))
+		cGroup.POST("/api/blog/:id/comments/:commentId/unhide", h.Unhide, gear.IsLoggedIn(db))
+
+		cGroup.PUT("/api/blog/:id/comments/:commentId", h.Update, gear.IsLoggedIn(db))
+
+		cGroup.DELETE("/api/blog/:id/comments/:commentId", h.Delete, gear.IsLoggedIn(db))
+	}
+	return h
+}
+
+// Author is the public part of who wrote or hid a comment, without the email
+// address stored along.
+type Author struct {
+	ID       primitive.ObjectID `json:"_id"`
+	FullName string             `json:"fullname"`
+	At       time.Time          `json:"at"`
+}
+
+func authorOf(by *blogRepo.By) *Author {
+	if by == nil {
+		return nil
+	}
+	return &Author{ID: by.ID, FullName: by.FullName, At: by.At}
+}
+
+// CommentView is a comment as everyone may see it.
+type CommentView struct {
+	ID        primitive.ObjectID  `json:"_id"`
+	BlogID    primitive.ObjectID  `json:"blogId"`
+	ThreadID  primitive.ObjectID  `json:"threadId"`
+	ParentID  *primitive.ObjectID `json:"parentId,omitempty"`
+	Depth     int                 `json:"depth"`
+	Content   string              `json:"content"`
+	CreatedBy Author              `json:"createdBy"`
+	UpdatedAt *time.Time          `json:"updatedAt,omitempty"`
+	Hidden    bool                `json:"hidden"`
+	HiddenBy  *Author             `json:"hiddenBy,omitempty"`
+	IsDeleted bool                `json:"isDeleted"`
+}
+
+func view(comment *repo.Comment) CommentView {
+	return CommentView{
+		ID:        comment.ID,
+		BlogID:    comment.BlogID,
+		ThreadID:  comment.ThreadID,
+		ParentID:  comment.ParentID,
+		Depth:     comment.Depth,
+		Content:   comment.Content,
+		CreatedBy: *authorOf(&comment.CreatedBy),
+		UpdatedAt: comment.UpdatedAt,
+		Hidden:    comment.Hidden,
+		HiddenBy:  authorOf(comment.HiddenBy),
+		IsDeleted: comment.IsDeleted,
+	}
+}
+
+// CommentNode is a comment with its replies.
+type CommentNode struct {
+	CommentView
+	Replies []*CommentNode `json:"replies"`
+}
+
+type ThreadPage struct {
+	Items      []*CommentNode `json:"items"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+	Limit      int64          `json:"limit"`
+	Threads    int64          `json:"threads"`
+	Comments   int            `json:"comments"`
+}
+
+// findBlog loads the blog of the id parameter. Comments belong to published
+// posts; the author also sees them on a post taken offline later.
+func (h *CommentHandler) findBlog(c echo.Context) (*blogRepo.Blog, error) {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid blog id", c)
+	}
+
+	blog, err := h.blogRepo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Blog not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+
+	me, ok := c.Get("me").(*gear.UserClaims)
+	if blog.Status != blogRepo.StatusPublished && !(ok && me.ID == blog.CreatedBy.ID) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Blog not found!", c)
+	}
+	return blog, nil
+}
+
+func (h *CommentHandler) findComment(c echo.Context, blog *blogRepo.Blog) (*repo.Comment, error) {
+	oId, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid comment id", c)
+	}
+
+	comment, err := h.repo.FindOne(oId, blog.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Comment not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting comment.", c)
+	}
+	return comment, nil
+}
+
+// canModerate tells whether the signed in user may hide comments on blog:
+// its author and moderators can.
+func (h *CommentHandler) canModerate(c echo.Context, blog *blogRepo.Blog) (bool, error) {
+	me, ok := c.Get("me").(*gear.UserClaims)
+	if !ok {
+		return false, nil
+	}
+	if me.ID == blog.CreatedBy.ID {
+		return true, nil
+	}
+
+	user, err := h.userRepo.FindOne(me.ID)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+	return user.IsModerator(), nil
+}
+
+// redact blanks what the viewer may not read: deleted comments for everyone,
+// hidden ones for all but moderators and their author.
+func redact(comment *repo.Comment, viewer *primitive.ObjectID, moderator bool) {
+	if comment.IsDeleted {
+		comment.Content = ""
+		return
+	}
+	if comment.Hidden && !moderator && (viewer == nil || *viewer != comment.CreatedBy.ID) {
+		comment.Content = ""
+	}
+}
+
+func by(c echo.Context) blogRepo.By {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	return blogRepo.By{
+		ID:       tokenData.ID,
+		Email:    tokenData.Email,
+		FullName: tokenData.FirstName + " " + tokenData.LastName,
+		At:       time.Now(),
+	}
+}
+
+// Comments
+// @Tags Comment
+// @Summary Get Blog Comments
+// @Description Pages through top-level comments, oldest first, each with its whole reply tree. Deleted comments and, except for moderators, hidden ones keep their place with empty content.
+// @ID comment-list
+// @Router /api/blog/{id}/comments [get]
+// @Param id path string true "Blog ID"
+// @Param limit query int false "Threads per page (default 20, max 50)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Produce json
+// @Success 200
+func (h *CommentHandler) Comments(c echo.Context) error {
+	blog, err := h.findBlog(c)
+	if err != nil {
+		return err
+	}
+
+	n, err := gear.QueryInt(c, "limit", defaultThreads, maxThreads)
+	if err != nil {
+		return err
+	}
+	limit := int64(n)
+
+	var after *blogRepo.Cursor
+	if v := c.QueryParam("cursor"); v != "" {
+		after, err = blogRepo.DecodeCursor(v)
+		if err != nil || after.Sort != blogRepo.SortOldest {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor", c)
+		}
+	}
+
+	moderator, err := h.canModerate(c, blog)
+	if err != nil {
+		return err
+	}
+	var viewer *primitive.ObjectID
+	if me, ok := c.Get("me").(*gear.UserClaims); ok {
+		viewer = &me.ID
+	}
+
+	roots, err := h.repo.FindThreads(blog.ID, after, limit+1)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting comments.", c)
+	}
+
+	page := &ThreadPage{Items: []*CommentNode{}, Limit: limit, Comments: blog.CommentCount}
+	if int64(len(*roots)) > limit {
+		*roots = (*roots)[:limit]
+		last := (*roots)[limit-1]
+		page.NextCursor = blogRepo.Cursor{Sort: blogRepo.SortOldest, At: last.CreatedBy.At, ID: last.ID}.Encode()
+	}
+
+	threadIDs := make([]primitive.ObjectID, len(*roots))
+	nodes := map[primitive.ObjectID]*CommentNode{}
+	for i, root := range *roots {
+		redact(&root, viewer, moderator)
+		node := &CommentNode{CommentView: view(&root), Replies: []*CommentNode{}}
+		page.Items = append(page.Items, node)
+		nodes[root.ID] = node
+		threadIDs[i] = root.ID
+	}
+
+	if len(threadIDs) > 0 {
+		replies, err := h.repo.FindReplies(threadIDs)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting comments.", c)
+		}
+		// replies are sorted oldest first, so a parent is always placed
+		// before its children
+		for _, reply := range *replies {
+			redact(&reply, viewer, moderator)
+			node := &CommentNode{CommentView: view(&reply), Replies: []*CommentNode{}}
+			nodes[reply.ID] = node
+			if parent, ok := nodes[*reply.ParentID]; ok {
+				parent.Replies = append(parent.Replies, node)
+			}
+		}
+	}
+
+	page.Threads, err = h.repo.CountThreads(blog.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting comments.", c)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// Create
+// @Tags Comment
+// @Summary Create Comment
+// @Description Comments on the post, or replies to parentId. Replies nest up to 3 levels deep.
+// @ID comment-create
+// @Router /api/blog/{id}/comments [post]
+// @Accept json
+// @Param id path string true "Blog ID"
+// @Param body body CommentForm true "comment body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *CommentHandler) Create(c echo.Context) error {
+	form, err := NewCommentForm(c)
+	if err != nil {
+		return err
+	}
+
+	blog, err := h.findBlog(c)
+	if err != nil {
+		return err
+	}
+	if blog.Status != blogRepo.StatusPublished {
+		return echo.NewHTTPError(http.StatusBadRequest, "Only published posts can be commented on", c)
+	}
+
+	comment := &repo.Comment{
+		ID:        primitive.NewObjectID(),
+		BlogID:    blog.ID,
+		Content:   form.Content,
+		CreatedBy: by(c),
+	}
+	comment.ThreadID = comment.ID
+
+	var parent *repo.Comment
+	if form.ParentID != nil {
+		parent, err = h.repo.FindOne(*form.ParentID, blog.ID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Comment not found!", c)
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting comment.", c)
+		}
+		if parent.IsDeleted {
+			return echo.NewHTTPError(http.StatusBadRequest, "Cannot reply to a deleted comment", c)
+		}
+		if parent.Depth >= repo.MaxDepth {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Replies cannot be nested more than %d levels deep", repo.MaxDepth), c)
+		}
+		comment.ParentID = &parent.ID
+		comment.ThreadID = parent.ThreadID
+		comment.Depth = parent.Depth + 1
+	}
+
+	_, err = h.repo.InsertOne(comment)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating comment.", c)
+	}
+	if err := h.blogRepo.IncCommentCount(blog.ID, 1); err != nil {
+		log.Errorf("failed to count comment on blog %s: %v", blog.ID.Hex(), err)
+	}
+
+	h.notify(blog, parent, comment)
+	return c.JSON(http.StatusOK, view(comment))
+}
+
+// notify tells the post author about a new comment and the parent's author
+// about a reply, but nobody about their own comments.
+func (h *CommentHandler) notify(blog *blogRepo.Blog, parent *repo.Comment, comment *repo.Comment) {
+	excerpt := []rune(comment.Content)
+	if len(excerpt) > excerptLength {
+		excerpt = append(excerpt[:excerptLength], '…')
+	}
+	payload := map[string]interface{}{"blogId": blog.ID, "commentId": comment.ID}
+	author := comment.CreatedBy
+
+	if blog.CreatedBy.ID != author.ID {
+		title := author.FullName + " commented on \"" + blog.Header + "\""
+		if _, err := h.notifier.Notify(blog.CreatedBy.ID, notificationRepo.TypeComment, title, string(excerpt), payload); err != nil {
+			log.Errorf("failed to notify about comment %s: %v", comment.ID.Hex(), err)
+		}
+	}
+
+	if parent != nil && parent.CreatedBy.ID != author.ID && parent.CreatedBy.ID != blog.CreatedBy.ID {
+		title := author.FullName + " replied to your comment"
+		if _, err := h.notifier.Notify(parent.CreatedBy.ID, notificationRepo.TypeComment, title, string(excerpt), payload); err != nil {
+			log.Errorf("failed to notify about comment %s: %v", comment.ID.Hex(), err)
+		}
+	}
+}
+
+// Update
+// @Tags Comment
+// @Summary Update Comment
+// @ID comment-update
+// @Router /api/blog/{id}/comments/{commentId} [put]
+// @Accept json
+// @Param id path string true "Blog ID"
+// @Param commentId path string true "Comment ID"
+// @Param body body CommentForm true "comment body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *CommentHandler) Update(c echo.Context) error {
+	form, err := NewCommentForm(c)
+	if err != nil {
+		return err
+	}
+
+	blog, err := h.findBlog(c)
+	if err != nil {
+		return err
+	}
+	comment, err := h.findComment(c, blog)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	if tokenData.ID != comment.CreatedBy.ID {
+		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to update this comment", c)
+	}
+	if comment.IsDeleted {
+		return echo.NewHTTPError(http.StatusBadRequest, "Comment not found!", c)
+	}
+
+	now := time.Now()
+	comment.Content = form.Content
+	comment.UpdatedAt = &now
+
+	docs, err := h.repo.UpdateOne(comment)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating comment.", c)
+	}
+	return c.JSON(http.StatusOK, view(docs))
+}
+
+// Delete
+// @Tags Comment
+// @Summary Delete Comment
+// @Description Deletes the comment's content; its replies stay in the thread.
+// @ID comment-delete
+// @Router /api/blog/{id}/comments/{commentId} [delete]
+// @Param id path string true "Blog ID"
+// @Param commentId path string true "Comment ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *CommentHandler) Delete(c echo.Context) error {
+	blog, err := h.findBlog(c)
+	if err != nil {
+		return err
+	}
+	comment, err := h.findComment(c, blog)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	if tokenData.ID != comment.CreatedBy.ID {
+		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to delete this comment", c)
+	}
+
+	deleted, err := h.repo.DeleteOne(comment.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting comment.", c)
+	}
+	if deleted {
+		if err := h.blogRepo.IncCommentCount(blog.ID, -1); err != nil {
+			log.Errorf("failed to count comment on blog %s: %v", blog.ID.Hex(), err)
+		}
+	}
+
+	comment.IsDeleted = true
+	redact(comment, &tokenData.ID, false)
+	return c.JSON(http.StatusOK, view(comment))
+}
+
+// Hide
+// @Tags Comment
+// @Summary Hide Comment
+// @Description For the post author and moderators: hides the comment's content from other readers.
+// @ID comment-hide
+// @Router /api/blog/{id}/comments/{commentId}/hide [post]
+// @Param id path string true "Blog ID"
+// @Param commentId path string true "Comment ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *CommentHandler) Hide(c echo.Context) error {
+	return h.setHidden(c, true)
+}
+
+// Unhide
+// @Tags Comment
+// @Summary Unhide Comment
+// @ID comment-unhide
+// @Router /api/blog/{id}/comments/{commentId}/unhide [post]
+// @Param id path string true "Blog ID"
+// @Param commentId path string true "Comment ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *CommentHandler) Unhide(c echo.Context) error {
+	return h.setHidden(c, false)
+}
+
+func (h *CommentHandler) setHidden(c echo.Context, hidden bool) error {
+	blog, err := h.findBlog(c)
+	if err != nil {
+		return err
+	}
+	comment, err := h.findComment(c, blog)
+	if err != nil {
+		return err
+	}
+
+	moderator, err := h.canModerate(c, blog)
+	if err != nil {
+		return err
+	}
+	if !moderator {
+		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to moderate this comment", c)
+	}
+
+	comment.Hidden = hidden
+	comment.HiddenBy = nil
+	if hidden {
+		moderatedBy := by(c)
+		comment.HiddenBy = &moderatedBy
+	}
+
+	docs, err := h.repo.UpdateOne(comment)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating comment.", c)
+	}
+	return c.JSON(http.StatusOK, view(docs))
+}
@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	blogRepo "dietku-backend/cmd/blog/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// MaxDepth is the deepest reply allowed: a top-level comment has depth 0, a
+// reply to it depth 1 and so on.
+const MaxDepth = 3
+
+// Comment is a comment on a blog. ThreadID is the id of the top-level comment
+// a reply belongs to, and a top-level comment's own id, so a whole thread is
+// one query. Deleted and hidden comments stay in place to keep their replies
+// attached.
+type Comment struct {
+	ID        primitive.ObjectID  `json:"_id" bson:"_id"`
+	BlogID    primitive.ObjectID  `json:"blogId" bson:"blogId"`
+	ThreadID  primitive.ObjectID  `json:"threadId" bson:"threadId"`
+	ParentID  *primitive.ObjectID `json:"parentId,omitempty" bson:"parentId,omitempty"`
+	Depth     int                 `json:"depth" bson:"depth"`
+	Content   string              `json:"content" bson:"content"`
+	CreatedBy blogRepo.By         `json:"createdBy" bson:"createdBy"`
+	UpdatedAt *time.Time          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	Hidden    bool                `json:"hidden" bson:"hidden"`
+	HiddenBy  *blogRepo.By        `json:"hiddenBy,omitempty" bson:"hiddenBy"`
+	IsDeleted bool                `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Comments []Comment
+
+func DecodeAsComments(cursor *mongo.Cursor) (*Comments, error) {
+	docs := Comments{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type CommentRepository struct {
+	coll *mongo.Collection
+}
+
+func NewCommentRepository(db *mongo.Database) *CommentRepository {
+	return &CommentRepository{
+		coll: db.Collection("blog_comments"),
+	}
+}
+
+func (r *CommentRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "blogId", Value: 1}, {Key: "depth", Value: 1}, {Key: "createdBy.at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "threadId", Value: 1}, {Key: "createdBy.at", Value: 1}}},
+	})
+	return err
+}
+
+// FindThreads returns up to limit top-level comments of a blog, oldest first,
+// starting after the cursor.
+func (r *CommentRepository) FindThreads(blogID primitive.ObjectID, after *blogRepo.Cursor, limit int64) (*Comments, error) {
+	filter := bson.M{"blogId": blogID, "depth": 0}
+	if after != nil {
+		filter["$or"] = bson.A{
+			bson.M{"createdBy.at": bson.M{"$gt": after.At}},
+			bson.M{"createdBy.at": after.At, "_id": bson.M{"$gt": after.ID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdBy.at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsComments(cursor)
+}
+
+// FindReplies returns every reply in the given threads, oldest first.
+func (r *CommentRepository) FindReplies(threadIDs []primitive.ObjectID) (*Comments, error) {
+	filter := bson.M{"threadId": bson.M{"$in": threadIDs}, "depth": bson.M{"$gt": 0}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdBy.at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsComments(cursor)
+}
+
+// CountThreads counts the top-level comments of a blog.
+func (r *CommentRepository) CountThreads(blogID primitive.ObjectID) (int64, error) {
+	return r.coll.CountDocuments(context.TODO(), bson.M{"blogId": blogID, "depth": 0})
+}
+
+func (r *CommentRepository) FindOne(id primitive.ObjectID, blogID primitive.ObjectID) (*Comment, error) {
+	var d = &Comment{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "blogId": blogID}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *CommentRepository) InsertOne(newComment *Comment) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newComment)
+}
+
+func (r *CommentRepository) UpdateOne(comment *Comment) (*Comment, error) {
+	filter := bson.M{"_id": comment.ID}
+
+	update := bson.M{
+		"$set": comment,
+	}
+
+	var d = &Comment{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeleteOne soft deletes a comment. It reports false when it was deleted
+// already, so the blog's comment count is only decremented once.
+func (r *CommentRepository) DeleteOne(id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "isDeleted": bson.M{"$ne": true}}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	result, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
@@ -0,0 +1,148 @@
+package gear
+
+import (
+	foodRepo "dietku-backend/cmd/food/repo"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	StatusDeficient = "deficient"
+	StatusAdequate  = "adequate"
+	StatusExcess    = "excess"
+
+	// DeficientPercent is the share of the recommendation below which an
+	// intake is flagged as deficient.
+	DeficientPercent = 70
+
+	// DefaultAge and DefaultSex are assumed when the profile lacks them.
+	DefaultAge = 30
+	DefaultSex = "female"
+)
+
+// Micronutrient describes one tracked vitamin or mineral.
+type Micronutrient struct {
+	Key   string
+	Name  string
+	Unit  string
+	Value func(n foodRepo.Nutrients) float64
+}
+
+// Micronutrients lists the nutrients reported against the AKG, in display
+// order. Keys match the bson names in foodRepo.Nutrients and the AKG table.
+var Micronutrients = []Micronutrient{
+	{"fiber", "Fiber", "g", func(n foodRepo.Nutrients) float64 { return n.Fiber }},
+	{"vitaminA", "Vitamin A", "µg", func(n foodRepo.Nutrients) float64 { return n.VitaminA }},
+	{"vitaminC", "Vitamin C", "mg", func(n foodRepo.Nutrients) float64 { return n.VitaminC }},
+	{"vitaminD", "Vitamin D", "µg", func(n foodRepo.Nutrients) float64 { return n.VitaminD }},
+	{"vitaminE", "Vitamin E", "mg", func(n foodRepo.Nutrients) float64 { return n.VitaminE }},
+	{"vitaminB12", "Vitamin B12", "µg", func(n foodRepo.Nutrients) float64 { return n.VitaminB12 }},
+	{"folate", "Folate", "µg", func(n foodRepo.Nutrients) float64 { return n.Folate }},
+	{"calcium", "Calcium", "mg", func(n foodRepo.Nutrients) float64 { return n.Calcium }},
+	{"iron", "Iron", "mg", func(n foodRepo.Nutrients) float64 { return n.Iron }},
+	{"magnesium", "Magnesium", "mg", func(n foodRepo.Nutrients) float64 { return n.Magnesium }},
+	{"potassium", "Potassium", "mg", func(n foodRepo.Nutrients) float64 { return n.Potassium }},
+	{"zinc", "Zinc", "mg", func(n foodRepo.Nutrients) float64 { return n.Zinc }},
+	{"sodium", "Sodium", "mg", func(n foodRepo.Nutrients) float64 { return n.Sodium }},
+}
+
+type akgGroup struct {
+	Sex    string             `json:"sex"`
+	MinAge int                `json:"minAge"`
+	MaxAge int                `json:"maxAge"`
+	Values map[string]float64 `json:"values"`
+}
+
+type akgTable struct {
+	Source      string             `json:"source"`
+	Groups      []akgGroup         `json:"groups"`
+	UpperLimits map[string]float64 `json:"upperLimits"`
+}
+
+// akgData is the bundled Indonesian recommended daily allowance table.
+//
+//go:embed akg.json
+var akgData []byte
+
+var akg = mustLoadAKG()
+
+func mustLoadAKG() akgTable {
+	t := akgTable{}
+	if err := json.Unmarshal(akgData, &t); err != nil {
+		panic(fmt.Sprintf("invalid akg.json: %v", err))
+	}
+	return t
+}
+
+// Recommendation is the AKG row that applies to a user.
+type Recommendation struct {
+	Source string             `json:"source"`
+	Sex    string             `json:"sex"`
+	MinAge int                `json:"minAge"`
+	MaxAge int                `json:"maxAge"`
+	Values map[string]float64 `json:"-"`
+}
+
+// Recommend picks the AKG group for sex and age. Ages outside the table use
+// the nearest group.
+func Recommend(sex string, age int) Recommendation {
+	var best *akgGroup
+	for i := range akg.Groups {
+		g := &akg.Groups[i]
+		if g.Sex != sex {
+			continue
+		}
+		if age >= g.MinAge && age <= g.MaxAge {
+			best = g
+			break
+		}
+		if best == nil || (age < g.MinAge && g.MinAge < best.MinAge) || (age > g.MaxAge && g.MaxAge > best.MaxAge) {
+			best = g
+		}
+	}
+	if best == nil {
+		return Recommend(DefaultSex, age)
+	}
+	return Recommendation{Source: akg.Source, Sex: best.Sex, MinAge: best.MinAge, MaxAge: best.MaxAge, Values: best.Values}
+}
+
+type NutrientStatus struct {
+	Key         string  `json:"key"`
+	Name        string  `json:"name"`
+	Unit        string  `json:"unit"`
+	Amount      float64 `json:"amount"`
+	Recommended float64 `json:"recommended"`
+	UpperLimit  float64 `json:"upperLimit,omitempty"`
+	Percent     float64 `json:"percent"`
+	Status      string  `json:"status"`
+}
+
+// Assess compares a day's intake n with the recommendation. Intake below
+// DeficientPercent of the AKG is deficient; above the tolerable upper limit,
+// where one is set, it is excess.
+func Assess(n foodRepo.Nutrients, r Recommendation) []NutrientStatus {
+	statuses := make([]NutrientStatus, 0, len(Micronutrients))
+	for _, m := range Micronutrients {
+		s := NutrientStatus{
+			Key:         m.Key,
+			Name:        m.Name,
+			Unit:        m.Unit,
+			Amount:      m.Value(n),
+			Recommended: r.Values[m.Key],
+			UpperLimit:  akg.UpperLimits[m.Key],
+			Status:      StatusAdequate,
+		}
+		if s.Recommended > 0 {
+			s.Percent = s.Amount / s.Recommended * 100
+		}
+		switch {
+		case s.UpperLimit > 0 && s.Amount > s.UpperLimit:
+			s.Status = StatusExcess
+		case s.Recommended > 0 && s.Percent < DeficientPercent:
+			s.Status = StatusDeficient
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"dietku-backend/cmd/diary/repo"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type EntryForm struct {
+	Meal     string             `json:"meal"`
+	MealName string             `json:"mealName"`
+	FoodID   primitive.ObjectID `json:"foodId"`
+	RecipeID primitive.ObjectID `json:"recipeId"`
+	Quantity float64            `json:"quantity"`
+	Unit     string             `json:"unit"`
+	LoggedAt *time.Time         `json:"loggedAt"`
+}
+
+func NewEntryForm(c echo.Context) (*EntryForm, error) {
+	form := new(EntryForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if err := validateMeal(&form.Meal, &form.MealName); err != nil {
+		return nil, err
+	}
+
+	if form.FoodID.IsZero() == form.RecipeID.IsZero() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Either foodId or recipeId is required.")
+	}
+
+	if form.Quantity <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Quantity must be greater than 0.")
+	}
+
+	if form.Unit == "" {
+		form.Unit = foodRepo.UnitGram
+		if !form.RecipeID.IsZero() {
+			form.Unit = foodRepo.UnitServing
+		}
+	}
+	if !isValidUnit(form.Unit) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unit must be g, ml or serving.")
+	}
+	return form, nil
+}
+
+type UpdateEntryForm struct {
+	Meal      string              `json:"meal"`
+	MealName  string              `json:"mealName"`
+	Name      string              `json:"name"`
+	Quantity  float64             `json:"quantity"`
+	Unit      string              `json:"unit"`
+	Nutrients *foodRepo.Nutrients `json:"nutrients"`
+	LoggedAt  *time.Time          `json:"loggedAt"`
+}
+
+func NewUpdateEntryForm(c echo.Context) (*UpdateEntryForm, error) {
+	form := new(UpdateEntryForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.Meal != "" {
+		if err := validateMeal(&form.Meal, &form.MealName); err != nil {
+			return nil, err
+		}
+	}
+
+	if form.Quantity < 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Quantity must be greater than 0.")
+	}
+
+	if form.Unit != "" && !isValidUnit(form.Unit) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unit must be g, ml or serving.")
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	return form, nil
+}
+
+type QuickAddForm struct {
+	Meal         string     `json:"meal"`
+	MealName     string     `json:"mealName"`
+	Name         string     `json:"name"`
+	Calories     float64    `json:"calories"`
+	Protein      float64    `json:"protein"`
+	Carbohydrate float64    `json:"carbohydrate"`
+	Fat          float64    `json:"fat"`
+	LoggedAt     *time.Time `json:"loggedAt"`
+}
+
+func NewQuickAddForm(c echo.Context) (*QuickAddForm, error) {
+	form := new(QuickAddForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if err := validateMeal(&form.Meal, &form.MealName); err != nil {
+		return nil, err
+	}
+
+	if form.Calories <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Calories must be greater than 0.")
+	}
+
+	if form.Protein < 0 || form.Carbohydrate < 0 || form.Fat < 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Macros must not be negative.")
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		form.Name = "Quick add"
+	}
+	return form, nil
+}
+
+type CopyMealForm struct {
+	FromDate string `json:"fromDate"`
+	Meal     string `json:"meal"`
+	MealName string `json:"mealName"`
+}
+
+func NewCopyMealForm(c echo.Context) (*CopyMealForm, error) {
+	form := new(CopyMealForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.FromDate == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "FromDate is required.")
+	}
+
+	if err := validateMeal(&form.Meal, &form.MealName); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+func validateMeal(meal *string, mealName *string) error {
+	*meal = strings.ToLower(strings.TrimSpace(*meal))
+	*mealName = strings.TrimSpace(*mealName)
+
+	valid := false
+	for _, m := range repo.Meals {
+		if *meal == m {
+			valid = true
+		}
+	}
+	if !valid {
+		return echo.NewHTTPError(http.StatusBadRequest, "Meal must be one of breakfast, lunch, dinner, snacks or custom.")
+	}
+
+	if *meal == repo.MealCustom && *mealName == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "MealName is required for custom meals.")
+	}
+	if *meal != repo.MealCustom {
+		*mealName = ""
+	}
+	return nil
+}
+
+func isValidUnit(unit string) bool {
+	return unit == foodRepo.UnitGram || unit == foodRepo.UnitMilliliter || unit == foodRepo.UnitServing
+}
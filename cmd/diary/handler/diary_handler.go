@@ -0,0 +1,534 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/diary/repo"
+	"dietku-backend/cmd/event"
+	exerciseRepo "dietku-backend/cmd/exercise/repo"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/log"
+	recipeRepo "dietku-backend/cmd/recipe/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"dietku-backend/config"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+type DiaryHandler struct {
+	repo         *repo.DiaryRepository
+	foodRepo     *foodRepo.FoodRepository
+	recipeRepo   *recipeRepo.RecipeRepository
+	userRepo     *userRepo.UserRepository
+	exerciseRepo *exerciseRepo.ExerciseRepository
+	conf         *config.Config
+}
+
+func NewDiaryApi(e *echo.Echo, db *mongo.Database, conf *config.Config) *DiaryHandler {
+	d := &DiaryHandler{
+		repo:         repo.NewDiaryRepository(db),
+		foodRepo:     foodRepo.NewFoodRepository(db),
+		recipeRepo:   recipeRepo.NewRecipeRepository(db),
+		userRepo:     userRepo.NewUserRepository(db),
+		exerciseRepo: exerciseRepo.NewExerciseRepository(db),
+		conf:         conf,
+	}
+	if err := d.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create diary indexes: %v", err)
+	}
+
+	dGroup := e.Group("")
+	dGroup.Use(gear.IsLoggedIn(db))
+	{
+		dGroup.GET("/api/diary/summary", d.Summary)
+		dGroup.GET("/api/diary/micronutrients", d.Micronutrients)
+		dGroup.GET("/api/diary/:date", d.Day)
+
+		dGroup.POST("/api/diary/:date", d.Create)
+		dGroup.POST("/api/diary/:date/quick-add", d.QuickAdd)
+		dGroup.POST("/api/diary/:date/copy", d.CopyMeal)
+
+		dGroup.PUT("/api/diary/:date/entries/:id", d.Update)
+
+		dGroup.DELETE("/api/diary/:date/entries/:id", d.Delete)
+	}
+	return d
+}
+
+type DiaryMeal struct {
+	Meal      string             `json:"meal"`
+	MealName  string             `json:"mealName,omitempty"`
+	Entries   repo.Entries       `json:"entries"`
+	Nutrients foodRepo.Nutrients `json:"nutrients"`
+}
+
+type DiaryDay struct {
+	Date      string             `json:"date"`
+	Timezone  string             `json:"timezone"`
+	Meals     []DiaryMeal        `json:"meals"`
+	Nutrients foodRepo.Nutrients `json:"nutrients"`
+}
+
+// groupByMeal arranges entries in display order: the fixed meals first, then
+// every custom meal in the order it was first logged.
+func groupByMeal(date string, loc *time.Location, entries repo.Entries) *DiaryDay {
+	day := &DiaryDay{Date: date, Timezone: loc.String(), Meals: []DiaryMeal{}}
+	index := map[string]int{}
+
+	for _, meal := range repo.Meals {
+		if meal == repo.MealCustom {
+			continue
+		}
+		index[meal] = len(day.Meals)
+		day.Meals = append(day.Meals, DiaryMeal{Meal: meal, Entries: repo.Entries{}})
+	}
+
+	for _, entry := range entries {
+		key := entry.Meal
+		if entry.Meal == repo.MealCustom {
+			key = repo.MealCustom + ":" + entry.MealName
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(day.Meals)
+			index[key] = i
+			day.Meals = append(day.Meals, DiaryMeal{Meal: entry.Meal, MealName: entry.MealName, Entries: repo.Entries{}})
+		}
+		day.Meals[i].Entries = append(day.Meals[i].Entries, entry)
+		day.Meals[i].Nutrients = day.Meals[i].Nutrients.Add(entry.Nutrients)
+		day.Nutrients = day.Nutrients.Add(entry.Nutrients)
+	}
+	return day
+}
+
+// loggedAt picks the time an entry is recorded at. Explicit times must fall on
+// the diary day in the user's time zone; otherwise "now" is used for today and
+// the start of the day for any other date.
+func loggedAt(requested *time.Time, start time.Time, end time.Time) (time.Time, error) {
+	if requested != nil {
+		if requested.Before(start) || !requested.Before(end) {
+			return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "LoggedAt must be within the diary date.")
+		}
+		return *requested, nil
+	}
+	now := time.Now()
+	if !now.Before(start) && now.Before(end) {
+		return now, nil
+	}
+	return start, nil
+}
+
+// allergies returns the allergens the user declared.
+func (h *DiaryHandler) allergies(c echo.Context, userID primitive.ObjectID) ([]string, error) {
+	user, err := h.userRepo.FindOne(userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+	if user.Preferences == nil {
+		return nil, nil
+	}
+	return user.Preferences.Allergens, nil
+}
+
+// warn sets a warning on entry for every allergen it shares with allergies.
+func warn(entry *repo.Entry, allergies []string) {
+	entry.Warnings = nil
+	for _, a := range foodRepo.Conflicts(entry.Allergens, allergies) {
+		entry.Warnings = append(entry.Warnings, fmt.Sprintf("%s contains %s, which you are allergic to.", entry.Name, a))
+	}
+}
+
+// Day
+// @Tags Diary
+// @Summary Get Diary Day
+// @Description Entries of one day grouped by meal. The date is interpreted in the user's time zone. Entries containing a declared allergen carry warnings.
+// @ID diary-day
+// @Router /api/diary/{date} [get]
+// @Produce json
+// @Param date path string true "Date (YYYY-MM-DD) or today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) Day(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	date, _, _, err := gear.ParseDay(c.Param("date"), loc)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD", c)
+	}
+
+	entries, err := h.repo.FindByDate(tokenData.ID, date)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting diary.", c)
+	}
+
+	allergies, err := h.allergies(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+	for i := range *entries {
+		warn(&(*entries)[i], allergies)
+	}
+	return c.JSON(http.StatusOK, groupByMeal(date, loc, *entries))
+}
+
+// Create
+// @Tags Diary
+// @Summary Log Food
+// @Description Logs either a food (foodId) or servings of a recipe (recipeId). Nutrients are snapshotted at log time. The entry is still logged when it contains a declared allergen, but carries a warning.
+// @ID diary-create
+// @Router /api/diary/{date} [post]
+// @Accept json
+// @Param date path string true "Date (YYYY-MM-DD) or today"
+// @Param body body EntryForm true "entry body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) Create(c echo.Context) error {
+	form, err := NewEntryForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	date, start, end, err := gear.ParseDay(c.Param("date"), tokenData.Location())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD", c)
+	}
+
+	at, err := loggedAt(form.LoggedAt, start, end)
+	if err != nil {
+		return err
+	}
+
+	entry := &repo.Entry{
+		ID:        primitive.NewObjectID(),
+		UserID:    tokenData.ID,
+		Date:      date,
+		Meal:      form.Meal,
+		MealName:  form.MealName,
+		Quantity:  form.Quantity,
+		Unit:      form.Unit,
+		LoggedAt:  at,
+		CreatedAt: time.Now(),
+	}
+
+	if !form.RecipeID.IsZero() {
+		recipe, err := h.recipeRepo.FindOne(form.RecipeID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipe.", c)
+		}
+		if err != nil || !recipe.IsVisibleTo(tokenData.ID) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Recipe not found!", c)
+		}
+
+		// a recipe serving is its total weight divided by the servings
+		servings := form.Quantity
+		if form.Unit != foodRepo.UnitServing {
+			if recipe.ServingGrams <= 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Recipe has no serving weight, log it in servings.", c)
+			}
+			servings = form.Quantity / recipe.ServingGrams
+		}
+		entry.Kind = repo.KindRecipe
+		entry.RecipeID = &recipe.ID
+		entry.Name = recipe.Name
+		entry.ServingSize = recipe.ServingGrams
+		entry.Grams = servings * recipe.ServingGrams
+		entry.Nutrients = recipe.PerServing.Scale(servings)
+		entry.Allergens = recipe.Allergens
+	} else {
+		food, err := h.foodRepo.FindOne(form.FoodID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Food not found!", c)
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting food.", c)
+		}
+
+		entry.Kind = repo.KindFood
+		entry.FoodID = &food.ID
+		entry.Name = food.Name
+		entry.Brand = food.Brand
+		entry.ServingSize = food.ServingSize
+		entry.Grams = food.Grams(form.Quantity, form.Unit)
+		entry.Nutrients = food.Nutrients.Scale(entry.Grams / 100)
+		entry.Allergens = food.Allergens
+	}
+
+	allergies, err := h.allergies(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.repo.InsertOne(entry)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while logging food.", c)
+	}
+	event.Publish(event.Event{Type: event.DiaryLogged, UserID: tokenData.ID, Date: date})
+	warn(entry, allergies)
+	return c.JSON(http.StatusOK, entry)
+}
+
+// QuickAdd
+// @Tags Diary
+// @Summary Quick Add Calories
+// @Description Log raw calories (and optionally macros) without referencing a food.
+// @ID diary-quick-add
+// @Router /api/diary/{date}/quick-add [post]
+// @Accept json
+// @Param date path string true "Date (YYYY-MM-DD) or today"
+// @Param body body QuickAddForm true "quick add body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) QuickAdd(c echo.Context) error {
+	form, err := NewQuickAddForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	date, start, end, err := gear.ParseDay(c.Param("date"), tokenData.Location())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD", c)
+	}
+
+	at, err := loggedAt(form.LoggedAt, start, end)
+	if err != nil {
+		return err
+	}
+
+	entry := &repo.Entry{
+		ID:       primitive.NewObjectID(),
+		UserID:   tokenData.ID,
+		Date:     date,
+		Meal:     form.Meal,
+		MealName: form.MealName,
+		Kind:     repo.KindQuick,
+		Name:     form.Name,
+		Quantity: 1,
+		Unit:     foodRepo.UnitServing,
+		Nutrients: foodRepo.Nutrients{
+			Calories:     form.Calories,
+			Protein:      form.Protein,
+			Carbohydrate: form.Carbohydrate,
+			Fat:          form.Fat,
+		},
+		LoggedAt:  at,
+		CreatedAt: time.Now(),
+	}
+
+	_, err = h.repo.InsertOne(entry)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while logging food.", c)
+	}
+	event.Publish(event.Event{Type: event.DiaryLogged, UserID: tokenData.ID, Date: date})
+	return c.JSON(http.StatusOK, entry)
+}
+
+// CopyMeal
+// @Tags Diary
+// @Summary Copy Meal From Another Day
+// @ID diary-copy
+// @Router /api/diary/{date}/copy [post]
+// @Accept json
+// @Param date path string true "Target date (YYYY-MM-DD) or today"
+// @Param body body CopyMealForm true "copy body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) CopyMeal(c echo.Context) error {
+	form, err := NewCopyMealForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	date, start, _, err := gear.ParseDay(c.Param("date"), loc)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD", c)
+	}
+
+	fromDate, fromStart, _, err := gear.ParseDay(form.FromDate, loc)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid fromDate, use YYYY-MM-DD", c)
+	}
+
+	if fromDate == date {
+		return echo.NewHTTPError(http.StatusBadRequest, "Cannot copy a meal onto the same day", c)
+	}
+
+	source, err := h.repo.FindByMeal(tokenData.ID, fromDate, form.Meal, form.MealName)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting diary.", c)
+	}
+
+	if len(*source) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Nothing to copy", c)
+	}
+
+	now := time.Now()
+	copies := make(repo.Entries, 0, len(*source))
+	for _, entry := range *source {
+		// keep the local clock time the meal was eaten at
+		offset := entry.LoggedAt.Sub(fromStart)
+		entry.ID = primitive.NewObjectID()
+		entry.Date = date
+		entry.LoggedAt = start.Add(offset)
+		entry.CreatedAt = now
+		entry.UpdatedAt = nil
+		copies = append(copies, entry)
+	}
+
+	_, err = h.repo.InsertMany(copies)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while copying meal.", c)
+	}
+	event.Publish(event.Event{Type: event.DiaryLogged, UserID: tokenData.ID, Date: date})
+	return c.JSON(http.StatusOK, copies)
+}
+
+// Update
+// @Tags Diary
+// @Summary Update Diary Entry
+// @Description Changing the quantity rescales the nutrients snapshotted when the entry was logged.
+// @ID diary-update
+// @Router /api/diary/{date}/entries/{id} [put]
+// @Accept json
+// @Param date path string true "Date (YYYY-MM-DD) or today"
+// @Param id path string true "Entry ID"
+// @Param body body UpdateEntryForm true "entry body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) Update(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	entry, start, end, err := h.findEntry(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	form, err := NewUpdateEntryForm(c)
+	if err != nil {
+		return err
+	}
+
+	if form.Meal != "" {
+		entry.Meal = form.Meal
+		entry.MealName = form.MealName
+	}
+	if form.Name != "" {
+		entry.Name = form.Name
+	}
+	if form.LoggedAt != nil {
+		at, err := loggedAt(form.LoggedAt, start, end)
+		if err != nil {
+			return err
+		}
+		entry.LoggedAt = at
+	}
+
+	switch entry.Kind {
+	case repo.KindFood, repo.KindRecipe:
+		if form.Quantity > 0 || form.Unit != "" {
+			if form.Quantity > 0 {
+				entry.Quantity = form.Quantity
+			}
+			if form.Unit != "" {
+				entry.Unit = form.Unit
+			}
+			grams := entry.Quantity
+			if entry.Unit == foodRepo.UnitServing {
+				grams = entry.Quantity * entry.ServingSize
+			}
+			if entry.Grams > 0 {
+				entry.Nutrients = entry.Nutrients.Scale(grams / entry.Grams)
+			}
+			entry.Grams = grams
+		}
+	case repo.KindQuick:
+		if form.Nutrients != nil {
+			entry.Nutrients = *form.Nutrients
+		}
+	}
+
+	now := time.Now()
+	entry.UpdatedAt = &now
+
+	allergies, err := h.allergies(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	docs, err := h.repo.UpdateOne(entry)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating entry.", c)
+	}
+	warn(docs, allergies)
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Delete
+// @Tags Diary
+// @Summary Delete Diary Entry
+// @ID diary-delete
+// @Router /api/diary/{date}/entries/{id} [delete]
+// @Produce json
+// @Param date path string true "Date (YYYY-MM-DD) or today"
+// @Param id path string true "Entry ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) Delete(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	entry, _, _, err := h.findEntry(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	docs, err := h.repo.DeleteOne(entry.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting entry.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// findEntry loads the entry addressed by the :date and :id path params along
+// with the boundaries of that day in the user's time zone.
+func (h *DiaryHandler) findEntry(c echo.Context, tokenData *gear.UserClaims) (*repo.Entry, time.Time, time.Time, error) {
+	date, start, end, err := gear.ParseDay(c.Param("date"), tokenData.Location())
+	if err != nil {
+		return nil, start, end, echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD", c)
+	}
+
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, start, end, echo.NewHTTPError(http.StatusBadRequest, "Invalid entry id", c)
+	}
+
+	entry, err := h.repo.FindOne(oId, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, start, end, echo.NewHTTPError(http.StatusBadRequest, "Entry not found!", c)
+		}
+		return nil, start, end, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting entry.", c)
+	}
+
+	if entry.Date != date {
+		return nil, start, end, echo.NewHTTPError(http.StatusBadRequest, "Entry not found!", c)
+	}
+	return entry, start, end, nil
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	diaryGear "dietku-backend/cmd/diary/gear"
+	"dietku-backend/cmd/diary/repo"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const reportDays = 7
+
+type MicronutrientDay struct {
+	Date      string                     `json:"date"`
+	Logged    bool                       `json:"logged"`
+	Nutrients []diaryGear.NutrientStatus `json:"nutrients"`
+}
+
+type MicronutrientReport struct {
+	From           string                     `json:"from"`
+	To             string                     `json:"to"`
+	Timezone       string                     `json:"timezone"`
+	Recommendation diaryGear.Recommendation   `json:"recommendation"`
+	Assumed        []string                   `json:"assumed"`
+	LoggedDays     int                        `json:"loggedDays"`
+	Average        []diaryGear.NutrientStatus `json:"average"`
+	Deficient      []string                   `json:"deficient"`
+	Excess         []string                   `json:"excess"`
+	Days           []MicronutrientDay         `json:"days"`
+}
+
+// recommendation picks the AKG row for the user. Assumed lists the profile
+// fields that were missing and replaced by defaults.
+func (h *DiaryHandler) recommendation(c echo.Context, userID primitive.ObjectID) (diaryGear.Recommendation, []string, error) {
+	user, err := h.userRepo.FindOne(userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return diaryGear.Recommendation{}, nil, echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return diaryGear.Recommendation{}, nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+
+	assumed := []string{}
+	sex := user.Sex
+	if sex == "" {
+		sex = diaryGear.DefaultSex
+		assumed = append(assumed, "sex")
+	}
+	age, ok := user.Age(time.Now())
+	if !ok {
+		age = diaryGear.DefaultAge
+		assumed = append(assumed, "age")
+	}
+	return diaryGear.Recommend(sex, age), assumed, nil
+}
+
+// Micronutrients
+// @Tags Diary
+// @Summary Get Micronutrient Report
+// @Description Vitamins, minerals, fiber and sodium eaten in the 7 days ending at to, compared with the Indonesian AKG for the user's sex and age. Each day and the average over logged days are flagged as deficient (below 70% of the AKG), adequate or excess (above the tolerable upper limit). Pass from=to for a single day.
+// @ID diary-micronutrients
+// @Router /api/diary/micronutrients [get]
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD), defaults to 6 days before to"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) Micronutrients(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	r, err := gear.ParseRange(c, loc, reportDays, reportDays)
+	if err != nil {
+		return err
+	}
+	from, to := r.From, r.To
+
+	rec, assumed, err := h.recommendation(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	totals, err := h.repo.Totals(tokenData.ID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while summarizing diary.", c)
+	}
+
+	logged := map[string]repo.DayTotal{}
+	for _, d := range totals.Days {
+		logged[d.Date] = d
+	}
+
+	report := &MicronutrientReport{
+		From:           from,
+		To:             to,
+		Timezone:       loc.String(),
+		Recommendation: rec,
+		Assumed:        assumed,
+		Deficient:      []string{},
+		Excess:         []string{},
+		Days:           []MicronutrientDay{},
+	}
+
+	sum := foodRepo.Nutrients{}
+	for day := r.FromStart; !day.After(r.ToStart); day = day.AddDate(0, 0, 1) {
+		date := day.Format(gear.DateLayout)
+		total, ok := logged[date]
+		if ok {
+			report.LoggedDays++
+			sum = sum.Add(total.Nutrients)
+		}
+		report.Days = append(report.Days, MicronutrientDay{
+			Date:      date,
+			Logged:    ok,
+			Nutrients: diaryGear.Assess(total.Nutrients, rec),
+		})
+	}
+
+	// days without entries are most likely not logged rather than fasted, so
+	// they are left out of the average
+	average := sum
+	if report.LoggedDays > 0 {
+		average = sum.Scale(1 / float64(report.LoggedDays))
+	}
+	report.Average = diaryGear.Assess(average, rec)
+	if report.LoggedDays > 0 {
+		for _, s := range report.Average {
+			switch s.Status {
+			case diaryGear.StatusDeficient:
+				report.Deficient = append(report.Deficient, s.Key)
+			case diaryGear.StatusExcess:
+				report.Excess = append(report.Excess, s.Key)
+			}
+		}
+	}
+	return c.JSON(http.StatusOK, report)
+}
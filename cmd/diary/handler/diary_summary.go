@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/diary/repo"
+	foodRepo "dietku-backend/cmd/food/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"sort"
+)
+
+const (
+	defaultSummaryDays = 7
+	maxSummaryDays     = 366
+)
+
+type Macros struct {
+	Calories     float64 `json:"calories"`
+	Protein      float64 `json:"protein"`
+	Carbohydrate float64 `json:"carbohydrate"`
+	Fat          float64 `json:"fat"`
+}
+
+type MacroPercent struct {
+	Protein      float64 `json:"protein"`
+	Carbohydrate float64 `json:"carbohydrate"`
+	Fat          float64 `json:"fat"`
+}
+
+type MealSummary struct {
+	repo.MealTotal
+	CaloriePercent float64 `json:"caloriePercent"`
+}
+
+type DaySummary struct {
+	Date         string             `json:"date"`
+	Nutrients    foodRepo.Nutrients `json:"nutrients"`
+	Burned       float64            `json:"burned"`
+	Remaining    Macros             `json:"remaining"`
+	MacroPercent MacroPercent       `json:"macroPercent"`
+	Meals        []MealSummary      `json:"meals"`
+}
+
+type WeekSummary struct {
+	Week         string             `json:"week"`
+	StartDate    string             `json:"startDate"`
+	Days         int                `json:"days"`
+	LoggedDays   int                `json:"loggedDays"`
+	Nutrients    foodRepo.Nutrients `json:"nutrients"`
+	Average      foodRepo.Nutrients `json:"average"`
+	Burned       float64            `json:"burned"`
+	Remaining    Macros             `json:"remaining"`
+	MacroPercent MacroPercent       `json:"macroPercent"`
+}
+
+type Summary struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Timezone string        `json:"timezone"`
+	Target   Macros        `json:"target"`
+	AddBack  bool          `json:"exerciseAddBack"`
+	Days     []DaySummary  `json:"days"`
+	Weeks    []WeekSummary `json:"weeks"`
+}
+
+func targetMacros(t userRepo.NutritionTarget) Macros {
+	return Macros{
+		Calories:     t.Calories,
+		Protein:      t.ProteinGrams(),
+		Carbohydrate: t.CarbohydrateGrams(),
+		Fat:          t.FatGrams(),
+	}
+}
+
+// remaining is what is left of target (scaled by days) after eating n, with
+// burned calories added back to the budget. It goes negative once the budget
+// is exceeded.
+func remaining(target Macros, days float64, n foodRepo.Nutrients, burned float64) Macros {
+	return Macros{
+		Calories:     target.Calories*days + burned - n.Calories,
+		Protein:      target.Protein*days - n.Protein,
+		Carbohydrate: target.Carbohydrate*days - n.Carbohydrate,
+		Fat:          target.Fat*days - n.Fat,
+	}
+}
+
+// macroPercent is the share of calories coming from each macro.
+func macroPercent(n foodRepo.Nutrients) MacroPercent {
+	if n.Calories <= 0 {
+		return MacroPercent{}
+	}
+	return MacroPercent{
+		Protein:      n.Protein * 4 / n.Calories * 100,
+		Carbohydrate: n.Carbohydrate * 4 / n.Calories * 100,
+		Fat:          n.Fat * 9 / n.Calories * 100,
+	}
+}
+
+func mealOrder(meal string) int {
+	for i, m := range repo.Meals {
+		if m == meal {
+			return i
+		}
+	}
+	return len(repo.Meals)
+}
+
+func isoWeekKey(year int, week int) string {
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Summary
+// @Tags Diary
+// @Summary Get Diary Summary
+// @Description Per-day and per-ISO-week totals of every nutrient, remaining budget versus the user's target, per-meal breakdown and macro split. Defaults to the last 7 days. Calories burned by exercise are reported and, when enabled in the server config, added back to the calorie budget.
+// @ID diary-summary
+// @Router /api/diary/summary [get]
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *DiaryHandler) Summary(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	r, err := gear.ParseRange(c, loc, defaultSummaryDays, maxSummaryDays)
+	if err != nil {
+		return err
+	}
+	from, to := r.From, r.To
+
+	user, err := h.userRepo.FindOne(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+	target := targetMacros(user.NutritionTarget())
+
+	totals, err := h.repo.Totals(tokenData.ID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while summarizing diary.", c)
+	}
+
+	logged := map[string]repo.DayTotal{}
+	for _, d := range totals.Days {
+		logged[d.Date] = d
+	}
+
+	exercise, err := h.exerciseRepo.DailyTotals(tokenData.ID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while summarizing exercise.", c)
+	}
+	burned := map[string]float64{}
+	for _, e := range exercise {
+		burned[e.Date] = e.Calories
+	}
+	addBack := func(calories float64) float64 {
+		if h.conf.ExerciseAddBack {
+			return calories
+		}
+		return 0
+	}
+
+	summary := &Summary{
+		From:     from,
+		To:       to,
+		Timezone: loc.String(),
+		Target:   target,
+		AddBack:  h.conf.ExerciseAddBack,
+		Days:     []DaySummary{},
+		Weeks:    []WeekSummary{},
+	}
+
+	// walk every date so days without entries still show the full budget
+	weekIndex := map[string]int{}
+	for day := r.FromStart; !day.After(r.ToStart); day = day.AddDate(0, 0, 1) {
+		date := day.Format(gear.DateLayout)
+		total := logged[date]
+
+		meals := make([]MealSummary, 0, len(total.Meals))
+		for _, m := range total.Meals {
+			ms := MealSummary{MealTotal: m}
+			if total.Nutrients.Calories > 0 {
+				ms.CaloriePercent = m.Nutrients.Calories / total.Nutrients.Calories * 100
+			}
+			meals = append(meals, ms)
+		}
+		sort.SliceStable(meals, func(i, j int) bool {
+			if mealOrder(meals[i].Meal) != mealOrder(meals[j].Meal) {
+				return mealOrder(meals[i].Meal) < mealOrder(meals[j].Meal)
+			}
+			return meals[i].MealName < meals[j].MealName
+		})
+
+		summary.Days = append(summary.Days, DaySummary{
+			Date:         date,
+			Nutrients:    total.Nutrients,
+			Burned:       burned[date],
+			Remaining:    remaining(target, 1, total.Nutrients, addBack(burned[date])),
+			MacroPercent: macroPercent(total.Nutrients),
+			Meals:        meals,
+		})
+
+		year, week := day.ISOWeek()
+		key := isoWeekKey(year, week)
+		i, ok := weekIndex[key]
+		if !ok {
+			i = len(summary.Weeks)
+			weekIndex[key] = i
+			weekday := (int(day.Weekday()) + 6) % 7
+			summary.Weeks = append(summary.Weeks, WeekSummary{
+				Week:      key,
+				StartDate: day.AddDate(0, 0, -weekday).Format(gear.DateLayout),
+			})
+		}
+		summary.Weeks[i].Days++
+		summary.Weeks[i].Burned += burned[date]
+	}
+
+	for _, w := range totals.Weeks {
+		i, ok := weekIndex[isoWeekKey(w.Year, w.Week)]
+		if !ok {
+			continue
+		}
+		summary.Weeks[i].LoggedDays = w.LoggedDays
+		summary.Weeks[i].Nutrients = w.Nutrients
+	}
+	for i := range summary.Weeks {
+		w := &summary.Weeks[i]
+		if w.LoggedDays > 0 {
+			w.Average = w.Nutrients.Scale(1 / float64(w.LoggedDays))
+		}
+		w.Remaining = remaining(target, float64(w.Days), w.Nutrients, addBack(w.Burned))
+		w.MacroPercent = macroPercent(w.Nutrients)
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,178 @@
+package repo
+
+import (
+	"context"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+	MealSnacks    = "snacks"
+	MealCustom    = "custom"
+
+	KindFood   = "food"
+	KindRecipe = "recipe"
+	KindQuick  = "quick"
+)
+
+// Meals lists the meals in the order a day is displayed.
+var Meals = []string{MealBreakfast, MealLunch, MealDinner, MealSnacks, MealCustom}
+
+// Entry is a single logged item. Nutrients are snapshotted when the entry is
+// logged so later edits to the food do not rewrite the user's history.
+type Entry struct {
+	ID          primitive.ObjectID  `json:"_id" bson:"_id"`
+	UserID      primitive.ObjectID  `json:"userId" bson:"userId"`
+	Date        string              `json:"date" bson:"date"`
+	Meal        string              `json:"meal" bson:"meal"`
+	MealName    string              `json:"mealName,omitempty" bson:"mealName,omitempty"`
+	Kind        string              `json:"kind" bson:"kind"`
+	FoodID      *primitive.ObjectID `json:"foodId,omitempty" bson:"foodId,omitempty"`
+	RecipeID    *primitive.ObjectID `json:"recipeId,omitempty" bson:"recipeId,omitempty"`
+	Name        string              `json:"name" bson:"name"`
+	Brand       string              `json:"brand,omitempty" bson:"brand,omitempty"`
+	Quantity    float64             `json:"quantity" bson:"quantity"`
+	Unit        string              `json:"unit" bson:"unit"`
+	ServingSize float64             `json:"servingSize,omitempty" bson:"servingSize,omitempty"`
+	Grams       float64             `json:"grams" bson:"grams"`
+	Nutrients   foodRepo.Nutrients  `json:"nutrients" bson:"nutrients"`
+	Allergens   []string            `json:"allergens,omitempty" bson:"allergens,omitempty"`
+	Warnings    []string            `json:"warnings,omitempty" bson:"-"`
+	LoggedAt    time.Time           `json:"loggedAt" bson:"loggedAt"`
+	CreatedAt   time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   *time.Time          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	IsDeleted   bool                `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Entries []Entry
+
+func DecodeAsEntries(cursor *mongo.Cursor) (*Entries, error) {
+	docs := Entries{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type DiaryRepository struct {
+	coll *mongo.Collection
+}
+
+func NewDiaryRepository(db *mongo.Database) *DiaryRepository {
+	return &DiaryRepository{
+		coll: db.Collection("diary_entries"),
+	}
+}
+
+// EnsureIndexes creates the index every diary query starts from: a user's
+// entries for a date or range of dates, which also serves the summary pipeline.
+func (r *DiaryRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}, {Key: "loggedAt", Value: 1}},
+	})
+	return err
+}
+
+func (r *DiaryRepository) FindByDate(userID primitive.ObjectID, date string) (*Entries, error) {
+	filter := bson.M{"userId": userID, "date": date, "isDeleted": bson.M{"$ne": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "loggedAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsEntries(cursor)
+}
+
+// FindByMeal returns the entries of one meal. mealName only narrows the result
+// for custom meals, where several can exist on the same day.
+func (r *DiaryRepository) FindByMeal(userID primitive.ObjectID, date string, meal string, mealName string) (*Entries, error) {
+	filter := bson.M{"userId": userID, "date": date, "meal": meal, "isDeleted": bson.M{"$ne": true}}
+	if meal == MealCustom && mealName != "" {
+		filter["mealName"] = mealName
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "loggedAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsEntries(cursor)
+}
+
+func (r *DiaryRepository) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*Entry, error) {
+	var d = &Entry{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// LoggedDates returns every date the user logged at least one entry on, in no
+// particular order.
+func (r *DiaryRepository) LoggedDates(userID primitive.ObjectID) ([]string, error) {
+	filter := bson.M{"userId": userID, "isDeleted": bson.M{"$ne": true}}
+	values, err := r.coll.Distinct(context.TODO(), "date", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, 0, len(values))
+	for _, v := range values {
+		if d, ok := v.(string); ok {
+			dates = append(dates, d)
+		}
+	}
+	return dates, nil
+}
+
+func (r *DiaryRepository) InsertOne(newEntry *Entry) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newEntry)
+}
+
+func (r *DiaryRepository) InsertMany(newEntries Entries) (*mongo.InsertManyResult, error) {
+	docs := make([]interface{}, len(newEntries))
+	for i := range newEntries {
+		docs[i] = newEntries[i]
+	}
+	return r.coll.InsertMany(context.TODO(), docs)
+}
+
+func (r *DiaryRepository) UpdateOne(entry *Entry) (*Entry, error) {
+	filter := bson.M{"_id": entry.ID}
+
+	update := bson.M{
+		"$set": entry,
+	}
+
+	var d = &Entry{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *DiaryRepository) DeleteOne(id primitive.ObjectID) (*Entry, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Entry{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
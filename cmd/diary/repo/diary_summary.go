@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MealTotal struct {
+	Meal      string             `json:"meal" bson:"meal"`
+	MealName  string             `json:"mealName,omitempty" bson:"mealName,omitempty"`
+	Nutrients foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+}
+
+type DayTotal struct {
+	Date      string             `json:"date" bson:"date"`
+	Meals     []MealTotal        `json:"meals" bson:"meals"`
+	Nutrients foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+}
+
+type WeekTotal struct {
+	Year       int                `json:"year" bson:"year"`
+	Week       int                `json:"week" bson:"week"`
+	LoggedDays int                `json:"loggedDays" bson:"loggedDays"`
+	Nutrients  foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+}
+
+type Totals struct {
+	Days  []DayTotal  `json:"days" bson:"days"`
+	Weeks []WeekTotal `json:"weeks" bson:"weeks"`
+}
+
+// sumNutrients builds $sum accumulators for every nutrient read from prefix,
+// e.g. "$nutrients." for raw entries or "$" for an already grouped stage.
+func sumNutrients(group bson.M, prefix string) bson.M {
+	for _, f := range foodRepo.NutrientFields {
+		group[f] = bson.M{"$sum": prefix + f}
+	}
+	return group
+}
+
+// nestNutrients turns the flat sums of a $group stage back into a nutrients
+// sub-document matching foodRepo.Nutrients.
+func nestNutrients() bson.M {
+	n := bson.M{}
+	for _, f := range foodRepo.NutrientFields {
+		n[f] = "$" + f
+	}
+	return n
+}
+
+// Totals sums the user's entries between from and to (inclusive, YYYY-MM-DD)
+// per day, per meal and per ISO week inside the database. Only the grouped
+// totals travel back, so a year of data stays a few hundred small documents.
+func (r *DiaryRepository) Totals(userID primitive.ObjectID, from string, to string) (*Totals, error) {
+	match := bson.M{
+		"userId":    userID,
+		"date":      bson.M{"$gte": from, "$lte": to},
+		"isDeleted": bson.M{"$ne": true},
+	}
+
+	days := bson.A{
+		bson.M{"$group": sumNutrients(bson.M{
+			"_id": bson.M{"date": "$date", "meal": "$meal", "mealName": "$mealName"},
+		}, "$nutrients.")},
+		bson.M{"$group": sumNutrients(bson.M{
+			"_id": "$_id.date",
+			"meals": bson.M{"$push": bson.M{
+				"meal":      "$_id.meal",
+				"mealName":  "$_id.mealName",
+				"nutrients": nestNutrients(),
+			}},
+		}, "$")},
+		bson.M{"$project": bson.M{"_id": 0, "date": "$_id", "meals": 1, "nutrients": nestNutrients()}},
+		bson.M{"$sort": bson.M{"date": 1}},
+	}
+
+	weeks := bson.A{
+		bson.M{"$addFields": bson.M{
+			"day": bson.M{"$dateFromString": bson.M{"dateString": "$date", "format": "%Y-%m-%d"}},
+		}},
+		bson.M{"$group": sumNutrients(bson.M{
+			"_id":   bson.M{"year": bson.M{"$isoWeekYear": "$day"}, "week": bson.M{"$isoWeek": "$day"}},
+			"dates": bson.M{"$addToSet": "$date"},
+		}, "$nutrients.")},
+		bson.M{"$project": bson.M{
+			"_id":        0,
+			"year":       "$_id.year",
+			"week":       "$_id.week",
+			"loggedDays": bson.M{"$size": "$dates"},
+			"nutrients":  nestNutrients(),
+		}},
+		bson.M{"$sort": bson.D{{Key: "year", Value: 1}, {Key: "week", Value: 1}}},
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$facet": bson.M{"days": days, "weeks": weeks}},
+	}
+
+	cursor, err := r.coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []Totals{}
+	if err := cursor.All(context.TODO(), &docs); err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return &Totals{}, nil
+	}
+	return &docs[0], nil
+}
@@ -0,0 +1,63 @@
+package event
+
+import (
+	"dietku-backend/cmd/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+)
+
+// Event types published by the handlers.
+const (
+	DiaryLogged   = "diary.logged"
+	WeightLogged  = "weight.logged"
+	BlogPublished = "blog.published"
+	BlogDeleted   = "blog.deleted"
+)
+
+// Event tells subscribers that something happened to a user. Date is the
+// user's local day when it matters; Value is event specific, e.g. the
+// previous weight for WeightLogged.
+type Event struct {
+	Type   string
+	UserID primitive.ObjectID
+	Date   string
+	Value  float64
+	At     time.Time
+}
+
+type Handler func(Event) error
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string][]Handler{}
+)
+
+// Subscribe registers h for events of type typ.
+func Subscribe(typ string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[typ] = append(handlers[typ], h)
+}
+
+// Publish hands e to every subscriber in the background, so the request that
+// caused it does not wait. Subscriber errors and panics are only logged.
+func Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	mu.RLock()
+	subscribers := handlers[e.Type]
+	mu.RUnlock()
+
+	for _, h := range subscribers {
+		go func(h Handler) {
+			defer log.RecoverWithTrace()
+
+			if err := h(e); err != nil {
+				log.Errorf("failed to handle %s event for %s: %v", e.Type, e.UserID.Hex(), err)
+			}
+		}(h)
+	}
+}
@@ -0,0 +1,52 @@
+package gear
+
+import (
+	"dietku-backend/cmd/exercise/repo"
+	_ "embed"
+	"encoding/json"
+)
+
+const (
+	IntensityLight    = "light"
+	IntensityModerate = "moderate"
+	IntensityVigorous = "vigorous"
+
+	// DefaultWeight is used for users that have not entered their weight.
+	DefaultWeight = 70.0
+)
+
+// compendium is a subset of the Compendium of Physical Activities with MET
+// values per intensity.
+//
+//go:embed compendium.json
+var compendium []byte
+
+// Compendium returns the bundled catalogue of exercises.
+func Compendium() ([]repo.Exercise, error) {
+	exercises := []repo.Exercise{}
+	if err := json.Unmarshal(compendium, &exercises); err != nil {
+		return nil, err
+	}
+	return exercises, nil
+}
+
+func IsValidIntensity(intensity string) bool {
+	return intensity == IntensityLight || intensity == IntensityModerate || intensity == IntensityVigorous
+}
+
+// Met returns the MET value of an exercise at the given intensity.
+func Met(mets repo.Mets, intensity string) float64 {
+	switch intensity {
+	case IntensityLight:
+		return mets.Light
+	case IntensityVigorous:
+		return mets.Vigorous
+	}
+	return mets.Moderate
+}
+
+// BurnedCalories estimates the energy used: one MET is 1 kcal per kg of body
+// weight per hour.
+func BurnedCalories(met float64, weight float64, minutes float64) float64 {
+	return met * weight * minutes / 60
+}
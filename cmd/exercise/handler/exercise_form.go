@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"dietku-backend/cmd/exercise/gear"
+	"dietku-backend/cmd/exercise/repo"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	maxDuration = 24 * 60
+	maxDistance = 1000
+	maxMet      = 25
+)
+
+type ExerciseLogForm struct {
+	ExerciseID primitive.ObjectID `json:"exerciseId"`
+	Duration   float64            `json:"duration"`
+	Intensity  string             `json:"intensity"`
+	Distance   float64            `json:"distance"`
+	LoggedAt   *time.Time         `json:"loggedAt"`
+}
+
+func NewExerciseLogForm(c echo.Context) (*ExerciseLogForm, error) {
+	form := new(ExerciseLogForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.ExerciseID.IsZero() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "ExerciseId is required.")
+	}
+
+	if form.Duration <= 0 || form.Duration > maxDuration {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Duration must be between 1 and 1440 minutes.")
+	}
+
+	form.Intensity = strings.ToLower(strings.TrimSpace(form.Intensity))
+	if form.Intensity == "" {
+		form.Intensity = gear.IntensityModerate
+	}
+	if !gear.IsValidIntensity(form.Intensity) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Intensity must be one of light, moderate or vigorous.")
+	}
+
+	if form.Distance < 0 || form.Distance > maxDistance {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Distance must be between 0 and 1000 km.")
+	}
+
+	if form.LoggedAt != nil && form.LoggedAt.After(time.Now().Add(time.Minute)) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "LoggedAt cannot be in the future.")
+	}
+	return form, nil
+}
+
+type ExerciseForm struct {
+	Name     string    `json:"name"`
+	Category string    `json:"category"`
+	Mets     repo.Mets `json:"mets"`
+}
+
+func NewExerciseForm(c echo.Context) (*ExerciseForm, error) {
+	form := new(ExerciseForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required.")
+	}
+
+	form.Category = strings.ToLower(strings.TrimSpace(form.Category))
+	if form.Category == "" {
+		form.Category = "custom"
+	}
+
+	// a single MET value is enough, the other intensities fall back to it
+	m := &form.Mets
+	if m.Moderate <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "A moderate MET value is required.")
+	}
+	if m.Light <= 0 {
+		m.Light = m.Moderate
+	}
+	if m.Vigorous <= 0 {
+		m.Vigorous = m.Moderate
+	}
+	if m.Light > maxMet || m.Moderate > maxMet || m.Vigorous > maxMet {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "MET values must not exceed 25.")
+	}
+	return form, nil
+}
@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	exerciseGear "dietku-backend/cmd/exercise/gear"
+	"dietku-backend/cmd/exercise/repo"
+	"dietku-backend/cmd/log"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+type ExerciseHandler struct {
+	repo     *repo.ExerciseRepository
+	userRepo *userRepo.UserRepository
+}
+
+func NewExerciseApi(e *echo.Echo, db *mongo.Database) *ExerciseHandler {
+	h := &ExerciseHandler{
+		repo:     repo.NewExerciseRepository(db),
+		userRepo: userRepo.NewUserRepository(db),
+	}
+	if err := h.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create exercise indexes: %v", err)
+	}
+
+	compendium, err := exerciseGear.Compendium()
+	if err != nil {
+		log.Errorf("failed to read exercise compendium: %v", err)
+	} else if err := h.repo.Seed(compendium); err != nil {
+		log.Errorf("failed to seed exercises: %v", err)
+	}
+
+	e.GET("/api/exercises", h.GetAll, gear.MaybeLoggedIn(db))
+
+	eGroup := e.Group("")
+	eGroup.Use(gear.IsLoggedIn(db))
+	{
+		eGroup.GET("/api/exercise-logs/:date", h.Day)
+
+		eGroup.POST("/api/exercises", h.CreateExercise)
+		eGroup.POST("/api/exercise-logs", h.Create)
+
+		eGroup.DELETE("/api/exercises/:id", h.DeleteExercise)
+		eGroup.DELETE("/api/exercise-logs/:id", h.Delete)
+	}
+	return h
+}
+
+type ExerciseDay struct {
+	Date     string             `json:"date"`
+	Timezone string             `json:"timezone"`
+	Calories float64            `json:"calories"`
+	Duration float64            `json:"duration"`
+	Logs     *repo.ExerciseLogs `json:"logs"`
+}
+
+// GetAll
+// @Tags Exercise
+// @Summary Get Exercises
+// @Description The bundled catalogue with MET values per intensity, plus the user's custom exercises when logged in.
+// @ID exercise-get-all
+// @Router /api/exercises [get]
+// @Produce json
+// @Param q query string false "Search by name"
+// @Success 200
+func (h *ExerciseHandler) GetAll(c echo.Context) error {
+	var userID primitive.ObjectID
+	if me, ok := c.Get("me").(*gear.UserClaims); ok {
+		userID = me.ID
+	}
+
+	docs, err := h.repo.FindAll(c.QueryParam("q"), userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting exercises.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// CreateExercise
+// @Tags Exercise
+// @Summary Create Custom Exercise
+// @Description Custom exercises are only visible to their creator. Light and vigorous MET values default to the moderate one.
+// @ID exercise-create
+// @Router /api/exercises [post]
+// @Accept json
+// @Param body body ExerciseForm true "exercise body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ExerciseHandler) CreateExercise(c echo.Context) error {
+	form, err := NewExerciseForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	ex := &repo.Exercise{
+		ID:        primitive.NewObjectID(),
+		Name:      form.Name,
+		Category:  form.Category,
+		Mets:      form.Mets,
+		CreatedBy: &tokenData.ID,
+		CreatedAt: time.Now(),
+	}
+
+	_, err = h.repo.InsertOne(ex)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating exercise.", c)
+	}
+	return c.JSON(http.StatusOK, ex)
+}
+
+// DeleteExercise
+// @Tags Exercise
+// @Summary Delete Custom Exercise
+// @Description Existing logs keep their calories.
+// @ID exercise-delete
+// @Router /api/exercises/{id} [delete]
+// @Produce json
+// @Param id path string true "Exercise ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ExerciseHandler) DeleteExercise(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid exercise id", c)
+	}
+
+	ex, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Exercise not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting exercise.", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	if ex.CreatedBy == nil || *ex.CreatedBy != tokenData.ID {
+		return echo.NewHTTPError(http.StatusForbidden, "You are not allowed to delete this exercise.", c)
+	}
+
+	docs, err := h.repo.DeleteOne(ex.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting exercise.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Day
+// @Tags Exercise
+// @Summary Get Exercise Logs By Date
+// @Description Logs and totals for one day in the user's time zone.
+// @ID exercise-log-day
+// @Router /api/exercise-logs/{date} [get]
+// @Produce json
+// @Param date path string true "Date (YYYY-MM-DD) or today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ExerciseHandler) Day(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	date, _, _, err := gear.ParseDay(c.Param("date"), loc)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD", c)
+	}
+
+	logs, err := h.repo.FindLogsByDate(tokenData.ID, date)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting exercise logs.", c)
+	}
+
+	day := &ExerciseDay{
+		Date:     date,
+		Timezone: loc.String(),
+		Logs:     logs,
+	}
+	for _, l := range *logs {
+		day.Calories += l.Calories
+		day.Duration += l.Duration
+	}
+	return c.JSON(http.StatusOK, day)
+}
+
+// Create
+// @Tags Exercise
+// @Summary Log Exercise
+// @Description Burned calories are MET x weight (kg) x hours, using the user's weight or 70 kg when it is not set. Duration is in minutes and distance in km.
+// @ID exercise-log-create
+// @Router /api/exercise-logs [post]
+// @Accept json
+// @Param body body ExerciseLogForm true "exercise log body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ExerciseHandler) Create(c echo.Context) error {
+	form, err := NewExerciseLogForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	ex, err := h.repo.FindOne(form.ExerciseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Exercise not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting exercise.", c)
+	}
+	if ex.CreatedBy != nil && *ex.CreatedBy != tokenData.ID {
+		return echo.NewHTTPError(http.StatusBadRequest, "Exercise not found!", c)
+	}
+
+	user, err := h.userRepo.FindOne(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+	weight := user.Weight
+	if weight <= 0 {
+		weight = exerciseGear.DefaultWeight
+	}
+
+	at := time.Now()
+	if form.LoggedAt != nil {
+		at = *form.LoggedAt
+	}
+
+	met := exerciseGear.Met(ex.Mets, form.Intensity)
+	l := &repo.ExerciseLog{
+		ID:         primitive.NewObjectID(),
+		UserID:     tokenData.ID,
+		ExerciseID: ex.ID,
+		Name:       ex.Name,
+		Date:       at.In(tokenData.Location()).Format(gear.DateLayout),
+		Duration:   form.Duration,
+		Intensity:  form.Intensity,
+		Distance:   form.Distance,
+		Met:        met,
+		Weight:     weight,
+		Calories:   exerciseGear.BurnedCalories(met, weight, form.Duration),
+		LoggedAt:   at,
+		CreatedAt:  time.Now(),
+	}
+
+	_, err = h.repo.InsertLog(l)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while logging exercise.", c)
+	}
+	return c.JSON(http.StatusOK, l)
+}
+
+// Delete
+// @Tags Exercise
+// @Summary Delete Exercise Log
+// @ID exercise-log-delete
+// @Router /api/exercise-logs/{id} [delete]
+// @Produce json
+// @Param id path string true "Exercise log ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ExerciseHandler) Delete(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid exercise log id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	l, err := h.repo.FindLog(oId, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Exercise log not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting exercise log.", c)
+	}
+
+	docs, err := h.repo.DeleteLog(l.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting exercise log.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
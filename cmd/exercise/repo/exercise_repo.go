@@ -0,0 +1,242 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
+)
+
+type Mets struct {
+	Light    float64 `json:"light" bson:"light"`
+	Moderate float64 `json:"moderate" bson:"moderate"`
+	Vigorous float64 `json:"vigorous" bson:"vigorous"`
+}
+
+// Exercise is a catalogue entry. Bundled entries have a Code, custom ones
+// belong to the user in CreatedBy.
+type Exercise struct {
+	ID        primitive.ObjectID  `json:"_id" bson:"_id"`
+	Code      string              `json:"code,omitempty" bson:"code,omitempty"`
+	Name      string              `json:"name" bson:"name"`
+	Category  string              `json:"category" bson:"category"`
+	Mets      Mets                `json:"mets" bson:"mets"`
+	CreatedBy *primitive.ObjectID `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	CreatedAt time.Time           `json:"createdAt" bson:"createdAt"`
+	IsDeleted bool                `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Exercises []Exercise
+
+func DecodeAsExercises(cursor *mongo.Cursor) (*Exercises, error) {
+	docs := Exercises{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type ExerciseLog struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID     primitive.ObjectID `json:"userId" bson:"userId"`
+	ExerciseID primitive.ObjectID `json:"exerciseId" bson:"exerciseId"`
+	Name       string             `json:"name" bson:"name"`
+	Date       string             `json:"date" bson:"date"`
+	Duration   float64            `json:"duration" bson:"duration"`
+	Intensity  string             `json:"intensity" bson:"intensity"`
+	Distance   float64            `json:"distance,omitempty" bson:"distance,omitempty"`
+	Met        float64            `json:"met" bson:"met"`
+	Weight     float64            `json:"weight" bson:"weight"`
+	Calories   float64            `json:"calories" bson:"calories"`
+	LoggedAt   time.Time          `json:"loggedAt" bson:"loggedAt"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	IsDeleted  bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+type ExerciseLogs []ExerciseLog
+
+func DecodeAsExerciseLogs(cursor *mongo.Cursor) (*ExerciseLogs, error) {
+	docs := ExerciseLogs{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type DayTotal struct {
+	Date     string  `json:"date" bson:"date"`
+	Calories float64 `json:"calories" bson:"calories"`
+	Duration float64 `json:"duration" bson:"duration"`
+}
+
+type ExerciseRepository struct {
+	coll    *mongo.Collection
+	logColl *mongo.Collection
+}
+
+func NewExerciseRepository(db *mongo.Database) *ExerciseRepository {
+	return &ExerciseRepository{
+		coll:    db.Collection("exercises"),
+		logColl: db.Collection("exercise_logs"),
+	}
+}
+
+func (r *ExerciseRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"code": bson.M{"$exists": true},
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.logColl.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}},
+	})
+	return err
+}
+
+// Seed upserts the bundled catalogue by code, so updated MET values in the
+// compendium file reach existing databases on the next start.
+func (r *ExerciseRepository) Seed(exercises []Exercise) error {
+	models := make([]mongo.WriteModel, 0, len(exercises))
+	for _, e := range exercises {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"code": e.Code}).
+			SetUpdate(bson.M{
+				"$set":         bson.M{"name": e.Name, "category": e.Category, "mets": e.Mets, "isDeleted": false},
+				"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "createdAt": time.Now()},
+			}).
+			SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := r.coll.BulkWrite(context.TODO(), models)
+	return err
+}
+
+// FindAll lists the bundled exercises plus the custom ones of userID.
+func (r *ExerciseRepository) FindAll(query string, userID primitive.ObjectID) (*Exercises, error) {
+	owners := bson.A{bson.M{"createdBy": bson.M{"$exists": false}}}
+	if !userID.IsZero() {
+		owners = append(owners, bson.M{"createdBy": userID})
+	}
+
+	filter := bson.M{"$or": owners, "isDeleted": bson.M{"$ne": true}}
+	if query != "" {
+		filter["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "category", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsExercises(cursor)
+}
+
+func (r *ExerciseRepository) FindOne(id primitive.ObjectID) (*Exercise, error) {
+	var d = &Exercise{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *ExerciseRepository) InsertOne(newExercise *Exercise) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newExercise)
+}
+
+func (r *ExerciseRepository) DeleteOne(id primitive.ObjectID) (*Exercise, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Exercise{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *ExerciseRepository) FindLogsByDate(userID primitive.ObjectID, date string) (*ExerciseLogs, error) {
+	filter := bson.M{"userId": userID, "date": date, "isDeleted": bson.M{"$ne": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "loggedAt", Value: 1}})
+	cursor, err := r.logColl.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsExerciseLogs(cursor)
+}
+
+func (r *ExerciseRepository) FindLog(id primitive.ObjectID, userID primitive.ObjectID) (*ExerciseLog, error) {
+	var d = &ExerciseLog{}
+	err := r.logColl.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DailyTotals sums burned calories and minutes per day between from and to
+// (inclusive). Days without exercise are not returned.
+func (r *ExerciseRepository) DailyTotals(userID primitive.ObjectID, from string, to string) ([]DayTotal, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{
+			"userId":    userID,
+			"date":      bson.M{"$gte": from, "$lte": to},
+			"isDeleted": bson.M{"$ne": true},
+		}},
+		bson.M{"$group": bson.M{
+			"_id":      "$date",
+			"calories": bson.M{"$sum": "$calories"},
+			"duration": bson.M{"$sum": "$duration"},
+		}},
+		bson.M{"$project": bson.M{"_id": 0, "date": "$_id", "calories": 1, "duration": 1}},
+		bson.M{"$sort": bson.M{"date": 1}},
+	}
+
+	cursor, err := r.logColl.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []DayTotal{}
+	if err := cursor.All(context.TODO(), &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *ExerciseRepository) InsertLog(newLog *ExerciseLog) (*mongo.InsertOneResult, error) {
+	return r.logColl.InsertOne(context.TODO(), newLog)
+}
+
+func (r *ExerciseRepository) DeleteLog(id primitive.ObjectID) (*ExerciseLog, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &ExerciseLog{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.logColl.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
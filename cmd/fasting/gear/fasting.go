@@ -0,0 +1,252 @@
+package gear
+
+import (
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/fasting/repo"
+	"dietku-backend/cmd/log"
+	notificationRepo "dietku-backend/cmd/notification/repo"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"time"
+)
+
+const (
+	ProtocolCustom = "custom"
+
+	ReminderStart = "start"
+	ReminderEnd   = "end"
+
+	// TimeLayout is the format of schedule start times.
+	TimeLayout = "15:04"
+
+	// nextReminderDays is how far ahead NextReminder looks; every schedule
+	// has a fast within a week.
+	nextReminderDays = 8
+)
+
+// Protocols maps each named protocol to its fasting hours.
+var Protocols = map[string]float64{
+	"12:12": 12,
+	"14:10": 14,
+	"16:8":  16,
+	"18:6":  18,
+	"20:4":  20,
+	"omad":  23,
+	"36h":   36,
+}
+
+var ErrInvalidTime = errors.New("invalid time, use HH:MM")
+
+// PlannedHours returns the fasting hours of protocol, or custom for the
+// custom protocol. ok is false for unknown protocols.
+func PlannedHours(protocol string, custom float64) (float64, bool) {
+	if protocol == ProtocolCustom {
+		return custom, custom > 0
+	}
+	hours, ok := Protocols[protocol]
+	return hours, ok
+}
+
+type Stats struct {
+	Sessions       int     `json:"sessions"`
+	Completed      int     `json:"completed"`
+	CompletionRate float64 `json:"completionRate"`
+	AverageHours   float64 `json:"averageHours"`
+	LongestHours   float64 `json:"longestHours"`
+	CurrentStreak  int     `json:"currentStreak"`
+	LongestStreak  int     `json:"longestStreak"`
+}
+
+// Summarize computes statistics over finished sessions. A streak is a run of
+// consecutive days that each started a completed fast; the current streak may
+// end yesterday, since today's fast may not have started yet.
+func Summarize(sessions []repo.Session, today string) Stats {
+	s := Stats{}
+	completedDays := map[string]bool{}
+	total := 0.0
+	for _, f := range sessions {
+		if f.Active {
+			continue
+		}
+		s.Sessions++
+		total += f.Hours
+		if f.Hours > s.LongestHours {
+			s.LongestHours = f.Hours
+		}
+		if f.Completed {
+			s.Completed++
+			completedDays[f.Date] = true
+		}
+	}
+	if s.Sessions == 0 {
+		return s
+	}
+	s.AverageHours = total / float64(s.Sessions)
+	s.CompletionRate = float64(s.Completed) / float64(s.Sessions) * 100
+
+	days := make([]string, 0, len(completedDays))
+	for d := range completedDays {
+		days = append(days, d)
+	}
+	sort.Strings(days)
+
+	run := 0
+	var prev time.Time
+	for _, d := range days {
+		day, _ := time.Parse(authGear.DateLayout, d)
+		if run > 0 && day.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.LongestStreak {
+			s.LongestStreak = run
+		}
+		prev = day
+	}
+
+	day, _ := time.Parse(authGear.DateLayout, today)
+	if !completedDays[today] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for completedDays[day.Format(authGear.DateLayout)] {
+		s.CurrentStreak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return s
+}
+
+type Reminder struct {
+	Kind     string    `json:"kind"`
+	At       time.Time `json:"at"`
+	Protocol string    `json:"protocol"`
+}
+
+// Reminders lists the start and end reminders of schedule falling between
+// from and from+days, in loc. Fasts that started before from still produce
+// their end reminder.
+func Reminders(schedule *repo.Schedule, loc *time.Location, from time.Time, days int) ([]Reminder, error) {
+	clock, err := time.Parse(TimeLayout, schedule.StartTime)
+	if err != nil {
+		return nil, ErrInvalidTime
+	}
+
+	weekdays := map[time.Weekday]bool{}
+	for _, d := range schedule.Days {
+		weekdays[time.Weekday(d)] = true
+	}
+
+	until := from.AddDate(0, 0, days)
+	duration := time.Duration(schedule.PlannedHours * float64(time.Hour))
+
+	// a fast can last longer than a day, so look back far enough to catch
+	// end reminders of fasts already running
+	lookBack := int(schedule.PlannedHours/24) + 1
+
+	local := from.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -lookBack)
+
+	reminders := []Reminder{}
+	for ; day.Before(until); day = day.AddDate(0, 0, 1) {
+		if !weekdays[day.Weekday()] {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		end := start.Add(duration)
+		if !start.Before(from) && start.Before(until) {
+			reminders = append(reminders, Reminder{Kind: ReminderStart, At: start, Protocol: schedule.Protocol})
+		}
+		if !end.Before(from) && end.Before(until) {
+			reminders = append(reminders, Reminder{Kind: ReminderEnd, At: end, Protocol: schedule.Protocol})
+		}
+	}
+	sort.SliceStable(reminders, func(i, j int) bool {
+		return reminders[i].At.Before(reminders[j].At)
+	})
+	return reminders, nil
+}
+
+// NextReminder returns the first reminder of schedule after now in the
+// schedule's time zone, or nil when reminders are off.
+func NextReminder(schedule *repo.Schedule, now time.Time) (*Reminder, error) {
+	if !schedule.Reminders {
+		return nil, nil
+	}
+
+	reminders, err := Reminders(schedule, authGear.LoadLocation(schedule.Timezone), now, nextReminderDays)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reminders {
+		if reminders[i].At.After(now) {
+			return &reminders[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Store is the part of the schedule collection the reminder worker uses.
+type Store interface {
+	FindDueSchedules(now time.Time, limit int64) (*repo.Schedules, error)
+	FireSchedule(userID primitive.ObjectID, due time.Time, next *time.Time, nextKind string) (bool, error)
+}
+
+// Notifier delivers a reminder to the user.
+type Notifier interface {
+	Notify(userID primitive.ObjectID, typ string, title string, body string, payload map[string]interface{}) (*notificationRepo.Notification, error)
+}
+
+// FireDue sends up to limit reminders due at now and moves each schedule on to
+// its next reminder. Reminders missed while no instance was running are sent
+// once, not once per missed fast.
+func FireDue(store Store, notifier Notifier, now time.Time, limit int64) error {
+	due, err := store.FindDueSchedules(now, limit)
+	if err != nil {
+		return err
+	}
+
+	for i := range *due {
+		schedule := &(*due)[i]
+
+		var nextAt *time.Time
+		nextKind := ""
+		if next, err := NextReminder(schedule, now); err != nil {
+			log.Errorf("failed to schedule fasting reminder of %s: %v", schedule.UserID.Hex(), err)
+		} else if next != nil {
+			nextAt, nextKind = &next.At, next.Kind
+		}
+
+		fired, err := store.FireSchedule(schedule.UserID, *schedule.NextReminderAt, nextAt, nextKind)
+		if err != nil {
+			return err
+		}
+		if fired {
+			notify(notifier, schedule, now)
+		}
+	}
+	return nil
+}
+
+// notify delivers one reminder, so a failing or panicking delivery does not
+// hold up the others.
+func notify(notifier Notifier, schedule *repo.Schedule, at time.Time) {
+	defer log.RecoverWithTrace()
+
+	title := "Time to start your fast"
+	body := fmt.Sprintf("Your %s fast starts now.", schedule.Protocol)
+	if schedule.NextReminderKind == ReminderEnd {
+		title = "Your fasting window has ended"
+		body = fmt.Sprintf("Your %s fast is done, time to eat.", schedule.Protocol)
+	}
+
+	_, err := notifier.Notify(schedule.UserID, notificationRepo.TypeReminder, title, body, map[string]interface{}{
+		"kind":     "fasting-" + schedule.NextReminderKind,
+		"protocol": schedule.Protocol,
+		"firedAt":  at,
+	})
+	if err != nil {
+		log.Errorf("failed to send fasting reminder to %s: %v", schedule.UserID.Hex(), err)
+	}
+}
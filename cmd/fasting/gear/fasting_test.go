@@ -0,0 +1,373 @@
+package gear
+
+import (
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/fasting/repo"
+	"dietku-backend/cmd/log"
+	notificationRepo "dietku-backend/cmd/notification/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetLogger(echo.New())
+	os.Exit(m.Run())
+}
+
+var jakarta = authGear.LoadLocation("Asia/Jakarta")
+
+// at is a wall clock time in Jakarta on a day of March 2026, which starts on
+// a Sunday.
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2026, 3, day, hour, minute, 0, 0, jakarta)
+}
+
+func TestPlannedHours(t *testing.T) {
+	tests := []struct {
+		protocol string
+		custom   float64
+		hours    float64
+		ok       bool
+	}{
+		{"16:8", 0, 16, true},
+		{"omad", 0, 23, true},
+		{"36h", 10, 36, true},
+		{ProtocolCustom, 13.5, 13.5, true},
+		{ProtocolCustom, 0, 0, false},
+		{"5:2", 0, 0, false},
+	}
+	for _, tt := range tests {
+		hours, ok := PlannedHours(tt.protocol, tt.custom)
+		if hours != tt.hours || ok != tt.ok {
+			t.Errorf("PlannedHours(%q, %v) = %v, %v, want %v, %v", tt.protocol, tt.custom, hours, ok, tt.hours, tt.ok)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	session := func(date string, hours float64, completed bool) repo.Session {
+		return repo.Session{Date: date, Hours: hours, Completed: completed}
+	}
+	sessions := []repo.Session{
+		session("2026-03-01", 16, true),
+		session("2026-03-02", 17, true),
+		session("2026-03-03", 16, true),
+		// 03-04 has no fast
+		session("2026-03-05", 18, true),
+		session("2026-03-06", 10, false),
+		{Date: "2026-03-07", Hours: 2, Active: true},
+	}
+
+	tests := []struct {
+		name          string
+		today         string
+		currentStreak int
+	}{
+		{name: "today not completed yet", today: "2026-03-06", currentStreak: 1},
+		{name: "completed today", today: "2026-03-05", currentStreak: 1},
+		{name: "after the longest run", today: "2026-03-03", currentStreak: 3},
+		{name: "run broken by a gap", today: "2026-03-08", currentStreak: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Summarize(sessions, tt.today)
+			if s.Sessions != 5 || s.Completed != 4 {
+				t.Errorf("sessions = %d, completed = %d, want 5 and 4 without the active fast", s.Sessions, s.Completed)
+			}
+			if s.CompletionRate != 80 || s.AverageHours != 15.4 || s.LongestHours != 18 {
+				t.Errorf("rate = %v, average = %v, longest = %v, want 80, 15.4, 18", s.CompletionRate, s.AverageHours, s.LongestHours)
+			}
+			if s.LongestStreak != 3 {
+				t.Errorf("longest streak = %d, want 3", s.LongestStreak)
+			}
+			if s.CurrentStreak != tt.currentStreak {
+				t.Errorf("current streak = %d, want %d", s.CurrentStreak, tt.currentStreak)
+			}
+		})
+	}
+
+	if s := Summarize(nil, "2026-03-01"); s != (Stats{}) {
+		t.Errorf("no sessions = %+v, want zero stats", s)
+	}
+}
+
+func TestReminders(t *testing.T) {
+	// 16:8 starting 20:00 on Mondays, ending Tuesday at 12:00
+	monday := &repo.Schedule{Protocol: "16:8", PlannedHours: 16, StartTime: "20:00", Days: []int{1}}
+	// 36h starting 18:00 on Sundays, ending Tuesday at 06:00
+	sunday := &repo.Schedule{Protocol: "36h", PlannedHours: 36, StartTime: "18:00", Days: []int{0}}
+
+	tests := []struct {
+		name     string
+		schedule *repo.Schedule
+		from     time.Time
+		days     int
+		want     []Reminder
+	}{
+		{
+			name:     "start and end",
+			schedule: monday,
+			from:     at(2, 12, 0),
+			days:     7,
+			want:     []Reminder{{ReminderStart, at(2, 20, 0), "16:8"}, {ReminderEnd, at(3, 12, 0), "16:8"}},
+		},
+		{
+			name:     "fast already running",
+			schedule: monday,
+			from:     at(3, 8, 0),
+			days:     1,
+			want:     []Reminder{{ReminderEnd, at(3, 12, 0), "16:8"}},
+		},
+		{
+			name:     "fast longer than a day",
+			schedule: sunday,
+			from:     at(2, 12, 0),
+			days:     1,
+			want:     []Reminder{{ReminderEnd, at(3, 6, 0), "36h"}},
+		},
+		{
+			name:     "from in another time zone",
+			schedule: monday,
+			from:     time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC),
+			days:     1,
+			want:     []Reminder{{ReminderStart, at(2, 20, 0), "16:8"}, {ReminderEnd, at(3, 12, 0), "16:8"}},
+		},
+		{
+			name:     "nothing in range",
+			schedule: monday,
+			from:     at(3, 13, 0),
+			days:     5,
+			want:     []Reminder{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reminders(tt.schedule, jakarta, tt.from, tt.days)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Kind != tt.want[i].Kind || !got[i].At.Equal(tt.want[i].At) || got[i].Protocol != tt.want[i].Protocol {
+					t.Errorf("reminder %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	bad := &repo.Schedule{PlannedHours: 16, StartTime: "8pm", Days: []int{1}}
+	if _, err := Reminders(bad, jakarta, at(2, 0, 0), 7); !errors.Is(err, ErrInvalidTime) {
+		t.Errorf("invalid start time: err = %v, want ErrInvalidTime", err)
+	}
+}
+
+func TestNextReminder(t *testing.T) {
+	schedule := &repo.Schedule{Protocol: "16:8", PlannedHours: 16, StartTime: "20:00", Days: []int{1}, Timezone: "Asia/Jakarta", Reminders: true}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		kind string
+		at   time.Time
+	}{
+		{name: "before the start", now: at(2, 19, 0), kind: ReminderStart, at: at(2, 20, 0)},
+		{name: "at the start", now: at(2, 20, 0), kind: ReminderEnd, at: at(3, 12, 0)},
+		{name: "after the end", now: at(3, 12, 0), kind: ReminderStart, at: at(9, 20, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := NextReminder(schedule, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next == nil || next.Kind != tt.kind || !next.At.Equal(tt.at) {
+				t.Errorf("NextReminder = %+v, want %s at %v", next, tt.kind, tt.at)
+			}
+		})
+	}
+
+	off := *schedule
+	off.Reminders = false
+	if next, err := NextReminder(&off, at(2, 19, 0)); next != nil || err != nil {
+		t.Errorf("reminders off = %+v, %v, want none", next, err)
+	}
+}
+
+// memoryStore keeps schedules the way the collection does for
+// FindDueSchedules and FireSchedule, including FireSchedule only matching a
+// schedule still due at the same time.
+type memoryStore struct {
+	mu        sync.Mutex
+	schedules map[primitive.ObjectID]*repo.Schedule
+}
+
+func newMemoryStore(schedules ...*repo.Schedule) *memoryStore {
+	s := &memoryStore{schedules: map[primitive.ObjectID]*repo.Schedule{}}
+	for _, schedule := range schedules {
+		s.schedules[schedule.UserID] = schedule
+	}
+	return s
+}
+
+func (s *memoryStore) FindDueSchedules(now time.Time, limit int64) (*repo.Schedules, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := repo.Schedules{}
+	for _, schedule := range s.schedules {
+		if schedule.Reminders && schedule.NextReminderAt != nil && !schedule.NextReminderAt.After(now) {
+			due = append(due, *schedule)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextReminderAt.Before(*due[j].NextReminderAt) })
+	if int64(len(due)) > limit {
+		due = due[:limit]
+	}
+	return &due, nil
+}
+
+func (s *memoryStore) FireSchedule(userID primitive.ObjectID, due time.Time, next *time.Time, nextKind string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.schedules[userID]
+	if !ok || !schedule.Reminders || schedule.NextReminderAt == nil || !schedule.NextReminderAt.Equal(due) {
+		return false, nil
+	}
+	schedule.NextReminderAt = next
+	schedule.NextReminderKind = nextKind
+	return true, nil
+}
+
+type sent struct {
+	userID primitive.ObjectID
+	title  string
+	kind   interface{}
+}
+
+// stubNotifier records notifications and panics for the users in panics.
+type stubNotifier struct {
+	sent   []sent
+	panics map[primitive.ObjectID]bool
+}
+
+func (n *stubNotifier) Notify(userID primitive.ObjectID, typ string, title string, body string, payload map[string]interface{}) (*notificationRepo.Notification, error) {
+	if n.panics[userID] {
+		panic("notify failed")
+	}
+	n.sent = append(n.sent, sent{userID: userID, title: title, kind: payload["kind"]})
+	return &notificationRepo.Notification{UserID: userID, Type: typ, Title: title, Body: body}, nil
+}
+
+func newSchedule(now time.Time) *repo.Schedule {
+	s := &repo.Schedule{
+		UserID:       primitive.NewObjectID(),
+		Protocol:     "16:8",
+		PlannedHours: 16,
+		StartTime:    "20:00",
+		Days:         []int{0, 1, 2, 3, 4, 5, 6},
+		Timezone:     "Asia/Jakarta",
+		Reminders:    true,
+	}
+	next, _ := NextReminder(s, now)
+	s.NextReminderAt, s.NextReminderKind = &next.At, next.Kind
+	return s
+}
+
+func TestFireDue(t *testing.T) {
+	schedule := newSchedule(at(2, 12, 0))
+	store := newMemoryStore(schedule)
+	notifier := &stubNotifier{}
+
+	// nothing is due before the fast starts
+	if err := FireDue(store, notifier, at(2, 19, 59), 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 0 {
+		t.Fatalf("sent %v before the fast started", notifier.sent)
+	}
+
+	if err := FireDue(store, notifier, at(2, 20, 0), 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := FireDue(store, notifier, at(3, 12, 0), 10); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"fasting-start", "fasting-end"}
+	if len(notifier.sent) != len(want) {
+		t.Fatalf("sent %v, want %v", notifier.sent, want)
+	}
+	for i, kind := range want {
+		if notifier.sent[i].kind != kind || notifier.sent[i].userID != schedule.UserID {
+			t.Errorf("notification %d = %+v, want %v", i, notifier.sent[i], kind)
+		}
+	}
+	if notifier.sent[1].title != "Your fasting window has ended" {
+		t.Errorf("end title = %q", notifier.sent[1].title)
+	}
+	if !schedule.NextReminderAt.Equal(at(3, 20, 0)) || schedule.NextReminderKind != ReminderStart {
+		t.Errorf("next = %s at %v, want the next start", schedule.NextReminderKind, schedule.NextReminderAt)
+	}
+}
+
+func TestFireDueMissedReminders(t *testing.T) {
+	schedule := newSchedule(at(2, 12, 0))
+	store := newMemoryStore(schedule)
+	notifier := &stubNotifier{}
+
+	// no instance ran for three days
+	now := at(5, 14, 0)
+	if err := FireDue(store, notifier, now, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 1 {
+		t.Fatalf("sent %d notifications for the missed reminders, want 1", len(notifier.sent))
+	}
+	if !schedule.NextReminderAt.After(now) {
+		t.Errorf("next reminder %v is not after %v", schedule.NextReminderAt, now)
+	}
+}
+
+func TestFireDueOnce(t *testing.T) {
+	schedule := newSchedule(at(2, 12, 0))
+	store := newMemoryStore(schedule)
+	notifier := &stubNotifier{}
+
+	// two instances that both found the reminder due
+	due, _ := store.FindDueSchedules(at(2, 20, 0), 10)
+	if err := FireDue(store, notifier, at(2, 20, 0), 10); err != nil {
+		t.Fatal(err)
+	}
+	fired, err := store.FireSchedule(schedule.UserID, *(*due)[0].NextReminderAt, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired || len(notifier.sent) != 1 {
+		t.Errorf("reminder claimed twice, sent %d", len(notifier.sent))
+	}
+}
+
+func TestFireDueSurvivesPanic(t *testing.T) {
+	broken := newSchedule(at(2, 12, 0))
+	working := newSchedule(at(2, 12, 0))
+	store := newMemoryStore(broken, working)
+	notifier := &stubNotifier{panics: map[primitive.ObjectID]bool{broken.UserID: true}}
+
+	if err := FireDue(store, notifier, at(2, 20, 0), 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].userID != working.UserID {
+		t.Errorf("sent %v, want only the working schedule", notifier.sent)
+	}
+	if !broken.NextReminderAt.Equal(at(3, 12, 0)) {
+		t.Errorf("broken schedule next = %v, want it moved on", broken.NextReminderAt)
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"dietku-backend/cmd/fasting/gear"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxFastingHours = 72
+
+type StartForm struct {
+	Protocol     string     `json:"protocol"`
+	PlannedHours float64    `json:"plannedHours"`
+	StartedAt    *time.Time `json:"startedAt"`
+}
+
+func NewStartForm(c echo.Context) (*StartForm, error) {
+	form := new(StartForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if err := validateProtocol(&form.Protocol, &form.PlannedHours); err != nil {
+		return nil, err
+	}
+
+	if form.StartedAt != nil && form.StartedAt.After(time.Now().Add(time.Minute)) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "StartedAt cannot be in the future.")
+	}
+	return form, nil
+}
+
+type StopForm struct {
+	EndedAt *time.Time `json:"endedAt"`
+}
+
+func NewStopForm(c echo.Context) (*StopForm, error) {
+	form := new(StopForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.EndedAt != nil && form.EndedAt.After(time.Now().Add(time.Minute)) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "EndedAt cannot be in the future.")
+	}
+	return form, nil
+}
+
+type ScheduleForm struct {
+	Protocol     string  `json:"protocol"`
+	PlannedHours float64 `json:"plannedHours"`
+	StartTime    string  `json:"startTime"`
+	Days         []int   `json:"days"`
+	Reminders    bool    `json:"reminders"`
+}
+
+func NewScheduleForm(c echo.Context) (*ScheduleForm, error) {
+	form := new(ScheduleForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if err := validateProtocol(&form.Protocol, &form.PlannedHours); err != nil {
+		return nil, err
+	}
+
+	if _, err := time.Parse(gear.TimeLayout, form.StartTime); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "StartTime must be in HH:MM format.")
+	}
+
+	if len(form.Days) == 0 {
+		form.Days = []int{0, 1, 2, 3, 4, 5, 6}
+	}
+	seen := map[int]bool{}
+	days := []int{}
+	for _, d := range form.Days {
+		if d < 0 || d > 6 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Days must be weekdays from 0 (Sunday) to 6 (Saturday).")
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	form.Days = days
+	return form, nil
+}
+
+// validateProtocol normalizes protocol and fills hours from it, keeping the
+// given hours only for the custom protocol.
+func validateProtocol(protocol *string, hours *float64) error {
+	*protocol = strings.ToLower(strings.TrimSpace(*protocol))
+	if *protocol == "" {
+		*protocol = "16:8"
+	}
+
+	planned, ok := gear.PlannedHours(*protocol, *hours)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown protocol, use one of 12:12, 14:10, 16:8, 18:6, 20:4, omad, 36h or custom with plannedHours.")
+	}
+	if planned > maxFastingHours {
+		return echo.NewHTTPError(http.StatusBadRequest, "Planned hours must not exceed 72.")
+	}
+	*hours = planned
+	return nil
+}
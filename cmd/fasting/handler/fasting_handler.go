@@ -0,0 +1,434 @@
+package handler
+
+import (
+	"context"
+	"dietku-backend/cmd/auth/gear"
+	fastingGear "dietku-backend/cmd/fasting/gear"
+	"dietku-backend/cmd/fasting/repo"
+	"dietku-backend/cmd/log"
+	notificationGear "dietku-backend/cmd/notification/gear"
+	"dietku-backend/cmd/worker"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHistoryDays  = 30
+	maxHistoryDays      = 366
+	defaultReminderDays = 7
+	maxReminderDays     = 31
+	completionTolerance = time.Minute
+
+	workerName     = "fasting-reminders"
+	workerInterval = 30 * time.Second
+	// dueBatch caps how many reminders one tick sends; the rest wait for the
+	// next tick.
+	dueBatch = 500
+)
+
+// Store is the fasting collection as the handler uses it.
+type Store interface {
+	fastingGear.Store
+	FindActive(userID primitive.ObjectID) (*repo.Session, error)
+	FindByRange(userID primitive.ObjectID, from string, to string) (*repo.Sessions, error)
+	FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*repo.Session, error)
+	InsertOne(newSession *repo.Session) (*mongo.InsertOneResult, error)
+	Stop(userID primitive.ObjectID, endedAt time.Time, hours float64, completed bool) (*repo.Session, error)
+	DeleteOne(id primitive.ObjectID) (*repo.Session, error)
+	FindSchedule(userID primitive.ObjectID) (*repo.Schedule, error)
+	UpsertSchedule(schedule *repo.Schedule) error
+	DeleteSchedule(userID primitive.ObjectID) (*repo.Schedule, error)
+}
+
+type FastingHandler struct {
+	repo     Store
+	notifier fastingGear.Notifier
+	clock    worker.Clock
+}
+
+func NewFastingApi(e *echo.Echo, db *mongo.Database) *FastingHandler {
+	fastingRepo := repo.NewFastingRepository(db)
+	if err := fastingRepo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create fasting indexes: %v", err)
+	}
+
+	f := &FastingHandler{
+		repo:     fastingRepo,
+		notifier: notificationGear.NewNotifier(db),
+		clock:    worker.RealClock{},
+	}
+
+	w := &worker.Worker{
+		Name:     workerName,
+		Interval: workerInterval,
+		Clock:    f.clock,
+		Lock:     worker.NewLock(db),
+		Job:      f.FireDue,
+	}
+	w.Start(context.Background())
+
+	fGroup := e.Group("")
+	fGroup.Use(gear.IsLoggedIn(db))
+	{
+		fGroup.GET("/api/fasting/active", f.Active)
+		fGroup.GET("/api/fasting/history", f.History)
+		fGroup.GET("/api/fasting/schedule", f.GetSchedule)
+		fGroup.GET("/api/fasting/schedule/reminders", f.Reminders)
+
+		fGroup.POST("/api/fasting/start", f.Start)
+		fGroup.POST("/api/fasting/stop", f.Stop)
+
+		fGroup.PUT("/api/fasting/schedule", f.UpdateSchedule)
+
+		fGroup.DELETE("/api/fasting/schedule", f.DeleteSchedule)
+		fGroup.DELETE("/api/fasting/:id", f.Delete)
+	}
+	return f
+}
+
+// FireDue is the job of the fasting reminder worker.
+func (h *FastingHandler) FireDue(now time.Time) error {
+	return fastingGear.FireDue(h.repo, h.notifier, now, dueBatch)
+}
+
+type ActiveFast struct {
+	*repo.Session
+	ElapsedHours   float64   `json:"elapsedHours"`
+	RemainingHours float64   `json:"remainingHours"`
+	Percent        float64   `json:"percent"`
+	EndsAt         time.Time `json:"endsAt"`
+}
+
+type FastingHistory struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Timezone string            `json:"timezone"`
+	Stats    fastingGear.Stats `json:"stats"`
+	Sessions *repo.Sessions    `json:"sessions"`
+}
+
+func newActiveFast(s *repo.Session, now time.Time) *ActiveFast {
+	a := &ActiveFast{
+		Session:      s,
+		ElapsedHours: now.Sub(s.StartedAt).Hours(),
+		EndsAt:       s.StartedAt.Add(time.Duration(s.PlannedHours * float64(time.Hour))),
+	}
+	a.RemainingHours = s.PlannedHours - a.ElapsedHours
+	if a.RemainingHours < 0 {
+		a.RemainingHours = 0
+	}
+	if s.PlannedHours > 0 {
+		a.Percent = a.ElapsedHours / s.PlannedHours * 100
+	}
+	return a
+}
+
+// Active
+// @Tags Fasting
+// @Summary Get Active Fast
+// @Description The running fast with elapsed and remaining hours, or 404 when not fasting.
+// @ID fasting-active
+// @Router /api/fasting/active [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) Active(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	s, err := h.repo.FindActive(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "No active fast.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting fast.", c)
+	}
+	return c.JSON(http.StatusOK, newActiveFast(s, h.clock.Now()))
+}
+
+// Start
+// @Tags Fasting
+// @Summary Start Fast
+// @Description Starts a fast with a named protocol (12:12, 14:10, 16:8, 18:6, 20:4, omad, 36h) or custom plannedHours. Only one fast can be active at a time.
+// @ID fasting-start
+// @Router /api/fasting/start [post]
+// @Accept json
+// @Param body body StartForm true "start body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) Start(c echo.Context) error {
+	form, err := NewStartForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	at := h.clock.Now()
+	if form.StartedAt != nil {
+		at = *form.StartedAt
+	}
+
+	s := &repo.Session{
+		ID:           primitive.NewObjectID(),
+		UserID:       tokenData.ID,
+		Protocol:     form.Protocol,
+		Date:         at.In(tokenData.Location()).Format(gear.DateLayout),
+		StartedAt:    at,
+		PlannedHours: form.PlannedHours,
+		Active:       true,
+		CreatedAt:    h.clock.Now(),
+	}
+
+	_, err = h.repo.InsertOne(s)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return echo.NewHTTPError(http.StatusConflict, "A fast is already active, stop it first.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while starting fast.", c)
+	}
+	return c.JSON(http.StatusOK, newActiveFast(s, h.clock.Now()))
+}
+
+// Stop
+// @Tags Fasting
+// @Summary Stop Fast
+// @Description Ends the active fast. It counts as completed when the planned duration was reached.
+// @ID fasting-stop
+// @Router /api/fasting/stop [post]
+// @Accept json
+// @Param body body StopForm false "stop body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) Stop(c echo.Context) error {
+	form, err := NewStopForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	s, err := h.repo.FindActive(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "No active fast.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting fast.", c)
+	}
+
+	at := h.clock.Now()
+	if form.EndedAt != nil {
+		at = *form.EndedAt
+	}
+	if at.Before(s.StartedAt) {
+		return echo.NewHTTPError(http.StatusBadRequest, "EndedAt cannot be before the fast started.", c)
+	}
+
+	elapsed := at.Sub(s.StartedAt)
+	planned := time.Duration(s.PlannedHours * float64(time.Hour))
+	completed := elapsed+completionTolerance >= planned
+
+	docs, err := h.repo.Stop(tokenData.ID, at, elapsed.Hours(), completed)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "No active fast.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while stopping fast.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// History
+// @Tags Fasting
+// @Summary Get Fasting History
+// @Description Fasts started between from and to (default the last 30 days) with average length, completion rate and streaks of days with a completed fast.
+// @ID fasting-history
+// @Router /api/fasting/history [get]
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) History(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	r, err := gear.ParseRange(c, loc, defaultHistoryDays, maxHistoryDays)
+	if err != nil {
+		return err
+	}
+	from, to := r.From, r.To
+
+	sessions, err := h.repo.FindByRange(tokenData.ID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting fasting history.", c)
+	}
+
+	return c.JSON(http.StatusOK, &FastingHistory{
+		From:     from,
+		To:       to,
+		Timezone: loc.String(),
+		Stats:    fastingGear.Summarize(*sessions, to),
+		Sessions: sessions,
+	})
+}
+
+// Delete
+// @Tags Fasting
+// @Summary Delete Fast
+// @ID fasting-delete
+// @Router /api/fasting/{id} [delete]
+// @Produce json
+// @Param id path string true "Fasting session ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) Delete(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid fasting session id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	s, err := h.repo.FindOne(oId, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Fasting session not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting fasting session.", c)
+	}
+
+	docs, err := h.repo.DeleteOne(s.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting fasting session.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// GetSchedule
+// @Tags Fasting
+// @Summary Get Fasting Schedule
+// @ID fasting-schedule-get
+// @Router /api/fasting/schedule [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) GetSchedule(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	schedule, err := h.repo.FindSchedule(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "No fasting schedule.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting fasting schedule.", c)
+	}
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateSchedule
+// @Tags Fasting
+// @Summary Set Fasting Schedule
+// @Description Fasts start at startTime (HH:MM, user's time zone) on the given weekdays (0 is Sunday, default every day). With reminders on, a notification is sent when each fast starts and when its window ends.
+// @ID fasting-schedule-update
+// @Router /api/fasting/schedule [put]
+// @Accept json
+// @Param body body ScheduleForm true "schedule body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) UpdateSchedule(c echo.Context) error {
+	form, err := NewScheduleForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	now := h.clock.Now()
+
+	schedule := &repo.Schedule{
+		UserID:       tokenData.ID,
+		Protocol:     form.Protocol,
+		PlannedHours: form.PlannedHours,
+		StartTime:    form.StartTime,
+		Days:         form.Days,
+		Timezone:     tokenData.Location().String(),
+		Reminders:    form.Reminders,
+		UpdatedAt:    now,
+	}
+
+	next, err := fastingGear.NextReminder(schedule, now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "StartTime must be in HH:MM format.", c)
+	}
+	if next != nil {
+		schedule.NextReminderAt, schedule.NextReminderKind = &next.At, next.Kind
+	}
+
+	if err := h.repo.UpsertSchedule(schedule); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while saving fasting schedule.", c)
+	}
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteSchedule
+// @Tags Fasting
+// @Summary Delete Fasting Schedule
+// @ID fasting-schedule-delete
+// @Router /api/fasting/schedule [delete]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) DeleteSchedule(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	docs, err := h.repo.DeleteSchedule(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Fasting schedule not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting fasting schedule.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Reminders
+// @Tags Fasting
+// @Summary Get Upcoming Fasting Reminders
+// @Description Start and end reminders of the schedule for the next days, as the reminder worker will send them to the notification centre. Empty when reminders are turned off.
+// @ID fasting-schedule-reminders
+// @Router /api/fasting/schedule/reminders [get]
+// @Produce json
+// @Param days query int false "Days ahead (default 7, max 31)"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FastingHandler) Reminders(c echo.Context) error {
+	days, err := gear.QueryInt(c, "days", defaultReminderDays, maxReminderDays)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	schedule, err := h.repo.FindSchedule(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "No fasting schedule.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting fasting schedule.", c)
+	}
+
+	reminders := []fastingGear.Reminder{}
+	if schedule.Reminders {
+		reminders, err = fastingGear.Reminders(schedule, gear.LoadLocation(schedule.Timezone), h.clock.Now(), days)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while generating reminders.", c)
+		}
+	}
+	return c.JSON(http.StatusOK, reminders)
+}
@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/fasting/repo"
+	"dietku-backend/cmd/log"
+	notificationRepo "dietku-backend/cmd/notification/repo"
+	"dietku-backend/cmd/worker"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetLogger(echo.New())
+	os.Exit(m.Run())
+}
+
+// memoryRepo is an in-memory Store that keeps the collection's rules the
+// handler relies on: one active fast per user and one schedule per user.
+type memoryRepo struct {
+	sessions  map[primitive.ObjectID]*repo.Session
+	schedules map[primitive.ObjectID]*repo.Schedule
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{
+		sessions:  map[primitive.ObjectID]*repo.Session{},
+		schedules: map[primitive.ObjectID]*repo.Schedule{},
+	}
+}
+
+func (r *memoryRepo) FindActive(userID primitive.ObjectID) (*repo.Session, error) {
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.Active {
+			copied := *s
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memoryRepo) FindByRange(userID primitive.ObjectID, from string, to string) (*repo.Sessions, error) {
+	docs := repo.Sessions{}
+	for _, s := range r.sessions {
+		if s.UserID == userID && !s.IsDeleted && s.Date >= from && s.Date <= to {
+			docs = append(docs, *s)
+		}
+	}
+	return &docs, nil
+}
+
+func (r *memoryRepo) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*repo.Session, error) {
+	s, ok := r.sessions[id]
+	if !ok || s.UserID != userID || s.IsDeleted {
+		return nil, mongo.ErrNoDocuments
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (r *memoryRepo) InsertOne(newSession *repo.Session) (*mongo.InsertOneResult, error) {
+	if _, err := r.FindActive(newSession.UserID); err == nil {
+		return nil, mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+	}
+	copied := *newSession
+	r.sessions[newSession.ID] = &copied
+	return &mongo.InsertOneResult{InsertedID: newSession.ID}, nil
+}
+
+func (r *memoryRepo) Stop(userID primitive.ObjectID, endedAt time.Time, hours float64, completed bool) (*repo.Session, error) {
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.Active {
+			s.EndedAt, s.Hours, s.Completed, s.Active = &endedAt, hours, completed, false
+			copied := *s
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memoryRepo) DeleteOne(id primitive.ObjectID) (*repo.Session, error) {
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	s.IsDeleted, s.Active = true, false
+	copied := *s
+	return &copied, nil
+}
+
+func (r *memoryRepo) FindSchedule(userID primitive.ObjectID) (*repo.Schedule, error) {
+	s, ok := r.schedules[userID]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (r *memoryRepo) UpsertSchedule(schedule *repo.Schedule) error {
+	copied := *schedule
+	r.schedules[schedule.UserID] = &copied
+	return nil
+}
+
+func (r *memoryRepo) DeleteSchedule(userID primitive.ObjectID) (*repo.Schedule, error) {
+	s, ok := r.schedules[userID]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	delete(r.schedules, userID)
+	return s, nil
+}
+
+func (r *memoryRepo) FindDueSchedules(now time.Time, limit int64) (*repo.Schedules, error) {
+	docs := repo.Schedules{}
+	for _, s := range r.schedules {
+		if s.Reminders && s.NextReminderAt != nil && !s.NextReminderAt.After(now) && int64(len(docs)) < limit {
+			docs = append(docs, *s)
+		}
+	}
+	return &docs, nil
+}
+
+func (r *memoryRepo) FireSchedule(userID primitive.ObjectID, due time.Time, next *time.Time, nextKind string) (bool, error) {
+	s, ok := r.schedules[userID]
+	if !ok || !s.Reminders || s.NextReminderAt == nil || !s.NextReminderAt.Equal(due) {
+		return false, nil
+	}
+	s.NextReminderAt, s.NextReminderKind = next, nextKind
+	return true, nil
+}
+
+type stubNotifier struct {
+	titles []string
+}
+
+func (n *stubNotifier) Notify(userID primitive.ObjectID, typ string, title string, body string, payload map[string]interface{}) (*notificationRepo.Notification, error) {
+	n.titles = append(n.titles, title)
+	return &notificationRepo.Notification{UserID: userID, Type: typ, Title: title, Body: body}, nil
+}
+
+// now is 12:00 on Monday 2 March 2026 in Jakarta.
+var now = time.Date(2026, 3, 2, 5, 0, 0, 0, time.UTC)
+
+type fixture struct {
+	handler  *FastingHandler
+	repo     *memoryRepo
+	notifier *stubNotifier
+	clock    *worker.FakeClock
+	user     *gear.UserClaims
+}
+
+func newFixture() *fixture {
+	f := &fixture{
+		repo:     newMemoryRepo(),
+		notifier: &stubNotifier{},
+		clock:    worker.NewFakeClock(now),
+		user:     &gear.UserClaims{ID: primitive.NewObjectID(), Timezone: "Asia/Jakarta"},
+	}
+	f.handler = &FastingHandler{repo: f.repo, notifier: f.notifier, clock: f.clock}
+	return f
+}
+
+// call runs handle with body as JSON and returns the response, or the status
+// of the returned error.
+func (f *fixture) call(handle echo.HandlerFunc, method string, target string, body string) (int, []byte) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Set("me", f.user)
+
+	if err := handle(c); err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			return httpErr.Code, nil
+		}
+		return http.StatusInternalServerError, nil
+	}
+	return rec.Code, rec.Body.Bytes()
+}
+
+func TestStartAndStop(t *testing.T) {
+	f := newFixture()
+	h := f.handler
+
+	code, body := f.call(h.Start, http.MethodPost, "/api/fasting/start", `{"protocol": "16:8"}`)
+	if code != http.StatusOK {
+		t.Fatalf("start = %d", code)
+	}
+	var active ActiveFast
+	if err := json.Unmarshal(body, &active); err != nil {
+		t.Fatal(err)
+	}
+	if active.PlannedHours != 16 || active.Date != "2026-03-02" || !active.EndsAt.Equal(now.Add(16*time.Hour)) {
+		t.Errorf("started %+v", active)
+	}
+
+	if code, _ := f.call(h.Start, http.MethodPost, "/api/fasting/start", `{}`); code != http.StatusConflict {
+		t.Errorf("second start = %d, want %d", code, http.StatusConflict)
+	}
+
+	f.clock.Advance(8 * time.Hour)
+	if code, _ := f.call(h.Active, http.MethodGet, "/api/fasting/active", ""); code != http.StatusOK {
+		t.Errorf("active = %d", code)
+	}
+
+	// one minute short of the plan still counts as completed
+	f.clock.Advance(8*time.Hour - time.Minute)
+	code, body = f.call(h.Stop, http.MethodPost, "/api/fasting/stop", `{}`)
+	if code != http.StatusOK {
+		t.Fatalf("stop = %d", code)
+	}
+	var stopped repo.Session
+	if err := json.Unmarshal(body, &stopped); err != nil {
+		t.Fatal(err)
+	}
+	if !stopped.Completed || stopped.Active {
+		t.Errorf("stopped %+v, want completed", stopped)
+	}
+
+	if code, _ := f.call(h.Active, http.MethodGet, "/api/fasting/active", ""); code != http.StatusNotFound {
+		t.Errorf("active after stop = %d, want %d", code, http.StatusNotFound)
+	}
+	if code, _ := f.call(h.Stop, http.MethodPost, "/api/fasting/stop", `{}`); code != http.StatusBadRequest {
+		t.Errorf("stop without a fast = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestStopEarly(t *testing.T) {
+	f := newFixture()
+	f.call(f.handler.Start, http.MethodPost, "/api/fasting/start", `{"protocol": "18:6"}`)
+
+	f.clock.Advance(12 * time.Hour)
+	code, body := f.call(f.handler.Stop, http.MethodPost, "/api/fasting/stop", `{}`)
+	if code != http.StatusOK {
+		t.Fatalf("stop = %d", code)
+	}
+	var stopped repo.Session
+	if err := json.Unmarshal(body, &stopped); err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Completed || stopped.Hours != 12 {
+		t.Errorf("stopped %+v, want 12 hours and not completed", stopped)
+	}
+
+	endedAt := now.Add(-time.Hour).Format(time.RFC3339)
+	f.call(f.handler.Start, http.MethodPost, "/api/fasting/start", `{}`)
+	if code, _ := f.call(f.handler.Stop, http.MethodPost, "/api/fasting/stop", `{"endedAt": "`+endedAt+`"}`); code != http.StatusBadRequest {
+		t.Errorf("stop before the start = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestReminderDays(t *testing.T) {
+	f := newFixture()
+	schedule := `{"protocol": "16:8", "startTime": "20:00", "reminders": true}`
+	if code, _ := f.call(f.handler.UpdateSchedule, http.MethodPut, "/api/fasting/schedule", schedule); code != http.StatusOK {
+		t.Fatalf("update schedule = %d", code)
+	}
+
+	tests := []struct {
+		query string
+		code  int
+		count int
+	}{
+		{query: "", code: http.StatusOK, count: 14},
+		{query: "?days=1", code: http.StatusOK, count: 2},
+		{query: "?days=31", code: http.StatusOK, count: 62},
+		{query: "?days=0", code: http.StatusBadRequest},
+		{query: "?days=32", code: http.StatusBadRequest},
+		{query: "?days=week", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		code, body := f.call(f.handler.Reminders, http.MethodGet, "/api/fasting/schedule/reminders"+tt.query, "")
+		if code != tt.code {
+			t.Errorf("%q = %d, want %d", tt.query, code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var reminders []map[string]interface{}
+		if err := json.Unmarshal(body, &reminders); err != nil {
+			t.Fatal(err)
+		}
+		if len(reminders) != tt.count {
+			t.Errorf("%q gave %d reminders, want %d", tt.query, len(reminders), tt.count)
+		}
+	}
+}
+
+func TestScheduleReminders(t *testing.T) {
+	f := newFixture()
+	schedule := `{"protocol": "16:8", "startTime": "20:00", "days": [1], "reminders": true}`
+	if code, _ := f.call(f.handler.UpdateSchedule, http.MethodPut, "/api/fasting/schedule", schedule); code != http.StatusOK {
+		t.Fatalf("update schedule = %d", code)
+	}
+
+	saved := f.repo.schedules[f.user.ID]
+	start := time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC)
+	if saved.Timezone != "Asia/Jakarta" || saved.NextReminderAt == nil || !saved.NextReminderAt.Equal(start) {
+		t.Fatalf("saved %+v, want the next reminder at %v", saved, start)
+	}
+
+	for _, tick := range []time.Duration{7 * time.Hour, time.Hour, 16 * time.Hour} {
+		f.clock.Advance(tick)
+		if err := f.handler.FireDue(f.clock.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"Time to start your fast", "Your fasting window has ended"}
+	if strings.Join(f.notifier.titles, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", f.notifier.titles, want)
+	}
+
+	// turning reminders off stops the worker from sending any more
+	off := `{"protocol": "16:8", "startTime": "20:00", "days": [1], "reminders": false}`
+	if code, _ := f.call(f.handler.UpdateSchedule, http.MethodPut, "/api/fasting/schedule", off); code != http.StatusOK {
+		t.Fatalf("update schedule = %d", code)
+	}
+	if saved := f.repo.schedules[f.user.ID]; saved.NextReminderAt != nil {
+		t.Errorf("next reminder = %v with reminders off", saved.NextReminderAt)
+	}
+	f.clock.Advance(7 * 24 * time.Hour)
+	if err := f.handler.FireDue(f.clock.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.notifier.titles) != len(want) {
+		t.Errorf("sent %q after reminders were turned off", f.notifier.titles)
+	}
+}
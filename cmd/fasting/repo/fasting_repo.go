@@ -0,0 +1,237 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Session is one fast. Active is true until the fast is stopped; a partial
+// unique index on it keeps a single active fast per user.
+type Session struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID       primitive.ObjectID `json:"userId" bson:"userId"`
+	Protocol     string             `json:"protocol" bson:"protocol"`
+	Date         string             `json:"date" bson:"date"`
+	StartedAt    time.Time          `json:"startedAt" bson:"startedAt"`
+	PlannedHours float64            `json:"plannedHours" bson:"plannedHours"`
+	EndedAt      *time.Time         `json:"endedAt,omitempty" bson:"endedAt,omitempty"`
+	Hours        float64            `json:"hours" bson:"hours"`
+	Completed    bool               `json:"completed" bson:"completed"`
+	Active       bool               `json:"active" bson:"active"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+	IsDeleted    bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Sessions []Session
+
+func DecodeAsSessions(cursor *mongo.Cursor) (*Sessions, error) {
+	docs := Sessions{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+// Schedule is the fasting plan of a user. A fast starts at StartTime (HH:MM in
+// Timezone) on each weekday in Days (0 is Sunday). NextReminderAt is when the
+// worker sends the next start or end reminder and is empty while reminders are
+// off.
+type Schedule struct {
+	UserID           primitive.ObjectID `json:"userId" bson:"userId"`
+	Protocol         string             `json:"protocol" bson:"protocol"`
+	PlannedHours     float64            `json:"plannedHours" bson:"plannedHours"`
+	StartTime        string             `json:"startTime" bson:"startTime"`
+	Days             []int              `json:"days" bson:"days"`
+	Timezone         string             `json:"timezone" bson:"timezone"`
+	Reminders        bool               `json:"reminders" bson:"reminders"`
+	NextReminderAt   *time.Time         `json:"nextReminderAt,omitempty" bson:"nextReminderAt"`
+	NextReminderKind string             `json:"nextReminderKind,omitempty" bson:"nextReminderKind,omitempty"`
+	UpdatedAt        time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+type Schedules []Schedule
+
+func DecodeAsSchedules(cursor *mongo.Cursor) (*Schedules, error) {
+	docs := Schedules{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type FastingRepository struct {
+	coll         *mongo.Collection
+	scheduleColl *mongo.Collection
+}
+
+func NewFastingRepository(db *mongo.Database) *FastingRepository {
+	return &FastingRepository{
+		coll:         db.Collection("fasting_sessions"),
+		scheduleColl: db.Collection("fasting_schedules"),
+	}
+}
+
+func (r *FastingRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.scheduleColl.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "reminders", Value: 1}, {Key: "nextReminderAt", Value: 1}}},
+	})
+	return err
+}
+
+func (r *FastingRepository) FindActive(userID primitive.ObjectID) (*Session, error) {
+	var d = &Session{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"userId": userID, "active": true}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// FindByRange lists the sessions started between from and to (inclusive),
+// newest first.
+func (r *FastingRepository) FindByRange(userID primitive.ObjectID, from string, to string) (*Sessions, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"date":      bson.M{"$gte": from, "$lte": to},
+		"isDeleted": bson.M{"$ne": true},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsSessions(cursor)
+}
+
+func (r *FastingRepository) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*Session, error) {
+	var d = &Session{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// InsertOne fails with a duplicate key error when the user already has an
+// active fast.
+func (r *FastingRepository) InsertOne(newSession *Session) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newSession)
+}
+
+// Stop ends the active fast of userID.
+func (r *FastingRepository) Stop(userID primitive.ObjectID, endedAt time.Time, hours float64, completed bool) (*Session, error) {
+	filter := bson.M{"userId": userID, "active": true}
+
+	update := bson.M{
+		"$set": bson.M{
+			"endedAt":   endedAt,
+			"hours":     hours,
+			"completed": completed,
+			"active":    false,
+		},
+	}
+
+	var d = &Session{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *FastingRepository) DeleteOne(id primitive.ObjectID) (*Session, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true, "active": false},
+	}
+
+	var d = &Session{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *FastingRepository) FindSchedule(userID primitive.ObjectID) (*Schedule, error) {
+	var d = &Schedule{}
+	err := r.scheduleColl.FindOne(context.TODO(), bson.M{"userId": userID}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *FastingRepository) UpsertSchedule(schedule *Schedule) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.scheduleColl.ReplaceOne(context.TODO(), bson.M{"userId": schedule.UserID}, schedule, opts)
+	return err
+}
+
+func (r *FastingRepository) DeleteSchedule(userID primitive.ObjectID) (*Schedule, error) {
+	var d = &Schedule{}
+	err := r.scheduleColl.FindOneAndDelete(context.TODO(), bson.M{"userId": userID}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// dueFilter matches the schedules whose next reminder should have been sent by
+// now.
+func dueFilter(now time.Time) bson.M {
+	return bson.M{"reminders": true, "nextReminderAt": bson.M{"$lte": now}}
+}
+
+// FindDueSchedules returns up to limit schedules with a reminder due at now,
+// oldest first.
+func (r *FastingRepository) FindDueSchedules(now time.Time, limit int64) (*Schedules, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "nextReminderAt", Value: 1}}).SetLimit(limit)
+	cursor, err := r.scheduleColl.Find(context.TODO(), dueFilter(now), opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsSchedules(cursor)
+}
+
+// FireSchedule moves the due reminder of userID's schedule on to next. It only
+// matches while nextReminderAt is still due, so a reminder claimed by another
+// run or replaced by a schedule change is not sent; in that case it reports
+// false.
+func (r *FastingRepository) FireSchedule(userID primitive.ObjectID, due time.Time, next *time.Time, nextKind string) (bool, error) {
+	filter := bson.M{"userId": userID, "reminders": true, "nextReminderAt": due}
+
+	update := bson.M{
+		"$set": bson.M{"nextReminderAt": next, "nextReminderKind": nextKind},
+	}
+
+	result, err := r.scheduleColl.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
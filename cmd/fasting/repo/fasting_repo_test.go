@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestScheduleDocument(t *testing.T) {
+	next := time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC)
+	schedule := &Schedule{
+		UserID:           primitive.NewObjectID(),
+		Protocol:         "16:8",
+		PlannedHours:     16,
+		StartTime:        "20:00",
+		Days:             []int{1},
+		Timezone:         "Asia/Jakarta",
+		Reminders:        true,
+		NextReminderAt:   &next,
+		NextReminderKind: "start",
+	}
+
+	raw, err := bson.Marshal(schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Schedule
+	if err := bson.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Timezone != schedule.Timezone || decoded.NextReminderKind != "start" || decoded.NextReminderAt == nil || !decoded.NextReminderAt.Equal(next) {
+		t.Errorf("decoded %+v, want %+v", decoded, schedule)
+	}
+
+	// replacing a schedule with reminders off must clear the next reminder,
+	// so the field is written even when empty
+	schedule.Reminders, schedule.NextReminderAt, schedule.NextReminderKind = false, nil, ""
+	raw, err = bson.Marshal(schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := bson.Raw(raw).LookupErr("nextReminderAt")
+	if err != nil {
+		t.Fatalf("nextReminderAt is not written: %v", err)
+	}
+	if value.Type != bson.TypeNull {
+		t.Errorf("nextReminderAt = %v, want null", value)
+	}
+}
+
+func TestDueFilter(t *testing.T) {
+	now := time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC)
+	filter := dueFilter(now)
+
+	if filter["reminders"] != true {
+		t.Errorf("filter %v does not require reminders", filter)
+	}
+	due, ok := filter["nextReminderAt"].(bson.M)
+	if !ok || due["$lte"] != now {
+		t.Errorf("filter %v does not match reminders due by %v", filter, now)
+	}
+}
+
+func TestSessionDocument(t *testing.T) {
+	session := &Session{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Date: "2026-03-02", Active: true}
+
+	raw, err := bson.Marshal(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the unique index on active fasts and the range queries read these fields
+	for _, field := range []string{"userId", "date", "active", "isDeleted", "startedAt"} {
+		if _, err := bson.Raw(raw).LookupErr(field); err != nil {
+			t.Errorf("session document has no %s: %v", field, err)
+		}
+	}
+	if _, err := bson.Raw(raw).LookupErr("endedAt"); err == nil {
+		t.Error("active session has an endedAt")
+	}
+}
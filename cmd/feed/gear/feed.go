@@ -0,0 +1,310 @@
+package gear
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"golang.org/x/net/html"
+	"strings"
+	"time"
+)
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+// ContentTypes maps each format to the media type it is served as.
+var ContentTypes = map[string]string{
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// Feed is a list of posts independent of the syndication format. Link is the
+// page the feed belongs to and Self the URL of the feed itself; both, like
+// every URL of the items, are absolute.
+type Feed struct {
+	Title       string
+	Description string
+	Language    string
+	Link        string
+	Self        string
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item is one post. ID is a permanent, unique URL; ContentHTML is sanitized
+// HTML whose links are absolute.
+type Item struct {
+	ID          string
+	Link        string
+	Title       string
+	Summary     string
+	ContentHTML string
+	Author      string
+	Categories  []string
+	Language    string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Encode writes f in format.
+func Encode(f *Feed, format string) ([]byte, error) {
+	switch format {
+	case FormatRSS:
+		return encodeXML(newRSS(f))
+	case FormatAtom:
+		return encodeXML(newAtom(f))
+	default:
+		return json.Marshal(newJSONFeed(f))
+	}
+}
+
+func encodeXML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RSS 2.0, with the Atom self link the spec recommends, the full post in
+// content:encoded and the author's name in dc:creator, as the author element
+// is meant to hold an email address.
+type rss struct {
+	XMLName      xml.Name   `xml:"rss"`
+	Version      string     `xml:"version,attr"`
+	AtomNS       string     `xml:"xmlns:atom,attr"`
+	ContentNS    string     `xml:"xmlns:content,attr"`
+	DublinCoreNS string     `xml:"xmlns:dc,attr"`
+	Channel      rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"content:encoded,omitempty"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssDate(t time.Time) string {
+	return t.UTC().Format(time.RFC1123Z)
+}
+
+func newRSS(f *Feed) *rss {
+	channel := rssChannel{
+		Title:       f.Title,
+		Link:        f.Link,
+		Description: f.Description,
+		Language:    f.Language,
+		AtomLink:    atomLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+		Items:       make([]rssItem, len(f.Items)),
+	}
+	if len(f.Items) > 0 {
+		channel.LastBuildDate = rssDate(f.Updated)
+	}
+	for i, item := range f.Items {
+		channel.Items[i] = rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Summary,
+			Content:     item.ContentHTML,
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			GUID:        rssGUID{IsPermaLink: item.ID == item.Link, Value: item.ID},
+			PubDate:     rssDate(item.Published),
+		}
+	}
+	return &rss{
+		Version:      "2.0",
+		AtomNS:       "http://www.w3.org/2005/Atom",
+		ContentNS:    "http://purl.org/rss/1.0/modules/content/",
+		DublinCoreNS: "http://purl.org/dc/elements/1.1/",
+		Channel:      channel,
+	}
+}
+
+// Atom (RFC 4287). Every entry has an author, so the feed needs none.
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Lang       string         `xml:"xml:lang,attr,omitempty"`
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Author     atomPerson     `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func atomDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func newAtom(f *Feed) *atomFeed {
+	feed := &atomFeed{
+		Lang:     f.Language,
+		ID:       f.Self,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  atomDate(f.Updated),
+		Links: []atomLink{
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate"},
+		},
+		Entries: make([]atomEntry, len(f.Items)),
+	}
+	for i, item := range f.Items {
+		categories := make([]atomCategory, len(item.Categories))
+		for j, category := range item.Categories {
+			categories[j] = atomCategory{Term: category}
+		}
+		feed.Entries[i] = atomEntry{
+			Lang:       item.Language,
+			ID:         item.ID,
+			Title:      item.Title,
+			Updated:    atomDate(item.Updated),
+			Published:  atomDate(item.Published),
+			Author:     atomPerson{Name: item.Author},
+			Links:      []atomLink{{Href: item.Link, Rel: "alternate"}},
+			Categories: categories,
+			Summary:    item.Summary,
+			Content:    atomContent{Type: "html", Value: item.ContentHTML},
+		}
+	}
+	return feed
+}
+
+// JSON Feed 1.1.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Language    string         `json:"language,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	Tags          []string         `json:"tags,omitempty"`
+	Language      string           `json:"language,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func newJSONFeed(f *Feed) *jsonFeed {
+	feed := &jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.Self,
+		Description: f.Description,
+		Language:    f.Language,
+		Items:       make([]jsonFeedItem, len(f.Items)),
+	}
+	for i, item := range f.Items {
+		feed.Items[i] = jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			Summary:       item.Summary,
+			DatePublished: atomDate(item.Published),
+			DateModified:  atomDate(item.Updated),
+			Authors:       []jsonFeedAuthor{{Name: item.Author}},
+			Tags:          item.Categories,
+			Language:      item.Language,
+		}
+	}
+	return feed
+}
+
+// Absolutize prefixes the root relative links and image sources of an HTML
+// fragment with base, as feed readers show posts away from the site. Other
+// URLs and the rest of the markup are left as they are.
+func Absolutize(fragment string, base string) string {
+	var out strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return out.String()
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(z.Raw())
+			continue
+		}
+
+		t := z.Token()
+		for i, attr := range t.Attr {
+			if (attr.Key == "href" || attr.Key == "src") && strings.HasPrefix(attr.Val, "/") && !strings.HasPrefix(attr.Val, "//") {
+				t.Attr[i].Val = base + attr.Val
+			}
+		}
+		out.WriteString(t.String())
+	}
+}
@@ -0,0 +1,282 @@
+package gear
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	published = time.Date(2026, 3, 1, 8, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+	updated   = time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+)
+
+func testFeed() *Feed {
+	return &Feed{
+		Title:       "Dietku Blog",
+		Description: "Articles & more",
+		Language:    "id",
+		Link:        "https://dietku.example.com/blog",
+		Self:        "https://api.dietku.example.com/feeds/blog.rss",
+		Updated:     updated,
+		Items: []Item{
+			{
+				ID:          "https://dietku.example.com/blog/1",
+				Link:        "https://dietku.example.com/blog/1",
+				Title:       "Less <sugar> & more fiber",
+				Summary:     "Why fiber matters",
+				ContentHTML: `<p>Eat <a href="https://x.example/?a=1&amp;b=2">greens</a> ]]> daily</p>`,
+				Author:      "Ani",
+				Categories:  []string{"diet", "nutrition"},
+				Language:    "id",
+				Published:   published,
+				Updated:     updated,
+			},
+		},
+	}
+}
+
+func TestEncodeRSS(t *testing.T) {
+	body, err := Encode(testFeed(), FormatRSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Errorf("missing XML declaration")
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"rss"`
+		Version string   `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			Description   string `xml:"description"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			// the RSS link and the Atom self link share a local name
+			Links []struct {
+				XMLName xml.Name
+				Href    string `xml:"href,attr"`
+				Rel     string `xml:"rel,attr"`
+				Value   string `xml:",chardata"`
+			} `xml:"link"`
+			Items []struct {
+				Title       string   `xml:"title"`
+				Link        string   `xml:"link"`
+				Description string   `xml:"description"`
+				Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+				Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories  []string `xml:"category"`
+				GUID        struct {
+					IsPermaLink string `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("feed does not parse: %v\n%s", err, body)
+	}
+
+	f, item := testFeed(), testFeed().Items[0]
+	if doc.Version != "2.0" || doc.Channel.Title != f.Title || doc.Channel.Description != f.Description {
+		t.Errorf("channel = %+v", doc.Channel)
+	}
+	var link, self string
+	for _, l := range doc.Channel.Links {
+		switch l.XMLName.Space {
+		case "":
+			link = l.Value
+		case "http://www.w3.org/2005/Atom":
+			if l.Rel == "self" {
+				self = l.Href
+			}
+		}
+	}
+	if link != f.Link || self != f.Self {
+		t.Errorf("link = %q and self link = %q, want %q and %q", link, self, f.Link, f.Self)
+	}
+	if len(doc.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(doc.Channel.Items))
+	}
+	got := doc.Channel.Items[0]
+	if got.Title != item.Title || got.Link != item.Link || got.Description != item.Summary || got.Creator != item.Author {
+		t.Errorf("item = %+v", got)
+	}
+	if got.Content != item.ContentHTML {
+		t.Errorf("content = %q, want %q", got.Content, item.ContentHTML)
+	}
+	if !reflect.DeepEqual(got.Categories, item.Categories) {
+		t.Errorf("categories = %v, want %v", got.Categories, item.Categories)
+	}
+	if got.GUID.Value != item.ID || got.GUID.IsPermaLink != "true" {
+		t.Errorf("guid = %+v", got.GUID)
+	}
+	if pub, err := time.Parse(time.RFC1123Z, got.PubDate); err != nil || !pub.Equal(item.Published) {
+		t.Errorf("pubDate = %q, want %v", got.PubDate, item.Published)
+	}
+	if built, err := time.Parse(time.RFC1123Z, doc.Channel.LastBuildDate); err != nil || !built.Equal(f.Updated) {
+		t.Errorf("lastBuildDate = %q, want %v", doc.Channel.LastBuildDate, f.Updated)
+	}
+}
+
+func TestEncodeAtom(t *testing.T) {
+	body, err := Encode(testFeed(), FormatAtom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type link struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Lang    string   `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+		ID      string   `xml:"id"`
+		Title   string   `xml:"title"`
+		Updated string   `xml:"updated"`
+		Links   []link   `xml:"link"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Updated   string `xml:"updated"`
+			Published string `xml:"published"`
+			Author    struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+			Links      []link `xml:"link"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+			Summary string `xml:"summary"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("feed does not parse: %v\n%s", err, body)
+	}
+
+	f, item := testFeed(), testFeed().Items[0]
+	if doc.ID != f.Self || doc.Title != f.Title || doc.Lang != f.Language {
+		t.Errorf("feed = %+v", doc)
+	}
+	if want := []link{{f.Self, "self"}, {f.Link, "alternate"}}; !reflect.DeepEqual(doc.Links, want) {
+		t.Errorf("links = %+v, want %+v", doc.Links, want)
+	}
+	if u, err := time.Parse(time.RFC3339, doc.Updated); err != nil || !u.Equal(f.Updated) {
+		t.Errorf("updated = %q, want %v", doc.Updated, f.Updated)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(doc.Entries))
+	}
+	got := doc.Entries[0]
+	if got.ID != item.ID || got.Title != item.Title || got.Author.Name != item.Author || got.Summary != item.Summary {
+		t.Errorf("entry = %+v", got)
+	}
+	if got.Content.Type != "html" || got.Content.Value != item.ContentHTML {
+		t.Errorf("content = %+v, want html %q", got.Content, item.ContentHTML)
+	}
+	if len(got.Links) != 1 || got.Links[0].Href != item.Link || len(got.Categories) != 2 || got.Categories[1].Term != "nutrition" {
+		t.Errorf("entry links and categories = %+v %+v", got.Links, got.Categories)
+	}
+	if p, err := time.Parse(time.RFC3339, got.Published); err != nil || !p.Equal(item.Published) {
+		t.Errorf("published = %q, want %v", got.Published, item.Published)
+	}
+	if u, err := time.Parse(time.RFC3339, got.Updated); err != nil || !u.Equal(item.Updated) {
+		t.Errorf("updated = %q, want %v", got.Updated, item.Updated)
+	}
+}
+
+func TestEncodeJSONFeed(t *testing.T) {
+	body, err := Encode(testFeed(), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Version     string `json:"version"`
+		Title       string `json:"title"`
+		HomePageURL string `json:"home_page_url"`
+		FeedURL     string `json:"feed_url"`
+		Language    string `json:"language"`
+		Items       []struct {
+			ID            string `json:"id"`
+			URL           string `json:"url"`
+			Title         string `json:"title"`
+			ContentHTML   string `json:"content_html"`
+			Summary       string `json:"summary"`
+			DatePublished string `json:"date_published"`
+			DateModified  string `json:"date_modified"`
+			Authors       []struct {
+				Name string `json:"name"`
+			} `json:"authors"`
+			Tags []string `json:"tags"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("feed does not parse: %v\n%s", err, body)
+	}
+
+	f, item := testFeed(), testFeed().Items[0]
+	if doc.Version != "https://jsonfeed.org/version/1.1" || doc.Title != f.Title || doc.HomePageURL != f.Link || doc.FeedURL != f.Self || doc.Language != f.Language {
+		t.Errorf("feed = %+v", doc)
+	}
+	if len(doc.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(doc.Items))
+	}
+	got := doc.Items[0]
+	if got.ID != item.ID || got.URL != item.Link || got.Title != item.Title || got.ContentHTML != item.ContentHTML || got.Summary != item.Summary {
+		t.Errorf("item = %+v", got)
+	}
+	if len(got.Authors) != 1 || got.Authors[0].Name != item.Author || !reflect.DeepEqual(got.Tags, item.Categories) {
+		t.Errorf("authors and tags = %+v %v", got.Authors, got.Tags)
+	}
+	if p, err := time.Parse(time.RFC3339, got.DatePublished); err != nil || !p.Equal(item.Published) {
+		t.Errorf("date_published = %q, want %v", got.DatePublished, item.Published)
+	}
+	if m, err := time.Parse(time.RFC3339, got.DateModified); err != nil || !m.Equal(item.Updated) {
+		t.Errorf("date_modified = %q, want %v", got.DateModified, item.Updated)
+	}
+}
+
+func TestEncodeEmptyFeed(t *testing.T) {
+	f := testFeed()
+	f.Items = nil
+	for _, format := range []string{FormatRSS, FormatAtom, FormatJSON} {
+		body, err := Encode(f, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if format == FormatJSON {
+			if !strings.Contains(string(body), `"items":[]`) {
+				t.Errorf("json feed without items array: %s", body)
+			}
+			continue
+		}
+		if err := xml.Unmarshal(body, new(struct{})); err != nil {
+			t.Errorf("%s: empty feed does not parse: %v", format, err)
+		}
+	}
+}
+
+func TestAbsolutize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`<p><a href="/blog/1">a</a></p>`, `<p><a href="https://api.example/blog/1">a</a></p>`},
+		{`<img src="/api/media/1/large" alt="x">`, `<img src="https://api.example/api/media/1/large" alt="x">`},
+		{`<a href="//cdn.example/x">a</a>`, `<a href="//cdn.example/x">a</a>`},
+		{`<a href="https://other.example/">a</a>`, `<a href="https://other.example/">a</a>`},
+		{`<p>1 &lt; 2</p>`, `<p>1 &lt; 2</p>`},
+	}
+	for _, tt := range tests {
+		if got := Absolutize(tt.in, "https://api.example"); got != tt.want {
+			t.Errorf("Absolutize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
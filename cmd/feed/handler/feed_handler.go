@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"crypto/sha256"
+	blogGear "dietku-backend/cmd/blog/gear"
+	blogRepo "dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/feed/gear"
+	userRepo "dietku-backend/cmd/user/repo"
+	"dietku-backend/config"
+	"encoding/hex"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// feedSize is how many of the newest posts a feed carries.
+	feedSize  = 50
+	feedTitle = "Dietku Blog"
+	// feedMaxAge lets clients and proxies reuse a feed for a while before
+	// asking again, which they then do conditionally.
+	feedMaxAge = "public, max-age=300"
+)
+
+type FeedHandler struct {
+	blogRepo     *blogRepo.BlogRepository
+	categoryRepo *blogRepo.CategoryRepository
+	userRepo     *userRepo.UserRepository
+	siteURL      string
+	apiURL       string
+}
+
+func NewFeedApi(e *echo.Echo, db *mongo.Database, conf *config.Config) *FeedHandler {
+	h := &FeedHandler{
+		blogRepo:     blogRepo.NewBlogRepository(db),
+		categoryRepo: blogRepo.NewCategoryRepository(db),
+		userRepo:     userRepo.NewUserRepository(db),
+		siteURL:      conf.SiteURL,
+		apiURL:       conf.APIURL,
+	}
+
+	fGroup := e.Group("")
+	{
+		fGroup.GET("/feeds/blog.:format", h.Blog)
+		fGroup.GET("/feeds/blog/category/:file", h.Category)
+		fGroup.GET("/feeds/blog/user/:file", h.User)
+	}
+	return h
+}
+
+// splitFile splits a feed file name like "diet.rss" into its name and format.
+func splitFile(file string) (name string, format string, ok bool) {
+	ext := path.Ext(file)
+	format = strings.TrimPrefix(ext, ".")
+	if _, ok := gear.ContentTypes[format]; !ok {
+		return "", "", false
+	}
+	name = strings.TrimSuffix(file, ext)
+	return name, format, name != ""
+}
+
+// link is the absolute URL of a blog page: on the site when one is configured,
+// otherwise the API endpoint with the same data. URLs are never built from
+// the Host header, which the client controls.
+func (h *FeedHandler) link(page string) string {
+	if h.siteURL != "" {
+		return h.siteURL + page
+	}
+	return h.apiURL + "/api" + page
+}
+
+// Blog
+// @Tags Feed
+// @Summary Get Blog Feed
+// @Description The newest published posts as RSS 2.0, Atom or JSON Feed 1.1, chosen by the extension. Answers 304 to a matching If-None-Match or, without one, an If-Modified-Since not before the newest change.
+// @ID feed-blog
+// @Router /feeds/blog.{format} [get]
+// @Param format path string true "rss, atom or json"
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Produce application/rss+xml,application/atom+xml,application/feed+json
+// @Success 200
+func (h *FeedHandler) Blog(c echo.Context) error {
+	_, format, ok := splitFile("blog." + c.Param("format"))
+	if !ok {
+		return echo.ErrNotFound
+	}
+
+	feed := &gear.Feed{
+		Title:       feedTitle,
+		Description: "Articles about diet, nutrition and healthy living from Dietku.",
+		Link:        h.link("/blog"),
+	}
+	return h.serve(c, feed, &blogRepo.ListQuery{}, format)
+}
+
+// Category
+// @Tags Feed
+// @Summary Get Blog Category Feed
+// @Description The newest published posts of a category, like the blog feed.
+// @ID feed-blog-category
+// @Router /feeds/blog/category/{file} [get]
+// @Param file path string true "Category slug and format, e.g. diet.rss, diet.atom or diet.json"
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Produce application/rss+xml,application/atom+xml,application/feed+json
+// @Success 200
+func (h *FeedHandler) Category(c echo.Context) error {
+	slug, format, ok := splitFile(c.Param("file"))
+	if !ok {
+		return echo.ErrNotFound
+	}
+
+	category, err := h.categoryRepo.FindBySlug(blogGear.Slugify(slug))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Category not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting category.", c)
+	}
+
+	feed := &gear.Feed{
+		Title:       feedTitle + ": " + category.NameID,
+		Description: category.Description,
+		Link:        h.link("/blog/category/" + category.Slug),
+	}
+	if feed.Description == "" {
+		feed.Description = "Dietku articles about " + category.NameID + "."
+	}
+	return h.serve(c, feed, &blogRepo.ListQuery{Categories: []string{category.Slug}}, format)
+}
+
+// User
+// @Tags Feed
+// @Summary Get Blog Author Feed
+// @Description The newest published posts of an author, like the blog feed.
+// @ID feed-blog-user
+// @Router /feeds/blog/user/{file} [get]
+// @Param file path string true "User ID and format, e.g. <userId>.rss, <userId>.atom or <userId>.json"
+// @Param If-None-Match header string false "ETag of the copy the client has"
+// @Param If-Modified-Since header string false "Last-Modified of the copy the client has"
+// @Produce application/rss+xml,application/atom+xml,application/feed+json
+// @Success 200
+func (h *FeedHandler) User(c echo.Context) error {
+	userId, format, ok := splitFile(c.Param("file"))
+	if !ok {
+		return echo.ErrNotFound
+	}
+	oId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id", c)
+	}
+
+	user, err := h.userRepo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "User not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	feed := &gear.Feed{
+		Title:       feedTitle + ": " + name,
+		Description: "Dietku articles by " + name + ".",
+		Link:        h.link("/blog/user/" + user.ID.Hex()),
+	}
+	return h.serve(c, feed, &blogRepo.ListQuery{Author: &user.ID}, format)
+}
+
+// serve fills feed with the published posts matching q and writes it in
+// format, or only answers 304 when the client's copy is still current.
+func (h *FeedHandler) serve(c echo.Context, feed *gear.Feed, q *blogRepo.ListQuery, format string) error {
+	q.Limit = feedSize
+	blogs, err := h.blogRepo.Latest(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+	// unpublishing or deleting a post changes the feed without changing
+	// any post left in it
+	changed, err := h.blogRepo.LastChange(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting blog.", c)
+	}
+
+	feed.Self = h.apiURL + c.Request().URL.Path
+	// an empty feed has a fixed date, so it keeps its ETag
+	feed.Updated = time.Unix(0, 0)
+	feed.Items = make([]gear.Item, len(*blogs))
+	for i, blog := range *blogs {
+		feed.Items[i] = h.item(&blog)
+		if feed.Items[i].Updated.After(feed.Updated) {
+			feed.Updated = feed.Items[i].Updated
+		}
+	}
+
+	body, err := gear.Encode(feed, format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while writing the feed.", c)
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", feedMaxAge)
+	modified := lastModified(feed, changed)
+	if !modified.IsZero() {
+		header.Set("Last-Modified", modified.Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request(), etag, modified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.Blob(http.StatusOK, gear.ContentTypes[format], body)
+}
+
+// lastModified is the newest of the feed's items and the last change to any
+// post it covers, in whole seconds; zero when there is neither.
+func lastModified(feed *gear.Feed, changed *time.Time) time.Time {
+	var t time.Time
+	if len(feed.Items) > 0 {
+		t = feed.Updated
+	}
+	if changed != nil && changed.After(t) {
+		t = *changed
+	}
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC().Truncate(time.Second)
+}
+
+func (h *FeedHandler) item(blog *blogRepo.Blog) gear.Item {
+	published := blog.CreatedBy.At
+	if blog.PublishedAt != nil {
+		published = *blog.PublishedAt
+	}
+	updated := published
+	if blog.UpdatedBy != nil && blog.UpdatedBy.At.After(updated) {
+		updated = blog.UpdatedBy.At
+	}
+
+	link := h.link("/blog/" + blog.ID.Hex())
+	author := blog.CreatedBy.FullName
+	if author == "" {
+		author = "Dietku"
+	}
+	return gear.Item{
+		ID:          link,
+		Link:        link,
+		Title:       blog.Header,
+		Summary:     blog.Excerpt,
+		ContentHTML: gear.Absolutize(blog.ContentHTML, h.apiURL),
+		Author:      author,
+		Categories:  blog.Category,
+		Language:    blog.Language,
+		Published:   published,
+		Updated:     updated,
+	}
+}
+
+// notModified evaluates the conditional headers of r as RFC 9110 orders them:
+// If-None-Match, when present, decides alone, using the weak comparison.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		return err == nil && !lastModified.After(t)
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"dietku-backend/cmd/feed/gear"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSplitFile(t *testing.T) {
+	tests := []struct {
+		file, name, format string
+		ok                 bool
+	}{
+		{"diet.rss", "diet", "rss", true},
+		{"diet.atom", "diet", "atom", true},
+		{"diet.json", "diet", "json", true},
+		{"diet.xml", "", "", false},
+		{".rss", "", "rss", false},
+		{"diet", "", "", false},
+	}
+	for _, tt := range tests {
+		name, format, ok := splitFile(tt.file)
+		if ok != tt.ok || (ok && (name != tt.name || format != tt.format)) {
+			t.Errorf("splitFile(%q) = %q, %q, %v, want %q, %q, %v", tt.file, name, format, ok, tt.name, tt.format, tt.ok)
+		}
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	newest := time.Date(2026, 3, 2, 9, 30, 15, 500, time.UTC)
+	feed := &gear.Feed{Updated: newest, Items: []gear.Item{{Updated: newest}}}
+
+	if got := lastModified(feed, nil); !got.Equal(newest.Truncate(time.Second)) {
+		t.Errorf("without changes = %v, want the newest item", got)
+	}
+
+	// a post taken out of the feed after its newest item was written
+	unpublished := newest.Add(time.Hour)
+	if got := lastModified(feed, &unpublished); !got.Equal(unpublished.Truncate(time.Second)) {
+		t.Errorf("after unpublishing = %v, want %v", got, unpublished)
+	}
+
+	older := newest.Add(-time.Hour)
+	if got := lastModified(feed, &older); !got.Equal(newest.Truncate(time.Second)) {
+		t.Errorf("with an older change = %v, want the newest item", got)
+	}
+
+	empty := &gear.Feed{Updated: time.Unix(0, 0)}
+	if got := lastModified(empty, nil); !got.IsZero() {
+		t.Errorf("empty feed = %v, want none", got)
+	}
+	if got := lastModified(empty, &unpublished); !got.Equal(unpublished.Truncate(time.Second)) {
+		t.Errorf("feed emptied by a change = %v, want %v", got, unpublished)
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	etag := `"abc"`
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no conditions", nil, false},
+		{"matching etag", map[string]string{"If-None-Match": `"abc"`}, true},
+		{"weak etag", map[string]string{"If-None-Match": `W/"abc"`}, true},
+		{"etag in a list", map[string]string{"If-None-Match": `"x", "abc"`}, true},
+		{"any etag", map[string]string{"If-None-Match": "*"}, true},
+		{"other etag", map[string]string{"If-None-Match": `"x"`}, false},
+		{"etag decides over date", map[string]string{"If-None-Match": `"x"`, "If-Modified-Since": modified.Format(http.TimeFormat)}, false},
+		{"same date", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, true},
+		{"later date", map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)}, true},
+		{"earlier date", map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, false},
+		{"bad date", map[string]string{"If-Modified-Since": "yesterday"}, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/feeds/blog.rss", nil)
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		if got := notModified(r, etag, modified); got != tt.want {
+			t.Errorf("%s: notModified = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/feeds/blog.rss", nil)
+	r.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	if notModified(r, etag, time.Time{}) {
+		t.Errorf("a feed without a date answered 304 to If-Modified-Since")
+	}
+}
@@ -0,0 +1,51 @@
+package gear
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidBarcode = errors.New("invalid barcode")
+
+// NormalizeBarcode validates an EAN-13, UPC-A or EAN-8 code and returns it as
+// EAN-13. Shorter codes are padded with leading zeros, as GS1 does to compare
+// them: a UPC-A code and its EAN-13 form resolve to one stored value, and an
+// EAN-8 code keeps its check digit. Spaces and dashes are ignored.
+func NormalizeBarcode(code string) (string, error) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidBarcode
+		}
+	}
+
+	switch len(code) {
+	case 8, 12:
+		code = strings.Repeat("0", 13-len(code)) + code
+	case 13:
+	default:
+		return "", ErrInvalidBarcode
+	}
+
+	if !validChecksum(code) {
+		return "", ErrInvalidBarcode
+	}
+	return code, nil
+}
+
+// validChecksum checks the GS1 check digit of an EAN-13 code: digits are
+// weighted 1 and 3 alternately from the left and the check digit brings the sum
+// up to a multiple of ten.
+func validChecksum(code string) bool {
+	sum := 0
+	for i, r := range code[:12] {
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	check := (10 - sum%10) % 10
+	return check == int(code[12]-'0')
+}
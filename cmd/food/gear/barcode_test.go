@@ -0,0 +1,41 @@
+package gear
+
+import "testing"
+
+func TestNormalizeBarcode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+		err  bool
+	}{
+		{name: "ean-13", code: "4006381333931", want: "4006381333931"},
+		{name: "ean-13 with spaces and dashes", code: "400-6381 333931", want: "4006381333931"},
+		{name: "upc-a padded to ean-13", code: "036000291452", want: "0036000291452"},
+		{name: "upc-a and its ean-13 form agree", code: "0036000291452", want: "0036000291452"},
+		{name: "ean-8 padded to ean-13", code: "96385074", want: "0000096385074"},
+		{name: "ean-13 bad check digit", code: "4006381333932", err: true},
+		{name: "upc-a bad check digit", code: "036000291453", err: true},
+		{name: "ean-8 bad check digit", code: "96385075", err: true},
+		{name: "letters", code: "40063813339a1", err: true},
+		{name: "unicode digits", code: "４００６３８１３３３９３１", err: true},
+		{name: "too short", code: "1234567", err: true},
+		{name: "between lengths", code: "1234567890", err: true},
+		{name: "too long", code: "40063813339310", err: true},
+		{name: "empty", code: "", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeBarcode(tt.code)
+			if tt.err {
+				if err != ErrInvalidBarcode {
+					t.Fatalf("NormalizeBarcode(%q) = %q, %v; want ErrInvalidBarcode", tt.code, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeBarcode(%q) = %q, %v; want %q", tt.code, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package gear
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/food/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+)
+
+// Preferences loads the listing filter of the logged in user, or nil for
+// anonymous requests and when all=true asks for everything.
+func Preferences(c echo.Context, users *userRepo.UserRepository) (*repo.Preferences, error) {
+	me, ok := c.Get("me").(*gear.UserClaims)
+	if !ok || c.QueryParam("all") == "true" {
+		return nil, nil
+	}
+
+	user, err := users.FindOne(me.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+	return user.Preferences.FoodPreferences(), nil
+}
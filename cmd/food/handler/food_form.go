@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"dietku-backend/cmd/food/gear"
+	"dietku-backend/cmd/food/repo"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+type FoodForm struct {
+	Name        string          `json:"name"`
+	Brand       string          `json:"brand"`
+	Barcode     string          `json:"barcode"`
+	Category    string          `json:"category"`
+	ServingSize float64         `json:"servingSize"`
+	ServingUnit string          `json:"servingUnit"`
+	Nutrients   *repo.Nutrients `json:"nutrients"`
+	Allergens   []string        `json:"allergens"`
+	Diets       []string        `json:"diets"`
+}
+
+func NewFoodForm(c echo.Context) (*FoodForm, error) {
+	form := new(FoodForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required.")
+	}
+
+	if form.Nutrients == nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Nutrients are required.")
+	}
+
+	if form.ServingUnit == "" {
+		form.ServingUnit = repo.UnitGram
+	}
+	if form.ServingSize == 0 {
+		form.ServingSize = 100
+	}
+	if form.Category == "" {
+		form.Category = repo.CategoryOther
+	}
+
+	if err := validateFoodForm(form); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+func NewUpdateFoodForm(c echo.Context) (*FoodForm, error) {
+	form := new(FoodForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+	form.Name = strings.TrimSpace(form.Name)
+
+	if err := validateFoodForm(form); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+func validateFoodForm(form *FoodForm) error {
+	form.Brand = strings.TrimSpace(form.Brand)
+
+	if form.Barcode != "" {
+		barcode, err := gear.NormalizeBarcode(form.Barcode)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Barcode must be a valid EAN-13, UPC-A or EAN-8 code.")
+		}
+		form.Barcode = barcode
+	}
+
+	if form.Category != "" {
+		form.Category = strings.ToLower(strings.TrimSpace(form.Category))
+		valid := false
+		for _, c := range repo.Categories {
+			if form.Category == c {
+				valid = true
+			}
+		}
+		if !valid {
+			return echo.NewHTTPError(http.StatusBadRequest, "Category must be one of "+strings.Join(repo.Categories, ", ")+".")
+		}
+	}
+
+	if form.ServingUnit != "" && form.ServingUnit != repo.UnitGram && form.ServingUnit != repo.UnitMilliliter {
+		return echo.NewHTTPError(http.StatusBadRequest, "Serving unit must be g or ml.")
+	}
+
+	if form.ServingSize < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Serving size must be positive.")
+	}
+
+	// nil means the tags were not sent; an empty list clears them
+	if form.Allergens != nil {
+		for i, a := range form.Allergens {
+			form.Allergens[i] = strings.ToLower(strings.TrimSpace(a))
+			if !repo.IsValidAllergen(form.Allergens[i]) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Allergens must be among "+strings.Join(repo.Allergens, ", ")+".")
+			}
+		}
+		form.Allergens = repo.NormalizeAllergens(form.Allergens)
+	}
+	if form.Diets != nil {
+		for i, d := range form.Diets {
+			form.Diets[i] = strings.ToLower(strings.TrimSpace(d))
+			if !repo.IsValidDiet(form.Diets[i]) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Diets must be among "+strings.Join(repo.Diets, ", ")+".")
+			}
+		}
+		form.Diets = repo.NormalizeDiets(form.Diets)
+	}
+
+	if n := form.Nutrients; n != nil {
+		if n.Calories < 0 || n.Protein < 0 || n.Carbohydrate < 0 || n.Fat < 0 || n.Fiber < 0 ||
+			n.Sugar < 0 || n.Sodium < 0 || n.SaturatedFat < 0 || n.Cholesterol < 0 ||
+			n.VitaminA < 0 || n.VitaminC < 0 || n.VitaminD < 0 || n.VitaminE < 0 || n.VitaminB12 < 0 ||
+			n.Folate < 0 || n.Calcium < 0 || n.Iron < 0 || n.Magnesium < 0 || n.Potassium < 0 || n.Zinc < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Nutrients must not be negative.")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	foodGear "dietku-backend/cmd/food/gear"
+	"dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/food/resolver"
+	"dietku-backend/cmd/log"
+	recipeRepo "dietku-backend/cmd/recipe/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+type FoodHandler struct {
+	repo       *repo.FoodRepository
+	recipeRepo *recipeRepo.RecipeRepository
+	userRepo   *userRepo.UserRepository
+	resolver   resolver.Resolver
+}
+
+// NewFoodApi registers the food endpoints. The resolver is consulted for
+// barcodes we do not know yet and may be nil to disable external lookups.
+func NewFoodApi(e *echo.Echo, db *mongo.Database, r resolver.Resolver) *FoodHandler {
+	f := &FoodHandler{
+		repo:       repo.NewFoodRepository(db),
+		recipeRepo: recipeRepo.NewRecipeRepository(db),
+		userRepo:   userRepo.NewUserRepository(db),
+		resolver:   r,
+	}
+	if err := f.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create food indexes: %v", err)
+	}
+
+	fGroup := e.Group("")
+	{
+		fGroup.GET("/api/foods", f.Foods, gear.MaybeLoggedIn(db))
+		fGroup.GET("/api/foods/:id", f.Food)
+		fGroup.GET("/api/foods/barcode/:code", f.FoodByBarcode)
+
+		fGroup.POST("/api/foods", f.Create, gear.IsLoggedIn(db))
+
+		fGroup.PUT("/api/foods/:id", f.Update, gear.IsLoggedIn(db))
+
+		fGroup.DELETE("/api/foods/:id", f.Delete, gear.IsLoggedIn(db))
+	}
+	return f
+}
+
+// Foods
+// @Tags Food
+// @Summary Search Foods
+// @Description When logged in, foods conflicting with the user's diet, allergens, halal requirement or dislikes are left out unless all=true.
+// @ID food
+// @Router /api/foods [get]
+// @Produce json
+// @Param q query string false "Name or brand"
+// @Param all query bool false "Ignore the user's preferences"
+// @Success 200
+func (h *FoodHandler) Foods(c echo.Context) error {
+	prefs, err := foodGear.Preferences(c, h.userRepo)
+	if err != nil {
+		return err
+	}
+
+	foods, err := h.repo.FindAll(c.QueryParam("q"), prefs)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting foods.", c)
+	}
+	return c.JSON(http.StatusOK, foods)
+}
+
+// Food
+// @Tags Food
+// @Summary Get Food
+// @ID food-get
+// @Router /api/foods/{id} [get]
+// @Produce json
+// @Param id path string true "Food ID"
+// @Success 200
+func (h *FoodHandler) Food(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid food id", c)
+	}
+
+	food, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Food not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting food.", c)
+	}
+	return c.JSON(http.StatusOK, food)
+}
+
+// FoodByBarcode
+// @Tags Food
+// @Summary Get Food By Barcode
+// @Description Accepts EAN-13, UPC-A and EAN-8 codes. Unknown codes are looked up in the external product database and cached.
+// @ID food-barcode
+// @Router /api/foods/barcode/{code} [get]
+// @Produce json
+// @Param code path string true "EAN-13, UPC-A or EAN-8 barcode"
+// @Success 200
+func (h *FoodHandler) FoodByBarcode(c echo.Context) error {
+	barcode, err := foodGear.NormalizeBarcode(c.Param("code"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid barcode", c)
+	}
+
+	food, err := resolver.Lookup(c.Request().Context(), h.repo, h.resolver, barcode, time.Now())
+	if err != nil {
+		if errors.Is(err, resolver.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Food not found!", c)
+		}
+		if errors.Is(err, resolver.ErrResolver) {
+			log.Errorcf(c, "barcode %s lookup failed: %v", barcode, err)
+			return echo.NewHTTPError(http.StatusBadGateway, "An error occurred while looking up barcode.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting food.", c)
+	}
+	return c.JSON(http.StatusOK, food)
+}
+
+// Create
+// @Tags Food
+// @Summary Create Food
+// @ID food-create
+// @Router /api/foods [post]
+// @Accept json
+// @Param body body FoodForm true "food body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FoodHandler) Create(c echo.Context) error {
+	form, err := NewFoodForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	f := &repo.Food{
+		ID:          primitive.NewObjectID(),
+		Name:        form.Name,
+		Brand:       form.Brand,
+		Barcode:     form.Barcode,
+		Category:    form.Category,
+		ServingSize: form.ServingSize,
+		ServingUnit: form.ServingUnit,
+		Nutrients:   *form.Nutrients,
+		Allergens:   form.Allergens,
+		Diets:       form.Diets,
+		Source:      repo.SourceUser,
+		CreatedBy:   &tokenData.ID,
+		CreatedAt:   time.Now(),
+	}
+
+	_, err = h.repo.InsertOne(f)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return echo.NewHTTPError(http.StatusBadRequest, "A food with this barcode already exists.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating food.", c)
+	}
+
+	docs, err := h.repo.FindOne(f.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting food.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Update
+// @Tags Food
+// @Summary Update Food
+// @ID food-update
+// @Router /api/foods/{id} [put]
+// @Accept json
+// @Param id path string true "Food ID"
+// @Param body body FoodForm true "food body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FoodHandler) Update(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid food id", c)
+	}
+
+	food, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Food not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting food.", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	if food.CreatedBy == nil || *food.CreatedBy != tokenData.ID {
+		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to update this food", c)
+	}
+
+	form, err := NewUpdateFoodForm(c)
+	if err != nil {
+		return err
+	}
+
+	if form.Name != "" {
+		food.Name = form.Name
+	}
+	if form.Brand != "" {
+		food.Brand = form.Brand
+	}
+	if form.Barcode != "" {
+		food.Barcode = form.Barcode
+	}
+	if form.Category != "" {
+		food.Category = form.Category
+	}
+	if form.ServingSize > 0 {
+		food.ServingSize = form.ServingSize
+	}
+	if form.ServingUnit != "" {
+		food.ServingUnit = form.ServingUnit
+	}
+	if form.Nutrients != nil {
+		food.Nutrients = *form.Nutrients
+	}
+	if form.Allergens != nil {
+		food.Allergens = form.Allergens
+	}
+	if form.Diets != nil {
+		food.Diets = form.Diets
+	}
+
+	now := time.Now()
+	food.UpdatedAt = &now
+
+	docs, err := h.repo.UpdateOne(food)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return echo.NewHTTPError(http.StatusBadRequest, "A food with this barcode already exists.", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating food.", c)
+	}
+
+	h.recalculateRecipes(c, docs)
+	return c.JSON(http.StatusOK, docs)
+}
+
+// recalculateRecipes refreshes the nutrition of every recipe using food. The
+// food update already succeeded, so failures are only logged.
+func (h *FoodHandler) recalculateRecipes(c echo.Context, food *repo.Food) {
+	recipes, err := h.recipeRepo.FindByFood(food.ID)
+	if err != nil {
+		log.Errorcf(c, "failed to find recipes using food %s: %v", food.ID.Hex(), err)
+		return
+	}
+
+	for i := range *recipes {
+		recipe := &(*recipes)[i]
+		if !recipe.ApplyFood(food) {
+			continue
+		}
+		if _, err := h.recipeRepo.UpdateOne(recipe); err != nil {
+			log.Errorcf(c, "failed to recalculate recipe %s: %v", recipe.ID.Hex(), err)
+		}
+	}
+}
+
+// Delete
+// @Tags Food
+// @Summary Delete Food
+// @ID food-delete
+// @Router /api/foods/{id} [delete]
+// @Produce json
+// @Param id path string true "Food ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *FoodHandler) Delete(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid food id", c)
+	}
+
+	food, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Food not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting food.", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	if food.CreatedBy == nil || *food.CreatedBy != tokenData.ID {
+		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to delete this food", c)
+	}
+
+	docs, err := h.repo.DeleteOne(food.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting food.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
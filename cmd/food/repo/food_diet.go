@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
+	"sort"
+)
+
+// Allergens are the common food allergens a food or recipe can be tagged with.
+var Allergens = []string{"peanut", "tree-nut", "milk", "egg", "fish", "shellfish", "soy", "wheat", "sesame"}
+
+const (
+	DietVegan       = "vegan"
+	DietVegetarian  = "vegetarian"
+	DietPescatarian = "pescatarian"
+	DietGlutenFree  = "gluten-free"
+	DietHalal       = "halal"
+)
+
+// Diets are the diet-type tags a food or recipe can satisfy.
+var Diets = []string{DietVegan, DietVegetarian, DietPescatarian, DietGlutenFree, DietHalal}
+
+// dietImplies lists the diets that are satisfied by another one, e.g. a vegan
+// food is also vegetarian.
+var dietImplies = map[string][]string{
+	DietVegan:      {DietVegetarian, DietPescatarian},
+	DietVegetarian: {DietPescatarian},
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidAllergen(allergen string) bool {
+	return contains(Allergens, allergen)
+}
+
+func IsValidDiet(diet string) bool {
+	return contains(Diets, diet)
+}
+
+// NormalizeDiets adds the implied diets and returns the tags sorted without
+// duplicates, so filtering only has to look for the wanted diet.
+func NormalizeDiets(diets []string) []string {
+	set := map[string]bool{}
+	for _, d := range diets {
+		set[d] = true
+		for _, implied := range dietImplies[d] {
+			set[implied] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// NormalizeAllergens returns the allergens sorted without duplicates.
+func NormalizeAllergens(allergens []string) []string {
+	set := map[string]bool{}
+	for _, a := range allergens {
+		set[a] = true
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Conflicts returns the allergens found in both lists.
+func Conflicts(allergens []string, avoid []string) []string {
+	conflicts := []string{}
+	for _, a := range allergens {
+		if contains(avoid, a) {
+			conflicts = append(conflicts, a)
+		}
+	}
+	return conflicts
+}
+
+// Preferences narrows listings to what a user can and wants to eat.
+type Preferences struct {
+	Allergens []string
+	Diets     []string
+	Dislikes  []string
+}
+
+// Apply adds the preference conditions to filter. Dislikes are matched
+// against each of nameFields.
+func (p *Preferences) Apply(filter bson.M, nameFields ...string) {
+	if p == nil {
+		return
+	}
+	if len(p.Allergens) > 0 {
+		filter["allergens"] = bson.M{"$nin": p.Allergens}
+	}
+	if len(p.Diets) > 0 {
+		filter["diets"] = bson.M{"$all": p.Diets}
+	}
+
+	nor := bson.A{}
+	for _, d := range p.Dislikes {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(d), Options: "i"}
+		for _, field := range nameFields {
+			nor = append(nor, bson.M{field: pattern})
+		}
+	}
+	if len(nor) > 0 {
+		filter["$nor"] = nor
+	}
+}
@@ -0,0 +1,277 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
+)
+
+const (
+	SourceUser          = "user"
+	SourceOpenFoodFacts = "openfoodfacts"
+
+	UnitGram       = "g"
+	UnitMilliliter = "ml"
+	UnitServing    = "serving"
+)
+
+// Categories are the store aisles foods are grouped by, in walking order.
+var Categories = []string{
+	"produce", "meat-fish", "dairy-eggs", "bakery", "pantry", "frozen", "beverages", "snacks", "other",
+}
+
+const CategoryOther = "other"
+
+// Nutrients holds nutrition values per 100 g (or 100 ml for liquids). Energy is
+// in kcal, sodium, cholesterol and the minerals in mg, vitamins A, D and B12
+// and folate in µg, vitamins C and E in mg and everything else in grams.
+// Micronutrients are optional, most foods only list a few of them.
+type Nutrients struct {
+	Calories     float64 `json:"calories" bson:"calories"`
+	Protein      float64 `json:"protein" bson:"protein"`
+	Carbohydrate float64 `json:"carbohydrate" bson:"carbohydrate"`
+	Fat          float64 `json:"fat" bson:"fat"`
+	Fiber        float64 `json:"fiber" bson:"fiber"`
+	Sugar        float64 `json:"sugar" bson:"sugar"`
+	Sodium       float64 `json:"sodium" bson:"sodium"`
+	SaturatedFat float64 `json:"saturatedFat" bson:"saturatedFat"`
+	Cholesterol  float64 `json:"cholesterol" bson:"cholesterol"`
+	VitaminA     float64 `json:"vitaminA,omitempty" bson:"vitaminA,omitempty"`
+	VitaminC     float64 `json:"vitaminC,omitempty" bson:"vitaminC,omitempty"`
+	VitaminD     float64 `json:"vitaminD,omitempty" bson:"vitaminD,omitempty"`
+	VitaminE     float64 `json:"vitaminE,omitempty" bson:"vitaminE,omitempty"`
+	VitaminB12   float64 `json:"vitaminB12,omitempty" bson:"vitaminB12,omitempty"`
+	Folate       float64 `json:"folate,omitempty" bson:"folate,omitempty"`
+	Calcium      float64 `json:"calcium,omitempty" bson:"calcium,omitempty"`
+	Iron         float64 `json:"iron,omitempty" bson:"iron,omitempty"`
+	Magnesium    float64 `json:"magnesium,omitempty" bson:"magnesium,omitempty"`
+	Potassium    float64 `json:"potassium,omitempty" bson:"potassium,omitempty"`
+	Zinc         float64 `json:"zinc,omitempty" bson:"zinc,omitempty"`
+}
+
+// NutrientFields are the bson names of every Nutrients field. Aggregations use
+// it to total all tracked nutrients without listing them by hand.
+var NutrientFields = []string{
+	"calories", "protein", "carbohydrate", "fat", "fiber", "sugar", "sodium", "saturatedFat", "cholesterol",
+	"vitaminA", "vitaminC", "vitaminD", "vitaminE", "vitaminB12", "folate",
+	"calcium", "iron", "magnesium", "potassium", "zinc",
+}
+
+// Scale returns the nutrients multiplied by factor, e.g. grams/100 to turn the
+// per 100 g values into an eaten amount.
+func (n Nutrients) Scale(factor float64) Nutrients {
+	return Nutrients{
+		Calories:     n.Calories * factor,
+		Protein:      n.Protein * factor,
+		Carbohydrate: n.Carbohydrate * factor,
+		Fat:          n.Fat * factor,
+		Fiber:        n.Fiber * factor,
+		Sugar:        n.Sugar * factor,
+		Sodium:       n.Sodium * factor,
+		SaturatedFat: n.SaturatedFat * factor,
+		Cholesterol:  n.Cholesterol * factor,
+		VitaminA:     n.VitaminA * factor,
+		VitaminC:     n.VitaminC * factor,
+		VitaminD:     n.VitaminD * factor,
+		VitaminE:     n.VitaminE * factor,
+		VitaminB12:   n.VitaminB12 * factor,
+		Folate:       n.Folate * factor,
+		Calcium:      n.Calcium * factor,
+		Iron:         n.Iron * factor,
+		Magnesium:    n.Magnesium * factor,
+		Potassium:    n.Potassium * factor,
+		Zinc:         n.Zinc * factor,
+	}
+}
+
+func (n Nutrients) Add(o Nutrients) Nutrients {
+	return Nutrients{
+		Calories:     n.Calories + o.Calories,
+		Protein:      n.Protein + o.Protein,
+		Carbohydrate: n.Carbohydrate + o.Carbohydrate,
+		Fat:          n.Fat + o.Fat,
+		Fiber:        n.Fiber + o.Fiber,
+		Sugar:        n.Sugar + o.Sugar,
+		Sodium:       n.Sodium + o.Sodium,
+		SaturatedFat: n.SaturatedFat + o.SaturatedFat,
+		Cholesterol:  n.Cholesterol + o.Cholesterol,
+		VitaminA:     n.VitaminA + o.VitaminA,
+		VitaminC:     n.VitaminC + o.VitaminC,
+		VitaminD:     n.VitaminD + o.VitaminD,
+		VitaminE:     n.VitaminE + o.VitaminE,
+		VitaminB12:   n.VitaminB12 + o.VitaminB12,
+		Folate:       n.Folate + o.Folate,
+		Calcium:      n.Calcium + o.Calcium,
+		Iron:         n.Iron + o.Iron,
+		Magnesium:    n.Magnesium + o.Magnesium,
+		Potassium:    n.Potassium + o.Potassium,
+		Zinc:         n.Zinc + o.Zinc,
+	}
+}
+
+type Food struct {
+	ID          primitive.ObjectID  `json:"_id" bson:"_id"`
+	Name        string              `json:"name" bson:"name"`
+	Brand       string              `json:"brand,omitempty" bson:"brand,omitempty"`
+	Category    string              `json:"category" bson:"category"`
+	Barcode     string              `json:"barcode,omitempty" bson:"barcode,omitempty"`
+	ServingSize float64             `json:"servingSize" bson:"servingSize"`
+	ServingUnit string              `json:"servingUnit" bson:"servingUnit"`
+	Nutrients   Nutrients           `json:"nutrients" bson:"nutrients"`
+	Allergens   []string            `json:"allergens,omitempty" bson:"allergens"`
+	Diets       []string            `json:"diets,omitempty" bson:"diets"`
+	Source      string              `json:"source" bson:"source"`
+	CreatedBy   *primitive.ObjectID `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	CreatedAt   time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   *time.Time          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	IsDeleted   bool                `json:"isDeleted" bson:"isDeleted"`
+}
+
+// Grams converts a quantity in unit into grams of this food. Millilitres are
+// treated as grams, which is close enough for the drinks people log.
+func (f *Food) Grams(quantity float64, unit string) float64 {
+	if unit == UnitServing {
+		return quantity * f.ServingSize
+	}
+	return quantity
+}
+
+type Foods []Food
+
+func DecodeAsFoods(cursor *mongo.Cursor) (*Foods, error) {
+	docs := Foods{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type FoodRepository struct {
+	coll *mongo.Collection
+}
+
+func NewFoodRepository(db *mongo.Database) *FoodRepository {
+	return &FoodRepository{
+		coll: db.Collection("foods"),
+	}
+}
+
+// EnsureIndexes creates the indexes the food queries rely on. A barcode can only
+// belong to a single food, so cached lookups never produce duplicates.
+func (r *FoodRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "barcode", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"barcode": bson.M{"$exists": true},
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "name", Value: 1}},
+		},
+	})
+	return err
+}
+
+// FindAll searches foods by name or brand, leaving out those that do not match
+// prefs. prefs may be nil.
+func (r *FoodRepository) FindAll(query string, prefs *Preferences) (*Foods, error) {
+	filter := bson.M{"isDeleted": bson.M{"$ne": true}}
+	if query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"brand": pattern},
+		}
+	}
+	prefs.Apply(filter, "name")
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetLimit(50)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsFoods(cursor)
+}
+
+// FindForPlanning returns foods with energy matching prefs in a stable order,
+// so the meal planner sees the same candidates for the same data. prefs may be
+// nil.
+func (r *FoodRepository) FindForPlanning(limit int64, prefs *Preferences) (*Foods, error) {
+	filter := bson.M{"nutrients.calories": bson.M{"$gt": 0}, "isDeleted": bson.M{"$ne": true}}
+	prefs.Apply(filter, "name")
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsFoods(cursor)
+}
+
+func (r *FoodRepository) FindOne(id primitive.ObjectID) (*Food, error) {
+	var d = &Food{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *FoodRepository) FindByIDs(ids []primitive.ObjectID) (*Foods, error) {
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"_id": bson.M{"$in": ids}, "isDeleted": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsFoods(cursor)
+}
+
+func (r *FoodRepository) FindByBarcode(barcode string) (*Food, error) {
+	var d = &Food{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"barcode": barcode, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *FoodRepository) InsertOne(newFood *Food) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newFood)
+}
+
+func (r *FoodRepository) UpdateOne(food *Food) (*Food, error) {
+	filter := bson.M{"_id": food.ID}
+
+	update := bson.M{
+		"$set": food,
+	}
+
+	var d = &Food{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *FoodRepository) DeleteOne(id primitive.ObjectID) (*Food, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set":   bson.M{"isDeleted": true},
+		"$unset": bson.M{"barcode": ""},
+	}
+
+	var d = &Food{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
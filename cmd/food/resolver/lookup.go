@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"context"
+	"dietku-backend/cmd/food/repo"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ErrResolver wraps the failures of a resolver, as opposed to those of the
+// store.
+var ErrResolver = errors.New("resolver failed")
+
+// Store is the part of the food collection a lookup reads and fills.
+type Store interface {
+	FindByBarcode(barcode string) (*repo.Food, error)
+	InsertOne(newFood *repo.Food) (*mongo.InsertOneResult, error)
+}
+
+// Lookup returns the food with barcode from store. Unknown barcodes are passed
+// to r, which may be nil to disable external lookups, and what it finds is
+// stored so the next lookup is answered locally. It returns ErrNotFound when
+// neither knows the barcode.
+func Lookup(ctx context.Context, store Store, r Resolver, barcode string, now time.Time) (*repo.Food, error) {
+	food, err := store.FindByBarcode(barcode)
+	if err == nil {
+		return food, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, ErrNotFound
+	}
+	food, err = r.Resolve(ctx, barcode)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", ErrResolver, err)
+	}
+
+	food.ID = primitive.NewObjectID()
+	food.Barcode = barcode
+	if food.Category == "" {
+		food.Category = repo.CategoryOther
+	}
+	food.CreatedAt = now
+	food.IsDeleted = false
+
+	_, err = store.InsertOne(food)
+	if err != nil {
+		// another request cached the same barcode first
+		if mongo.IsDuplicateKeyError(err) {
+			return store.FindByBarcode(barcode)
+		}
+		return nil, err
+	}
+	return food, nil
+}
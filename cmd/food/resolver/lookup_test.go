@@ -0,0 +1,167 @@
+package resolver
+
+import (
+	"context"
+	"dietku-backend/cmd/food/repo"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+// memoryStore is a food collection keyed by barcode. insertErr, when set, is
+// returned by the next insert after running race, which can store a competing
+// food.
+type memoryStore struct {
+	foods     map[string]repo.Food
+	inserts   int
+	insertErr error
+	race      func()
+}
+
+func (s *memoryStore) FindByBarcode(barcode string) (*repo.Food, error) {
+	f, ok := s.foods[barcode]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &f, nil
+}
+
+func (s *memoryStore) InsertOne(newFood *repo.Food) (*mongo.InsertOneResult, error) {
+	s.inserts++
+	if s.insertErr != nil {
+		if s.race != nil {
+			s.race()
+		}
+		return nil, s.insertErr
+	}
+	s.foods[newFood.Barcode] = *newFood
+	return &mongo.InsertOneResult{InsertedID: newFood.ID}, nil
+}
+
+// failingResolver stands in for an external database that is down.
+type failingResolver struct{}
+
+func (failingResolver) Resolve(context.Context, string) (*repo.Food, error) {
+	return nil, errors.New("connection refused")
+}
+
+const barcode = "4006381333931"
+
+var now = time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+
+func TestLookupCacheHit(t *testing.T) {
+	store := &memoryStore{foods: map[string]repo.Food{barcode: {Name: "Cached", Barcode: barcode}}}
+	r := StaticResolver{barcode: {Name: "Remote"}}
+
+	food, err := Lookup(context.Background(), store, r, barcode, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.Name != "Cached" {
+		t.Errorf("got %q, want the cached food", food.Name)
+	}
+	if store.inserts != 0 {
+		t.Errorf("cache hit inserted %d foods", store.inserts)
+	}
+}
+
+func TestLookupResolvesAndCaches(t *testing.T) {
+	store := &memoryStore{foods: map[string]repo.Food{}}
+	r := StaticResolver{barcode: {Name: "Remote", Brand: "Acme"}}
+
+	food, err := Lookup(context.Background(), store, r, barcode, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.Name != "Remote" || food.Barcode != barcode || food.Category != repo.CategoryOther || !food.CreatedAt.Equal(now) || food.ID.IsZero() {
+		t.Errorf("resolved food not filled in: %+v", food)
+	}
+
+	cached, ok := store.foods[barcode]
+	if !ok || cached.ID != food.ID {
+		t.Fatalf("resolved food was not cached")
+	}
+
+	// the second lookup is answered from the store
+	delete(r, barcode)
+	food, err = Lookup(context.Background(), store, r, barcode, now)
+	if err != nil || food.ID != cached.ID {
+		t.Fatalf("second lookup = %+v, %v; want the cached food", food, err)
+	}
+	if store.inserts != 1 {
+		t.Errorf("inserted %d foods, want 1", store.inserts)
+	}
+}
+
+func TestLookupKeepsResolvedCategory(t *testing.T) {
+	store := &memoryStore{foods: map[string]repo.Food{}}
+	r := StaticResolver{barcode: {Name: "Remote", Category: "beverage"}}
+
+	food, err := Lookup(context.Background(), store, r, barcode, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.Category != "beverage" {
+		t.Errorf("category = %q, want beverage", food.Category)
+	}
+}
+
+func TestLookupNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Resolver
+	}{
+		{name: "resolver miss", r: StaticResolver{}},
+		{name: "no resolver", r: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{foods: map[string]repo.Food{}}
+			_, err := Lookup(context.Background(), store, tt.r, barcode, now)
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("err = %v, want ErrNotFound", err)
+			}
+			if store.inserts != 0 {
+				t.Errorf("a miss inserted %d foods", store.inserts)
+			}
+		})
+	}
+}
+
+func TestLookupResolverFailure(t *testing.T) {
+	store := &memoryStore{foods: map[string]repo.Food{}}
+
+	_, err := Lookup(context.Background(), store, failingResolver{}, barcode, now)
+	if !errors.Is(err, ErrResolver) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrResolver", err)
+	}
+}
+
+func TestLookupConcurrentInsert(t *testing.T) {
+	store := &memoryStore{foods: map[string]repo.Food{}}
+	store.insertErr = mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "duplicate key"}}}
+	store.race = func() {
+		store.foods[barcode] = repo.Food{Name: "Winner", Barcode: barcode}
+	}
+	r := StaticResolver{barcode: {Name: "Loser"}}
+
+	food, err := Lookup(context.Background(), store, r, barcode, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.Name != "Winner" {
+		t.Errorf("got %q, want the food the other request stored", food.Name)
+	}
+}
+
+func TestLookupInsertFailure(t *testing.T) {
+	insertErr := errors.New("disk full")
+	store := &memoryStore{foods: map[string]repo.Food{}, insertErr: insertErr}
+	r := StaticResolver{barcode: {Name: "Remote"}}
+
+	_, err := Lookup(context.Background(), store, r, barcode, now)
+	if !errors.Is(err, insertErr) || errors.Is(err, ErrResolver) {
+		t.Fatalf("err = %v, want the store error", err)
+	}
+}
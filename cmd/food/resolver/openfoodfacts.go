@@ -0,0 +1,177 @@
+package resolver
+
+import (
+	"context"
+	"dietku-backend/cmd/food/repo"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OpenFoodFacts resolves barcodes against the Open Food Facts product API.
+type OpenFoodFacts struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOpenFoodFacts(baseURL string) *OpenFoodFacts {
+	return &OpenFoodFacts{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  http.DefaultClient,
+	}
+}
+
+// flexFloat accepts numbers that Open Food Facts sometimes encodes as strings.
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		*f = 0
+		return nil
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+// offAllergens and offLabels map Open Food Facts taxonomy tags to our own
+// allergen and diet tags.
+var offAllergens = map[string]string{
+	"en:peanuts":      "peanut",
+	"en:nuts":         "tree-nut",
+	"en:milk":         "milk",
+	"en:eggs":         "egg",
+	"en:fish":         "fish",
+	"en:crustaceans":  "shellfish",
+	"en:molluscs":     "shellfish",
+	"en:soybeans":     "soy",
+	"en:gluten":       "wheat",
+	"en:sesame-seeds": "sesame",
+}
+
+var offLabels = map[string]string{
+	"en:vegan":      repo.DietVegan,
+	"en:vegetarian": repo.DietVegetarian,
+	"en:no-gluten":  repo.DietGlutenFree,
+	"en:halal":      repo.DietHalal,
+}
+
+func mapTags(tags []string, known map[string]string) []string {
+	mapped := []string{}
+	for _, t := range tags {
+		if v, ok := known[t]; ok {
+			mapped = append(mapped, v)
+		}
+	}
+	return mapped
+}
+
+type offResponse struct {
+	Status  int `json:"status"`
+	Product struct {
+		ProductName     string    `json:"product_name"`
+		Brands          string    `json:"brands"`
+		ServingQuantity flexFloat `json:"serving_quantity"`
+		AllergensTags   []string  `json:"allergens_tags"`
+		LabelsTags      []string  `json:"labels_tags"`
+		Nutriments      struct {
+			EnergyKcal   flexFloat `json:"energy-kcal_100g"`
+			Proteins     flexFloat `json:"proteins_100g"`
+			Carbohydrate flexFloat `json:"carbohydrates_100g"`
+			Fat          flexFloat `json:"fat_100g"`
+			Fiber        flexFloat `json:"fiber_100g"`
+			Sugars       flexFloat `json:"sugars_100g"`
+			Sodium       flexFloat `json:"sodium_100g"`
+			SaturatedFat flexFloat `json:"saturated-fat_100g"`
+			Cholesterol  flexFloat `json:"cholesterol_100g"`
+			VitaminA     flexFloat `json:"vitamin-a_100g"`
+			VitaminC     flexFloat `json:"vitamin-c_100g"`
+			VitaminD     flexFloat `json:"vitamin-d_100g"`
+			VitaminE     flexFloat `json:"vitamin-e_100g"`
+			VitaminB12   flexFloat `json:"vitamin-b12_100g"`
+			Folate       flexFloat `json:"vitamin-b9_100g"`
+			Calcium      flexFloat `json:"calcium_100g"`
+			Iron         flexFloat `json:"iron_100g"`
+			Magnesium    flexFloat `json:"magnesium_100g"`
+			Potassium    flexFloat `json:"potassium_100g"`
+			Zinc         flexFloat `json:"zinc_100g"`
+		} `json:"nutriments"`
+	} `json:"product"`
+}
+
+func (r *OpenFoodFacts) Resolve(ctx context.Context, barcode string) (*repo.Food, error) {
+	url := fmt.Sprintf("%s/api/v2/product/%s.json?fields=product_name,brands,serving_quantity,allergens_tags,labels_tags,nutriments", r.baseURL, barcode)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "dietku-backend")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("open food facts: unexpected status %d", resp.StatusCode)
+	}
+
+	var body offResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Status != 1 || body.Product.ProductName == "" {
+		return nil, ErrNotFound
+	}
+
+	p := body.Product
+	servingSize := float64(p.ServingQuantity)
+	if servingSize <= 0 {
+		servingSize = 100
+	}
+
+	return &repo.Food{
+		Name:        p.ProductName,
+		Brand:       strings.TrimSpace(strings.Split(p.Brands, ",")[0]),
+		Barcode:     barcode,
+		ServingSize: servingSize,
+		ServingUnit: repo.UnitGram,
+		// Open Food Facts reports nutriments in grams, ours use mg and µg
+		Nutrients: repo.Nutrients{
+			Calories:     float64(p.Nutriments.EnergyKcal),
+			Protein:      float64(p.Nutriments.Proteins),
+			Carbohydrate: float64(p.Nutriments.Carbohydrate),
+			Fat:          float64(p.Nutriments.Fat),
+			Fiber:        float64(p.Nutriments.Fiber),
+			Sugar:        float64(p.Nutriments.Sugars),
+			Sodium:       float64(p.Nutriments.Sodium) * 1000,
+			SaturatedFat: float64(p.Nutriments.SaturatedFat),
+			Cholesterol:  float64(p.Nutriments.Cholesterol) * 1000,
+			VitaminA:     float64(p.Nutriments.VitaminA) * 1e6,
+			VitaminC:     float64(p.Nutriments.VitaminC) * 1000,
+			VitaminD:     float64(p.Nutriments.VitaminD) * 1e6,
+			VitaminE:     float64(p.Nutriments.VitaminE) * 1000,
+			VitaminB12:   float64(p.Nutriments.VitaminB12) * 1e6,
+			Folate:       float64(p.Nutriments.Folate) * 1e6,
+			Calcium:      float64(p.Nutriments.Calcium) * 1000,
+			Iron:         float64(p.Nutriments.Iron) * 1000,
+			Magnesium:    float64(p.Nutriments.Magnesium) * 1000,
+			Potassium:    float64(p.Nutriments.Potassium) * 1000,
+			Zinc:         float64(p.Nutriments.Zinc) * 1000,
+		},
+		Allergens: repo.NormalizeAllergens(mapTags(p.AllergensTags, offAllergens)),
+		Diets:     repo.NormalizeDiets(mapTags(p.LabelsTags, offLabels)),
+		Source:    repo.SourceOpenFoodFacts,
+	}, nil
+}
@@ -0,0 +1,27 @@
+package resolver
+
+import (
+	"context"
+	"dietku-backend/cmd/food/repo"
+	"errors"
+)
+
+var ErrNotFound = errors.New("product not found")
+
+// Resolver looks up a product outside of our own food collection. Barcodes are
+// passed in normalized EAN-13 form and the returned food is cached by Lookup.
+type Resolver interface {
+	Resolve(ctx context.Context, barcode string) (*repo.Food, error)
+}
+
+// StaticResolver resolves barcodes from a fixed in-memory set of foods. It is
+// meant for local development and tests where no external database is reachable.
+type StaticResolver map[string]repo.Food
+
+func (r StaticResolver) Resolve(_ context.Context, barcode string) (*repo.Food, error) {
+	f, ok := r[barcode]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &f, nil
+}
@@ -0,0 +1,150 @@
+package gear
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment holding a TIFF block whose first IFD has
+// only the orientation tag.
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], orientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withExif encodes img as a JPEG and puts the segment right after its start.
+func withExif(t *testing.T, img image.Image, segment []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	plain := withExif(t, img, nil)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "big endian 3", data: withExif(t, img, exifSegment(binary.BigEndian, 3)), want: 3},
+		{name: "big endian 6", data: withExif(t, img, exifSegment(binary.BigEndian, 6)), want: 6},
+		{name: "big endian 8", data: withExif(t, img, exifSegment(binary.BigEndian, 8)), want: 8},
+		{name: "little endian 6", data: withExif(t, img, exifSegment(binary.LittleEndian, 6)), want: 6},
+		{name: "upright", data: withExif(t, img, exifSegment(binary.BigEndian, 1)), want: 1},
+		{name: "out of range", data: withExif(t, img, exifSegment(binary.BigEndian, 9)), want: 1},
+		{name: "no exif", data: plain, want: 1},
+		{name: "not a jpeg", data: []byte("\x89PNG\r\n\x1a\n"), want: 1},
+		{name: "empty", data: nil, want: 1},
+		{name: "truncated", data: withExif(t, img, exifSegment(binary.BigEndian, 6))[:20], want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Orientation(tt.data); got != tt.want {
+				t.Errorf("Orientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// numbered returns a w×h image whose pixels hold their index in red, so where
+// each one ends up can be told.
+func numbered(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(y*w + x), A: 255})
+		}
+	}
+	return img
+}
+
+func TestOrient(t *testing.T) {
+	// a 3×2 source; corners are top left, top right, bottom left, bottom
+	// right, as the indexes of the source pixels that land there
+	src := numbered(3, 2)
+	tl, tr, bl, br := uint8(0), uint8(2), uint8(3), uint8(5)
+
+	tests := []struct {
+		orientation   int
+		width, height int
+		corners       [4]uint8
+	}{
+		{orientation: 1, width: 3, height: 2, corners: [4]uint8{tl, tr, bl, br}},
+		{orientation: 2, width: 3, height: 2, corners: [4]uint8{tr, tl, br, bl}},
+		{orientation: 3, width: 3, height: 2, corners: [4]uint8{br, bl, tr, tl}},
+		{orientation: 4, width: 3, height: 2, corners: [4]uint8{bl, br, tl, tr}},
+		{orientation: 5, width: 2, height: 3, corners: [4]uint8{tl, bl, tr, br}},
+		// the camera was turned, so the top of the scene is on the left
+		{orientation: 6, width: 2, height: 3, corners: [4]uint8{bl, tl, br, tr}},
+		{orientation: 7, width: 2, height: 3, corners: [4]uint8{br, tr, bl, tl}},
+		// the top of the scene is on the right
+		{orientation: 8, width: 2, height: 3, corners: [4]uint8{tr, br, tl, bl}},
+	}
+	for _, tt := range tests {
+		dst := Orient(src, tt.orientation)
+		w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+		if w != tt.width || h != tt.height {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.orientation, w, h, tt.width, tt.height)
+			continue
+		}
+		got := [4]uint8{
+			dst.NRGBAAt(0, 0).R,
+			dst.NRGBAAt(w-1, 0).R,
+			dst.NRGBAAt(0, h-1).R,
+			dst.NRGBAAt(w-1, h-1).R,
+		}
+		if got != tt.corners {
+			t.Errorf("orientation %d: corners %v, want %v", tt.orientation, got, tt.corners)
+		}
+	}
+
+	if Orient(src, 0) != src || Orient(src, 9) != src {
+		t.Error("an unknown orientation changed the image")
+	}
+}
+
+func TestDecodeOriented(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for _, tt := range []struct {
+		orientation   uint16
+		width, height int
+	}{
+		{orientation: 3, width: 40, height: 20},
+		{orientation: 6, width: 20, height: 40},
+		{orientation: 8, width: 20, height: 40},
+	} {
+		decoded, err := Decode(withExif(t, img, exifSegment(binary.BigEndian, tt.orientation)), "image/jpeg")
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tt.orientation, err)
+		}
+		if w, h := decoded.Bounds().Dx(), decoded.Bounds().Dy(); w != tt.width || h != tt.height {
+			t.Errorf("orientation %d: decoded %dx%d, want %dx%d", tt.orientation, w, h, tt.width, tt.height)
+		}
+	}
+}
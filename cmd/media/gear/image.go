@@ -0,0 +1,169 @@
+package gear
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// MaxPixels bounds the decoded size of an upload, so a small file cannot
+	// claim gigabytes of memory.
+	MaxPixels   = 40_000_000
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported image type")
+	ErrTooLarge    = errors.New("image dimensions too large")
+)
+
+// Types maps the accepted sniffed content types to the type variants are
+// stored as. GIFs keep only their first frame and become PNGs.
+var Types = map[string]string{
+	"image/jpeg": "image/jpeg",
+	"image/png":  "image/png",
+	"image/gif":  "image/png",
+}
+
+// Extensions maps stored content types to file extensions.
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// Variant is a size an upload is stored in, fitting within Size×Size pixels.
+// A Size of 0 keeps the original dimensions.
+type Variant struct {
+	Name string
+	Size int
+}
+
+const VariantOriginal = "original"
+
+// Variants lists the stored sizes, largest first.
+var Variants = []Variant{
+	{Name: VariantOriginal},
+	{Name: "large", Size: 1600},
+	{Name: "medium", Size: 800},
+	{Name: "thumb", Size: 320},
+}
+
+// Decode reads an image of contentType, checking its dimensions before
+// decoding the pixels. JPEGs are turned upright by their EXIF orientation, as
+// the EXIF data itself does not survive re-encoding.
+func Decode(data []byte, contentType string) (*image.NRGBA, error) {
+	var decodeConfig func([]byte) (image.Config, error)
+	var decode func([]byte) (image.Image, error)
+	switch contentType {
+	case "image/jpeg":
+		decodeConfig = func(b []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(b)) }
+		decode = func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }
+	case "image/png":
+		decodeConfig = func(b []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(b)) }
+		decode = func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) }
+	case "image/gif":
+		decodeConfig = func(b []byte) (image.Config, error) { return gif.DecodeConfig(bytes.NewReader(b)) }
+		decode = func(b []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(b)) }
+	default:
+		return nil, ErrUnsupported
+	}
+
+	config, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	if contentType == "image/jpeg" {
+		dst = Orient(dst, Orientation(data))
+	}
+	return dst, nil
+}
+
+// Encode writes img as contentType, which carries no metadata.
+func Encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Fit returns img scaled down to fit within size×size pixels. Images already
+// small enough, and a size of 0, are returned as they are.
+func Fit(img *image.NRGBA, size int) *image.NRGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if size == 0 || (w <= size && h <= size) {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*size/w)
+		w = size
+	} else {
+		w = max(1, w*size/h)
+		h = size
+	}
+	return resize(img, w, h)
+}
+
+// resize shrinks src to w×h by averaging the source pixels under each target
+// pixel, weighting colour by alpha so transparent pixels do not darken edges.
+func resize(src *image.NRGBA, w, h int) *image.NRGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					pa := uint64(p[3])
+					r += uint64(p[0]) * pa
+					g += uint64(p[1]) * pa
+					b += uint64(p[2]) * pa
+					a += pa
+					n++
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4:]
+			if a > 0 {
+				d[0] = uint8(r / a)
+				d[1] = uint8(g / a)
+				d[2] = uint8(b / a)
+			}
+			d[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,66 @@
+package gear
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		size          int
+		wantW, wantH  int
+	}{
+		{name: "wide", width: 400, height: 200, size: 100, wantW: 100, wantH: 50},
+		{name: "tall", width: 200, height: 400, size: 100, wantW: 50, wantH: 100},
+		{name: "square", width: 300, height: 300, size: 100, wantW: 100, wantH: 100},
+		{name: "thin", width: 1000, height: 1, size: 100, wantW: 100, wantH: 1},
+		{name: "small enough", width: 80, height: 60, size: 100, wantW: 80, wantH: 60},
+		{name: "original", width: 4000, height: 3000, size: 0, wantW: 4000, wantH: 3000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			got := Fit(img, tt.size)
+			if w, h := got.Bounds().Dx(), got.Bounds().Dy(); w != tt.wantW || h != tt.wantH {
+				t.Errorf("Fit = %dx%d, want %dx%d", w, h, tt.wantW, tt.wantH)
+			}
+			if tt.wantW == tt.width && tt.wantH == tt.height && got != img {
+				t.Error("Fit copied an image that already fits")
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	// left half opaque red and white, right half blue with one transparent
+	// pixel
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	src.SetNRGBA(1, 0, color.NRGBA{R: 255, A: 255})
+	src.SetNRGBA(0, 1, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	src.SetNRGBA(1, 1, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	src.SetNRGBA(2, 0, color.NRGBA{B: 255, A: 255})
+	src.SetNRGBA(3, 0, color.NRGBA{B: 255, A: 255})
+	src.SetNRGBA(2, 1, color.NRGBA{B: 255, A: 255})
+	src.SetNRGBA(3, 1, color.NRGBA{})
+
+	dst := resize(src, 2, 1)
+	if w, h := dst.Bounds().Dx(), dst.Bounds().Dy(); w != 2 || h != 1 {
+		t.Fatalf("resize = %dx%d, want 2x1", w, h)
+	}
+	if got, want := dst.NRGBAAt(0, 0), (color.NRGBA{R: 255, G: 127, B: 127, A: 255}); got != want {
+		t.Errorf("left pixel = %v, want %v", got, want)
+	}
+	// the transparent pixel lowers the alpha but does not darken the blue
+	if got, want := dst.NRGBAAt(1, 0), (color.NRGBA{B: 255, A: 191}); got != want {
+		t.Errorf("right pixel = %v, want %v", got, want)
+	}
+
+	empty := resize(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 2, 2)
+	if got := empty.NRGBAAt(1, 1); got != (color.NRGBA{}) {
+		t.Errorf("transparent image resized to %v", got)
+	}
+}
@@ -0,0 +1,465 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"dietku-backend/cmd/auth/gear"
+	blogRepo "dietku-backend/cmd/blog/repo"
+	"dietku-backend/cmd/event"
+	"dietku-backend/cmd/log"
+	mediaGear "dietku-backend/cmd/media/gear"
+	"dietku-backend/cmd/media/repo"
+	"dietku-backend/cmd/storage"
+	"dietku-backend/cmd/worker"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxMediaSize    = 10 << 20
+	maxFilename     = 200
+	defaultPageSize = 30
+	maxPageSize     = 100
+	maxPage         = 1000
+
+	collectorName     = "media-collector"
+	collectorInterval = time.Hour
+	// collectGrace keeps the images of posts saved within it, so a save that
+	// is still attaching them does not race the collector.
+	collectGrace = time.Hour
+	// collectBatch is how many media one lookup of the posts checks.
+	collectBatch = 100
+)
+
+type MediaHandler struct {
+	repo         *repo.MediaRepository
+	blogRepo     *blogRepo.BlogRepository
+	revisionRepo *blogRepo.RevisionRepository
+	storage      storage.Storage
+	clock        worker.Clock
+}
+
+func NewMediaApi(e *echo.Echo, db *mongo.Database, store storage.Storage) *MediaHandler {
+	m := &MediaHandler{
+		repo:         repo.NewMediaRepository(db),
+		blogRepo:     blogRepo.NewBlogRepository(db),
+		revisionRepo: blogRepo.NewRevisionRepository(db),
+		storage:      store,
+		clock:        worker.RealClock{},
+	}
+	if err := m.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create media indexes: %v", err)
+	}
+
+	event.Subscribe(event.BlogDeleted, m.onBlogDeleted)
+
+	w := &worker.Worker{
+		Name:     collectorName,
+		Interval: collectorInterval,
+		Clock:    m.clock,
+		Lock:     worker.NewLock(db),
+		Job:      m.CollectOrphans,
+	}
+	w.Start(context.Background())
+
+	// files are embedded in public posts with <img>, so they are served to
+	// anyone who has the link
+	e.GET("/api/media/:id/:variant", m.File)
+
+	mGroup := e.Group("")
+	mGroup.Use(gear.IsLoggedIn(db))
+	{
+		mGroup.GET("/api/media", m.Library)
+
+		mGroup.POST("/api/media", m.Upload)
+
+		mGroup.DELETE("/api/media/:id", m.Delete)
+	}
+	return m
+}
+
+type MediaView struct {
+	repo.Media
+	// URLs maps every variant name to the path it is served at.
+	URLs map[string]string `json:"urls"`
+}
+
+func mediaView(m repo.Media) MediaView {
+	urls := map[string]string{}
+	for _, v := range mediaGear.Variants {
+		if m.Variant(v.Name) != nil {
+			urls[v.Name] = repo.PathPrefix(m.ID) + v.Name
+		}
+	}
+	return MediaView{Media: m, URLs: urls}
+}
+
+type MediaPage struct {
+	Items []MediaView `json:"items"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+	Total int64       `json:"total"`
+}
+
+// Library
+// @Tags Media
+// @Summary List My Media
+// @Description The signed in user's uploads, newest first.
+// @ID media-list
+// @Router /api/media [get]
+// @Param page query int false "Page, starting at 1"
+// @Param limit query int false "Page size (default 30, max 100)"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *MediaHandler) Library(c echo.Context) error {
+	page, err := gear.QueryInt(c, "page", 1, maxPage)
+	if err != nil {
+		return err
+	}
+	limit, err := gear.QueryInt(c, "limit", defaultPageSize, maxPageSize)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+	docs, err := h.repo.FindByUser(tokenData.ID, int64(page-1)*int64(limit), int64(limit))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting media.", c)
+	}
+	total, err := h.repo.Count(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting media.", c)
+	}
+
+	items := make([]MediaView, len(*docs))
+	for i, m := range *docs {
+		items[i] = mediaView(m)
+	}
+	return c.JSON(http.StatusOK, &MediaPage{Items: items, Page: page, Limit: limit, Total: total})
+}
+
+// Upload
+// @Tags Media
+// @Summary Upload Media
+// @Description JPEG, PNG or GIF up to 10 MB and 40 megapixels; the type is detected from the content. The image is turned upright, stripped of its metadata and stored in the original size plus large (1600px), medium (800px) and thumb (320px) variants where smaller than the original. GIFs keep their first frame only.
+// @ID media-upload
+// @Router /api/media [post]
+// @Accept multipart/form-data
+// @Param file formData file true "image file"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *MediaHandler) Upload(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "File is required.", c)
+	}
+	if file.Size > maxMediaSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "File must not exceed 10 MB.", c)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file.", c)
+	}
+	defer src.Close()
+
+	// read one byte past the limit to catch files lying about their size
+	data, err := io.ReadAll(io.LimitReader(src, maxMediaSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file.", c)
+	}
+	if len(data) > maxMediaSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "File must not exceed 10 MB.", c)
+	}
+
+	sniffed := http.DetectContentType(data)
+	contentType, ok := mediaGear.Types[sniffed]
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "File must be a JPEG, PNG or GIF image.", c)
+	}
+
+	img, err := mediaGear.Decode(data, sniffed)
+	if err != nil {
+		if errors.Is(err, mediaGear.ErrTooLarge) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Image must not exceed 40 megapixels.", c)
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid image file.", c)
+	}
+
+	m := repo.Media{
+		ID:        primitive.NewObjectID(),
+		UserID:    tokenData.ID,
+		Filename:  filename(file.Filename),
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		Variants:  []repo.Variant{},
+		CreatedAt: h.clock.Now(),
+	}
+
+	ctx := c.Request().Context()
+	for _, v := range mediaGear.Variants {
+		if v.Size > 0 && m.Width <= v.Size && m.Height <= v.Size {
+			continue
+		}
+
+		scaled := mediaGear.Fit(img, v.Size)
+		encoded, err := mediaGear.Encode(scaled, contentType)
+		if err != nil {
+			h.remove(&m)
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while processing image.", c)
+		}
+
+		variant := repo.Variant{
+			Name:        v.Name,
+			Key:         "media/" + tokenData.ID.Hex() + "/" + m.ID.Hex() + "/" + v.Name + mediaGear.Extensions[contentType],
+			ContentType: contentType,
+			Width:       scaled.Bounds().Dx(),
+			Height:      scaled.Bounds().Dy(),
+			Size:        int64(len(encoded)),
+		}
+		if err := h.storage.Put(ctx, variant.Key, bytes.NewReader(encoded), contentType); err != nil {
+			log.Errorf("failed to store media %s: %v", variant.Key, err)
+			h.remove(&m)
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while storing media.", c)
+		}
+		m.Variants = append(m.Variants, variant)
+	}
+
+	_, err = h.repo.InsertOne(&m)
+	if err != nil {
+		h.remove(&m)
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while saving media.", c)
+	}
+	return c.JSON(http.StatusOK, mediaView(m))
+}
+
+// filename keeps the base name of an uploaded file for display; browsers on
+// Windows may send the whole path.
+func filename(name string) string {
+	name = strings.TrimSpace(name[strings.LastIndexAny(name, "/\\")+1:])
+	if utf8.RuneCountInString(name) > maxFilename {
+		name = string([]rune(name)[:maxFilename])
+	}
+	return name
+}
+
+// File
+// @Tags Media
+// @Summary Get Media File
+// @Description Serves one variant of an image: original, large, medium or thumb. Variants larger than the upload fall back to the original.
+// @ID media-file
+// @Router /api/media/{id}/{variant} [get]
+// @Param id path string true "Media ID"
+// @Param variant path string true "original, large, medium or thumb"
+// @Produce image/jpeg,image/png
+// @Success 200
+func (h *MediaHandler) File(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid media id", c)
+	}
+
+	known := false
+	for _, v := range mediaGear.Variants {
+		known = known || v.Name == c.Param("variant")
+	}
+	if !known {
+		return echo.NewHTTPError(http.StatusNotFound, "Media not found!", c)
+	}
+
+	m, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Media not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting media.", c)
+	}
+	variant := m.Variant(c.Param("variant"))
+	if variant == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Media not found!", c)
+	}
+
+	file, err := h.storage.Get(c.Request().Context(), variant.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Media not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while reading media.", c)
+	}
+	defer file.Close()
+
+	// a media id always names the same bytes
+	c.Response().Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Stream(http.StatusOK, variant.ContentType, file)
+}
+
+// Delete
+// @Tags Media
+// @Summary Delete Media
+// @Description Removes an image from the library with its files. Images still used by a post cannot be deleted.
+// @ID media-delete
+// @Router /api/media/{id} [delete]
+// @Param id path string true "Media ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *MediaHandler) Delete(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid media id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	m, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Media not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting media.", c)
+	}
+	if m.UserID != tokenData.ID {
+		return echo.NewHTTPError(http.StatusBadRequest, "Media not found!", c)
+	}
+
+	used, err := h.blogRepo.UsesMedia(m.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting media.", c)
+	}
+	if used {
+		return echo.NewHTTPError(http.StatusBadRequest, "Media is used by a post", c)
+	}
+
+	docs, err := h.repo.DeleteOne(m.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting media.", c)
+	}
+	h.remove(m)
+	return c.JSON(http.StatusOK, mediaView(*docs))
+}
+
+// remove deletes the stored files of m, logging failures.
+func (h *MediaHandler) remove(m *repo.Media) {
+	for _, v := range m.Variants {
+		if err := h.storage.Delete(context.Background(), v.Key); err != nil {
+			log.Errorf("failed to remove media %s: %v", v.Key, err)
+		}
+	}
+}
+
+func (h *MediaHandler) onBlogDeleted(e event.Event) error {
+	return h.collect(&e.UserID, h.clock.Now())
+}
+
+// CollectOrphans removes the media that were used by posts and are not used
+// by any post anymore.
+func (h *MediaHandler) CollectOrphans(now time.Time) error {
+	return h.collect(nil, now)
+}
+
+// collect removes orphaned media of one user, or of everyone when userID is
+// nil. It walks the media attached before the grace period in batches, looking
+// each batch up in the posts at once.
+func (h *MediaHandler) collect(userID *primitive.ObjectID, now time.Time) error {
+	before := now.Add(-collectGrace)
+	after := primitive.NilObjectID
+	for {
+		batch, err := h.repo.FindAttached(userID, before, after, collectBatch)
+		if err != nil {
+			return err
+		}
+		if len(*batch) == 0 {
+			return nil
+		}
+		after = (*batch)[len(*batch)-1].ID
+
+		used, err := h.used(*batch)
+		if err != nil {
+			return err
+		}
+		for i := range *batch {
+			m := &(*batch)[i]
+			if used[m.ID] {
+				continue
+			}
+
+			// a post may have picked the image up since the lookup, which
+			// attaches it again
+			if _, err := h.repo.DeleteDetached(m.ID, before); err != nil {
+				if errors.Is(err, mongo.ErrNoDocuments) {
+					continue
+				}
+				return err
+			}
+			h.remove(m)
+			log.Infof("collected orphaned media %s", m.ID.Hex())
+		}
+
+		if len(*batch) < collectBatch {
+			return nil
+		}
+	}
+}
+
+// used tells which of list posts that are not deleted use, themselves or in
+// one of their revisions, so that restoring the revision brings the image
+// back.
+func (h *MediaHandler) used(list repo.MediaList) (map[primitive.ObjectID]bool, error) {
+	ids := make([]primitive.ObjectID, len(list))
+	for i, m := range list {
+		ids[i] = m.ID
+	}
+	used, err := h.blogRepo.UsedMedia(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := []primitive.ObjectID{}
+	for _, id := range ids {
+		if !used[id] {
+			rest = append(rest, id)
+		}
+	}
+	revised, err := h.revisionRepo.UsedMedia(rest)
+	if err != nil {
+		return nil, err
+	}
+	if len(revised) == 0 {
+		return used, nil
+	}
+
+	blogIDs := []primitive.ObjectID{}
+	for _, ids := range revised {
+		blogIDs = append(blogIDs, ids...)
+	}
+	// revisions of deleted blogs can no longer be restored
+	blogs, err := h.blogRepo.FindByIDs(blogIDs)
+	if err != nil {
+		return nil, err
+	}
+	live := map[primitive.ObjectID]bool{}
+	for _, b := range *blogs {
+		live[b.ID] = true
+	}
+	for id, blogIDs := range revised {
+		for _, blogID := range blogIDs {
+			if live[blogID] {
+				used[id] = true
+				break
+			}
+		}
+	}
+	return used, nil
+}
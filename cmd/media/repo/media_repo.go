@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Variant is one stored size of a media file.
+type Variant struct {
+	Name        string `json:"name" bson:"name"`
+	Key         string `json:"-" bson:"key"`
+	ContentType string `json:"contentType" bson:"contentType"`
+	Width       int    `json:"width" bson:"width"`
+	Height      int    `json:"height" bson:"height"`
+	Size        int64  `json:"size" bson:"size"`
+}
+
+// Media is an image in a user's library. AttachedAt is set when a blog refers
+// to it; from then on it is removed when no blog refers to it anymore.
+type Media struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID     primitive.ObjectID `json:"userId" bson:"userId"`
+	Filename   string             `json:"filename" bson:"filename"`
+	Width      int                `json:"width" bson:"width"`
+	Height     int                `json:"height" bson:"height"`
+	Variants   []Variant          `json:"variants" bson:"variants"`
+	AttachedAt *time.Time         `json:"attachedAt,omitempty" bson:"attachedAt,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	IsDeleted  bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+// Variant returns the variant called name, the original when there is none by
+// that name because the upload was smaller.
+func (m *Media) Variant(name string) *Variant {
+	for i := range m.Variants {
+		if m.Variants[i].Name == name {
+			return &m.Variants[i]
+		}
+	}
+	if len(m.Variants) > 0 {
+		return &m.Variants[0]
+	}
+	return nil
+}
+
+// PathPrefix is the path the files of a media are served under, each at its
+// variant's name. Posts may also refer to an image by such a path.
+func PathPrefix(id primitive.ObjectID) string {
+	return "/api/media/" + id.Hex() + "/"
+}
+
+type MediaList []Media
+
+func DecodeAsMediaList(cursor *mongo.Cursor) (*MediaList, error) {
+	docs := MediaList{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type MediaRepository struct {
+	coll *mongo.Collection
+}
+
+func NewMediaRepository(db *mongo.Database) *MediaRepository {
+	return &MediaRepository{
+		coll: db.Collection("media"),
+	}
+}
+
+func (r *MediaRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	return err
+}
+
+// FindByUser returns a page of the user's library, newest first.
+func (r *MediaRepository) FindByUser(userID primitive.ObjectID, skip int64, limit int64) (*MediaList, error) {
+	filter := bson.M{"userId": userID, "isDeleted": bson.M{"$ne": true}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsMediaList(cursor)
+}
+
+func (r *MediaRepository) Count(userID primitive.ObjectID) (int64, error) {
+	return r.coll.CountDocuments(context.TODO(), bson.M{"userId": userID, "isDeleted": bson.M{"$ne": true}})
+}
+
+func (r *MediaRepository) FindOne(id primitive.ObjectID) (*Media, error) {
+	var d = &Media{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// CountOwned counts how many of ids are media of the user.
+func (r *MediaRepository) CountOwned(userID primitive.ObjectID, ids []primitive.ObjectID) (int64, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}, "userId": userID, "isDeleted": bson.M{"$ne": true}}
+	return r.coll.CountDocuments(context.TODO(), filter)
+}
+
+// FindOwnedIDs returns which of ids are still media of the user.
+func (r *MediaRepository) FindOwnedIDs(userID primitive.ObjectID, ids []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}, "userId": userID, "isDeleted": bson.M{"$ne": true}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := DecodeAsMediaList(cursor)
+	if err != nil {
+		return nil, err
+	}
+	owned := make([]primitive.ObjectID, len(*docs))
+	for i, m := range *docs {
+		owned[i] = m.ID
+	}
+	return owned, nil
+}
+
+// Attach marks media as used by a blog, which makes them subject to garbage
+// collection once no blog uses them. It is called on every save, so
+// AttachedAt tells when a blog last took them up.
+func (r *MediaRepository) Attach(ids []primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	_, err := r.coll.UpdateMany(context.TODO(), filter, bson.M{"$set": bson.M{"attachedAt": at}})
+	return err
+}
+
+// FindAttached returns up to limit media last attached to a blog at or before
+// before, of one user or of everyone when userID is nil, in id order from
+// after on. These are the candidates for garbage collection.
+func (r *MediaRepository) FindAttached(userID *primitive.ObjectID, before time.Time, after primitive.ObjectID, limit int64) (*MediaList, error) {
+	filter := bson.M{
+		"_id":        bson.M{"$gt": after},
+		"attachedAt": bson.M{"$lte": before},
+		"isDeleted":  bson.M{"$ne": true},
+	}
+	if userID != nil {
+		filter["userId"] = *userID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsMediaList(cursor)
+}
+
+func (r *MediaRepository) InsertOne(newMedia *Media) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newMedia)
+}
+
+func (r *MediaRepository) DeleteOne(id primitive.ObjectID) (*Media, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Media{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeleteDetached deletes the media unless a blog attached it after before,
+// returning mongo.ErrNoDocuments when one did.
+func (r *MediaRepository) DeleteDetached(id primitive.ObjectID, before time.Time) (*Media, error) {
+	filter := bson.M{"_id": id, "attachedAt": bson.M{"$lte": before}, "isDeleted": bson.M{"$ne": true}}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Media{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
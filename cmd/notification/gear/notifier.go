@@ -0,0 +1,41 @@
+package gear
+
+import (
+	"dietku-backend/cmd/notification/repo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// Notifier is how other packages tell a user something, e.g.
+//
+//	notifier.Notify(post.CreatedBy.ID, repo.TypeComment, "New comment", text,
+//		map[string]interface{}{"blogId": post.ID})
+type Notifier struct {
+	repo *repo.NotificationRepository
+}
+
+func NewNotifier(db *mongo.Database) *Notifier {
+	return &Notifier{
+		repo: repo.NewNotificationRepository(db),
+	}
+}
+
+// Notify stores a new unread notification for userID.
+func (n *Notifier) Notify(userID primitive.ObjectID, typ string, title string, body string, payload map[string]interface{}) (*repo.Notification, error) {
+	notification := &repo.Notification{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Type:      typ,
+		Title:     title,
+		Body:      body,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := n.repo.InsertOne(notification)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
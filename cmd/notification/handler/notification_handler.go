@@ -0,0 +1,188 @@
+package handler
+
+import (
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/log"
+	"dietku-backend/cmd/notification/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxPage         = 1000
+)
+
+type NotificationHandler struct {
+	repo *repo.NotificationRepository
+}
+
+func NewNotificationApi(e *echo.Echo, db *mongo.Database) *NotificationHandler {
+	n := &NotificationHandler{
+		repo: repo.NewNotificationRepository(db),
+	}
+	if err := n.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create notification indexes: %v", err)
+	}
+
+	nGroup := e.Group("")
+	nGroup.Use(authGear.IsLoggedIn(db))
+	{
+		nGroup.GET("/api/notifications", n.List)
+
+		nGroup.POST("/api/notifications/read", n.MarkAllRead)
+		nGroup.POST("/api/notifications/:id/read", n.MarkRead)
+
+		nGroup.DELETE("/api/notifications/:id", n.Delete)
+	}
+	return n
+}
+
+type NotificationPage struct {
+	Items  repo.Notifications `json:"items"`
+	Page   int                `json:"page"`
+	Limit  int                `json:"limit"`
+	Total  int64              `json:"total"`
+	Unread int64              `json:"unread"`
+}
+
+// List
+// @Tags Notification
+// @Summary List Notifications
+// @Description Newest first, with the number of unread notifications.
+// @ID notification-list
+// @Router /api/notifications [get]
+// @Param page query int false "Page, starting at 1"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param unread query bool false "Only unread notifications"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *NotificationHandler) List(c echo.Context) error {
+	page, err := authGear.QueryInt(c, "page", 1, maxPage)
+	if err != nil {
+		return err
+	}
+	limit, err := authGear.QueryInt(c, "limit", defaultPageSize, maxPageSize)
+	if err != nil {
+		return err
+	}
+	unreadOnly := c.QueryParam("unread") == "true"
+
+	tokenData := c.Get("me").(*authGear.UserClaims)
+
+	docs, err := h.repo.FindByUser(tokenData.ID, unreadOnly, int64(page-1)*int64(limit), int64(limit))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting notifications.", c)
+	}
+
+	total, err := h.repo.Count(tokenData.ID, unreadOnly)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting notifications.", c)
+	}
+
+	unread := total
+	if !unreadOnly {
+		unread, err = h.repo.Count(tokenData.ID, true)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting notifications.", c)
+		}
+	}
+
+	return c.JSON(http.StatusOK, &NotificationPage{
+		Items:  *docs,
+		Page:   page,
+		Limit:  limit,
+		Total:  total,
+		Unread: unread,
+	})
+}
+
+// MarkRead
+// @Tags Notification
+// @Summary Mark Notification Read
+// @ID notification-read
+// @Router /api/notifications/{id}/read [post]
+// @Param id path string true "Notification ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *NotificationHandler) MarkRead(c echo.Context) error {
+	tokenData := c.Get("me").(*authGear.UserClaims)
+	notification, err := h.findNotification(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	if notification.Read {
+		return c.JSON(http.StatusOK, notification)
+	}
+
+	result, err := h.repo.MarkRead(notification.ID, time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating notification.", c)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// MarkAllRead
+// @Tags Notification
+// @Summary Mark All Notifications Read
+// @ID notification-read-all
+// @Router /api/notifications/read [post]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *NotificationHandler) MarkAllRead(c echo.Context) error {
+	tokenData := c.Get("me").(*authGear.UserClaims)
+
+	updated, err := h.repo.MarkAllRead(tokenData.ID, time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating notifications.", c)
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"updated": updated})
+}
+
+// Delete
+// @Tags Notification
+// @Summary Delete Notification
+// @ID notification-delete
+// @Router /api/notifications/{id} [delete]
+// @Param id path string true "Notification ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *NotificationHandler) Delete(c echo.Context) error {
+	tokenData := c.Get("me").(*authGear.UserClaims)
+	notification, err := h.findNotification(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.repo.DeleteOne(notification.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting notification.", c)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *NotificationHandler) findNotification(c echo.Context, tokenData *authGear.UserClaims) (*repo.Notification, error) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid notification id", c)
+	}
+
+	notification, err := h.repo.FindOne(id, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Notification not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting notification.", c)
+	}
+	return notification, nil
+}
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	TypeWelcome     = "welcome"
+	TypeReminder    = "reminder"
+	TypeAchievement = "achievement"
+	TypeComment     = "comment"
+)
+
+// Notification is a message to one user. Payload carries what a client needs
+// to act on it, e.g. the blog and comment ids of a TypeComment notification.
+type Notification struct {
+	ID        primitive.ObjectID     `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID     `json:"userId" bson:"userId"`
+	Type      string                 `json:"type" bson:"type"`
+	Title     string                 `json:"title" bson:"title"`
+	Body      string                 `json:"body,omitempty" bson:"body,omitempty"`
+	Payload   map[string]interface{} `json:"payload,omitempty" bson:"payload,omitempty"`
+	Read      bool                   `json:"read" bson:"read"`
+	ReadAt    *time.Time             `json:"readAt,omitempty" bson:"readAt,omitempty"`
+	CreatedAt time.Time              `json:"createdAt" bson:"createdAt"`
+	IsDeleted bool                   `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Notifications []Notification
+
+func DecodeAsNotifications(cursor *mongo.Cursor) (*Notifications, error) {
+	docs := Notifications{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type NotificationRepository struct {
+	coll *mongo.Collection
+}
+
+func NewNotificationRepository(db *mongo.Database) *NotificationRepository {
+	return &NotificationRepository{
+		coll: db.Collection("notifications"),
+	}
+}
+
+func (r *NotificationRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"read": false, "isDeleted": false}),
+		},
+	})
+	return err
+}
+
+// userFilter matches the user's notifications. Unread ones are matched with
+// the partial filter of the unread index, which Mongo only uses for queries
+// containing it; isDeleted is always written, so false is the same as $ne true.
+func userFilter(userID primitive.ObjectID, unreadOnly bool) bson.M {
+	filter := bson.M{"userId": userID, "isDeleted": bson.M{"$ne": true}}
+	if unreadOnly {
+		filter["read"] = false
+		filter["isDeleted"] = false
+	}
+	return filter
+}
+
+// FindByUser returns a page of the user's notifications, newest first.
+func (r *NotificationRepository) FindByUser(userID primitive.ObjectID, unreadOnly bool, skip int64, limit int64) (*Notifications, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), userFilter(userID, unreadOnly), opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsNotifications(cursor)
+}
+
+func (r *NotificationRepository) Count(userID primitive.ObjectID, unreadOnly bool) (int64, error) {
+	return r.coll.CountDocuments(context.TODO(), userFilter(userID, unreadOnly))
+}
+
+func (r *NotificationRepository) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*Notification, error) {
+	var d = &Notification{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *NotificationRepository) InsertOne(newNotification *Notification) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newNotification)
+}
+
+func (r *NotificationRepository) MarkRead(id primitive.ObjectID, at time.Time) (*Notification, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"read": true, "readAt": at},
+	}
+
+	var d = &Notification{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns
+// how many changed.
+func (r *NotificationRepository) MarkAllRead(userID primitive.ObjectID, at time.Time) (int64, error) {
+	update := bson.M{
+		"$set": bson.M{"read": true, "readAt": at},
+	}
+
+	result, err := r.coll.UpdateMany(context.TODO(), userFilter(userID, true), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *NotificationRepository) DeleteOne(id primitive.ObjectID) (*Notification, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Notification{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,247 @@
+package gear
+
+import (
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/planner/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// CalorieTolerance is the allowed relative deviation of a day's calories.
+	CalorieTolerance = 0.10
+	// MacroTolerance is the allowed deviation of each macro's share of calories,
+	// in percentage points.
+	MacroTolerance = 5.0
+	// RepeatGap is the number of days before an item may be planned again.
+	RepeatGap = 3
+
+	attemptsPerDay = 40
+	topChoices     = 3
+)
+
+// ErrNoCandidates is returned when none of the candidates can fill a meal.
+var ErrNoCandidates = errors.New("no candidate fits the plan")
+
+// Candidate is a food or recipe the generator may put on the plan.
+type Candidate struct {
+	Kind         string
+	RefID        primitive.ObjectID
+	Name         string
+	ServingGrams float64
+	PerServing   foodRepo.Nutrients
+}
+
+// slot is a meal with its share of the daily calories.
+type slot struct {
+	meal  string
+	share float64
+	main  bool
+}
+
+var slots = []slot{
+	{meal: "breakfast", share: 0.25, main: true},
+	{meal: "lunch", share: 0.35, main: true},
+	{meal: "dinner", share: 0.30, main: true},
+	{meal: "snacks", share: 0.10},
+}
+
+var servingSteps = []float64{0.5, 1, 1.5, 2}
+
+type choice struct {
+	index    int
+	servings float64
+	score    float64
+}
+
+type generator struct {
+	rng        *rand.Rand
+	candidates []Candidate
+	target     userRepo.NutritionTarget
+	lastUsed   map[primitive.ObjectID]int
+}
+
+// Generate builds a plan of days days. Candidates must be passed in a stable
+// order: together with seed they fully determine the result, so the same
+// request always yields the same plan.
+func Generate(candidates []Candidate, target userRepo.NutritionTarget, days int, seed int64) ([]repo.PlanDay, error) {
+	usable := false
+	for _, cand := range candidates {
+		if cand.PerServing.Calories > 0 {
+			usable = true
+			break
+		}
+	}
+	if !usable || target.Calories <= 0 {
+		return nil, ErrNoCandidates
+	}
+
+	g := &generator{
+		rng:        rand.New(rand.NewSource(seed)),
+		candidates: candidates,
+		target:     target,
+		lastUsed:   map[primitive.ObjectID]int{},
+	}
+
+	plan := make([]repo.PlanDay, 0, days)
+	for day := 1; day <= days; day++ {
+		var best repo.PlanDay
+		bestScore := math.Inf(1)
+		for attempt := 0; attempt < attemptsPerDay; attempt++ {
+			candidate := g.day(day)
+			score := g.score(candidate.Nutrients, target.Calories)
+			if score < bestScore {
+				best, bestScore = candidate, score
+			}
+			if candidate.WithinTolerance {
+				break
+			}
+		}
+
+		for _, m := range best.Meals {
+			for _, item := range m.Items {
+				g.lastUsed[item.RefID] = day
+			}
+		}
+		plan = append(plan, best)
+	}
+	return plan, nil
+}
+
+func (g *generator) day(day int) repo.PlanDay {
+	d := repo.PlanDay{Day: day, Meals: []repo.PlanMeal{}}
+	usedToday := map[int]bool{}
+	eaten := 0.0
+	shareLeft := 1.0
+
+	for _, s := range slots {
+		// aim each meal at its share of what is left, so earlier misses are
+		// made up for later in the day
+		slotCalories := (g.target.Calories - eaten) * s.share / shareLeft
+		shareLeft -= s.share
+
+		c, ok := g.pick(s, slotCalories, day, usedToday)
+		if !ok {
+			continue
+		}
+		usedToday[c.index] = true
+
+		item := toItem(g.candidates[c.index], c.servings)
+		eaten += item.Nutrients.Calories
+		d.Meals = append(d.Meals, repo.PlanMeal{
+			Meal:  s.meal,
+			Items: []repo.PlanItem{item},
+		})
+	}
+	d.Total()
+	d.WithinTolerance = WithinTolerance(d.Nutrients, g.target)
+	return d
+}
+
+// pick ranks every allowed candidate and serving size for the slot and picks
+// one of the best few at random, which keeps plans varied but close to target.
+func (g *generator) pick(s slot, slotCalories float64, day int, usedToday map[int]bool) (choice, bool) {
+	rank := func(recipesOnly bool, spaced bool) []choice {
+		choices := []choice{}
+		for i, cand := range g.candidates {
+			if usedToday[i] || cand.PerServing.Calories <= 0 {
+				continue
+			}
+			// recipes make proper meals, plain foods are reserved for snacks
+			// unless there are no recipes to choose from
+			if recipesOnly && s.main && cand.Kind != repo.KindRecipe {
+				continue
+			}
+			if last, ok := g.lastUsed[cand.RefID]; spaced && ok && day-last < RepeatGap {
+				continue
+			}
+			for _, servings := range servingSteps {
+				choices = append(choices, choice{
+					index:    i,
+					servings: servings,
+					score:    g.score(cand.PerServing.Scale(servings), slotCalories),
+				})
+			}
+		}
+		return choices
+	}
+
+	// a food at a main meal is better than repeating a recipe too soon, a
+	// repeat is only allowed when nothing else is left
+	choices := rank(true, true)
+	if len(choices) == 0 {
+		choices = rank(false, true)
+	}
+	if len(choices) == 0 {
+		choices = rank(false, false)
+	}
+	if len(choices) == 0 {
+		return choice{}, false
+	}
+
+	sort.SliceStable(choices, func(i, j int) bool {
+		return choices[i].score < choices[j].score
+	})
+
+	// only one serving size per candidate may compete for the top spots
+	top := []choice{}
+	seen := map[int]bool{}
+	for _, c := range choices {
+		if seen[c.index] {
+			continue
+		}
+		seen[c.index] = true
+		top = append(top, c)
+		if len(top) == topChoices {
+			break
+		}
+	}
+	return top[g.rng.Intn(len(top))], true
+}
+
+// score is the relative calorie error plus the macro split error, lower is
+// better.
+func (g *generator) score(n foodRepo.Nutrients, calories float64) float64 {
+	if calories <= 0 {
+		return math.Inf(1)
+	}
+	p, c, f := MacroShares(n)
+	macro := math.Abs(p-g.target.ProteinPercent) + math.Abs(c-g.target.CarbohydratePercent) + math.Abs(f-g.target.FatPercent)
+	return math.Abs(n.Calories-calories)/calories + macro/100
+}
+
+// MacroShares returns the percentage of calories from protein, carbohydrate
+// and fat.
+func MacroShares(n foodRepo.Nutrients) (protein float64, carbohydrate float64, fat float64) {
+	if n.Calories <= 0 {
+		return 0, 0, 0
+	}
+	return n.Protein * 4 / n.Calories * 100, n.Carbohydrate * 4 / n.Calories * 100, n.Fat * 9 / n.Calories * 100
+}
+
+// WithinTolerance reports whether a day's totals meet the calorie budget and
+// macro split within CalorieTolerance and MacroTolerance.
+func WithinTolerance(n foodRepo.Nutrients, target userRepo.NutritionTarget) bool {
+	if target.Calories <= 0 || math.Abs(n.Calories-target.Calories)/target.Calories > CalorieTolerance {
+		return false
+	}
+	p, c, f := MacroShares(n)
+	return math.Abs(p-target.ProteinPercent) <= MacroTolerance &&
+		math.Abs(c-target.CarbohydratePercent) <= MacroTolerance &&
+		math.Abs(f-target.FatPercent) <= MacroTolerance
+}
+
+func toItem(cand Candidate, servings float64) repo.PlanItem {
+	return repo.PlanItem{
+		Kind:         cand.Kind,
+		RefID:        cand.RefID,
+		Name:         cand.Name,
+		Servings:     servings,
+		ServingGrams: cand.ServingGrams,
+		Nutrients:    cand.PerServing.Scale(servings),
+	}
+}
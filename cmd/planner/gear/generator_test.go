@@ -0,0 +1,159 @@
+package gear
+
+import (
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/planner/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var testTarget = userRepo.NutritionTarget{
+	Calories:            2000,
+	ProteinPercent:      25,
+	CarbohydratePercent: 50,
+	FatPercent:          25,
+}
+
+// balanced returns a serving with calories split exactly like testTarget.
+func balanced(calories float64) foodRepo.Nutrients {
+	return foodRepo.Nutrients{
+		Calories:     calories,
+		Protein:      calories * 0.25 / 4,
+		Carbohydrate: calories * 0.50 / 4,
+		Fat:          calories * 0.25 / 9,
+	}
+}
+
+func candidates(kind string, n int, calories ...float64) []Candidate {
+	out := make([]Candidate, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, Candidate{
+			Kind:         kind,
+			RefID:        primitive.NewObjectID(),
+			Name:         fmt.Sprintf("%s %d", kind, i),
+			ServingGrams: 100,
+			PerServing:   balanced(calories[i%len(calories)]),
+		})
+	}
+	return out
+}
+
+func TestGenerate(t *testing.T) {
+	recipes := candidates(repo.KindRecipe, 12, 300, 450, 600)
+	foods := candidates(repo.KindFood, 4, 100, 200)
+
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		target     userRepo.NutritionTarget
+		days       int
+		err        error
+	}{
+		{name: "recipes and foods", candidates: append(append([]Candidate{}, recipes...), foods...), target: testTarget, days: 7},
+		{name: "recipes only", candidates: recipes, target: testTarget, days: 7},
+		{name: "single day", candidates: recipes, target: testTarget, days: 1},
+		{name: "no candidates", candidates: nil, target: testTarget, days: 3, err: ErrNoCandidates},
+		{name: "no calories", candidates: candidates(repo.KindFood, 3, 0), target: testTarget, days: 3, err: ErrNoCandidates},
+		{name: "no target", candidates: recipes, target: userRepo.NutritionTarget{}, days: 3, err: ErrNoCandidates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Generate(tt.candidates, tt.target, tt.days, 42)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if len(plan) != tt.days {
+				t.Fatalf("got %d days, want %d", len(plan), tt.days)
+			}
+
+			for _, day := range plan {
+				if !day.WithinTolerance {
+					t.Errorf("day %d: %v kcal is not within tolerance of %v", day.Day, day.Nutrients.Calories, tt.target.Calories)
+				}
+				if off := math.Abs(day.Nutrients.Calories-tt.target.Calories) / tt.target.Calories; off > CalorieTolerance {
+					t.Errorf("day %d: %v kcal is %.0f%% off target", day.Day, day.Nutrients.Calories, off*100)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRepeatGap(t *testing.T) {
+	// every day has four meals, so 4 * RepeatGap candidates are the fewest
+	// that never need a repeat
+	tests := []struct {
+		name    string
+		recipes int
+		foods   int
+	}{
+		{name: "exactly enough recipes", recipes: 4 * RepeatGap},
+		{name: "too few recipes", recipes: 3 * RepeatGap, foods: RepeatGap},
+		{name: "plenty of recipes", recipes: 20, foods: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cands := append(candidates(repo.KindRecipe, tt.recipes, 500, 700, 600), candidates(repo.KindFood, tt.foods, 200)...)
+			plan, err := Generate(cands, testTarget, 7, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lastUsed := map[primitive.ObjectID]int{}
+			for _, day := range plan {
+				today := map[primitive.ObjectID]bool{}
+				for _, meal := range day.Meals {
+					for _, item := range meal.Items {
+						if today[item.RefID] {
+							t.Errorf("day %d: %s planned twice", day.Day, item.Name)
+						}
+						today[item.RefID] = true
+						if last, ok := lastUsed[item.RefID]; ok && day.Day-last < RepeatGap {
+							t.Errorf("day %d: %s repeated after %d days, want at least %d", day.Day, item.Name, day.Day-last, RepeatGap)
+						}
+					}
+				}
+				for id := range today {
+					lastUsed[id] = day.Day
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	cands := append(candidates(repo.KindRecipe, 15, 350, 500, 650, 800), candidates(repo.KindFood, 5, 120, 250)...)
+
+	a, err := Generate(cands, testTarget, 7, 99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Generate(cands, testTarget, 7, 99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("same seed gave different plans")
+	}
+
+	differs := false
+	for seed := int64(100); seed < 110 && !differs; seed++ {
+		c, err := Generate(cands, testTarget, 7, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		differs = !reflect.DeepEqual(a, c)
+	}
+	if !differs {
+		t.Error("different seeds always gave the same plan")
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	diaryRepo "dietku-backend/cmd/diary/repo"
+	"dietku-backend/cmd/planner/repo"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxPlanDays = 7
+
+type GeneratePlanForm struct {
+	Name            string               `json:"name"`
+	Days            int                  `json:"days"`
+	Seed            *int64               `json:"seed"`
+	RequireTags     []string             `json:"requireTags"`
+	ExcludeKeywords []string             `json:"excludeKeywords"`
+	ExcludeIDs      []primitive.ObjectID `json:"excludeIds"`
+}
+
+func NewGeneratePlanForm(c echo.Context) (*GeneratePlanForm, error) {
+	form := new(GeneratePlanForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.Days == 0 {
+		form.Days = maxPlanDays
+	}
+	if form.Days < 1 || form.Days > maxPlanDays {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Days must be between 1 and 7.")
+	}
+
+	// without a seed the plan is random, but the seed used is returned so the
+	// client can save exactly what it previewed
+	if form.Seed == nil {
+		seed := time.Now().UnixNano()
+		form.Seed = &seed
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	form.RequireTags = normalize(form.RequireTags)
+	form.ExcludeKeywords = normalize(form.ExcludeKeywords)
+	return form, nil
+}
+
+func (f *GeneratePlanForm) Options() repo.PlanOptions {
+	return repo.PlanOptions{
+		Days:            f.Days,
+		Seed:            *f.Seed,
+		RequireTags:     f.RequireTags,
+		ExcludeKeywords: f.ExcludeKeywords,
+		ExcludeIDs:      f.ExcludeIDs,
+	}
+}
+
+type UpdatePlanForm struct {
+	Name string `json:"name"`
+}
+
+func NewUpdatePlanForm(c echo.Context) (*UpdatePlanForm, error) {
+	form := new(UpdatePlanForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required.")
+	}
+	return form, nil
+}
+
+type PlanItemForm struct {
+	Kind     string             `json:"kind"`
+	RefID    primitive.ObjectID `json:"refId"`
+	Servings float64            `json:"servings"`
+}
+
+type PlanMealForm struct {
+	Meal  string         `json:"meal"`
+	Items []PlanItemForm `json:"items"`
+}
+
+type PlanDayForm struct {
+	Meals []PlanMealForm `json:"meals"`
+}
+
+func NewPlanDayForm(c echo.Context) (*PlanDayForm, error) {
+	form := new(PlanDayForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	for i := range form.Meals {
+		m := &form.Meals[i]
+		m.Meal = strings.ToLower(strings.TrimSpace(m.Meal))
+		if m.Meal != diaryRepo.MealBreakfast && m.Meal != diaryRepo.MealLunch &&
+			m.Meal != diaryRepo.MealDinner && m.Meal != diaryRepo.MealSnacks {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Meal must be one of breakfast, lunch, dinner or snacks.")
+		}
+		for _, item := range m.Items {
+			if item.Kind != repo.KindFood && item.Kind != repo.KindRecipe {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Item kind must be food or recipe.")
+			}
+			if item.RefID.IsZero() {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Every item needs a refId.")
+			}
+			if item.Servings <= 0 {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Servings must be greater than 0.")
+			}
+		}
+	}
+	return form, nil
+}
+
+type ApplyPlanForm struct {
+	StartDate string `json:"startDate"`
+}
+
+func NewApplyPlanForm(c echo.Context) (*ApplyPlanForm, error) {
+	form := new(ApplyPlanForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.StartDate == "" {
+		form.StartDate = "today"
+	}
+	if form.StartDate != "today" {
+		if _, err := time.Parse(gear.DateLayout, form.StartDate); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid startDate, use YYYY-MM-DD")
+		}
+	}
+	return form, nil
+}
+
+func normalize(values []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
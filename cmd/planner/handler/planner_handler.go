@@ -0,0 +1,577 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	diaryRepo "dietku-backend/cmd/diary/repo"
+	"dietku-backend/cmd/event"
+	foodRepo "dietku-backend/cmd/food/repo"
+	planGear "dietku-backend/cmd/planner/gear"
+	"dietku-backend/cmd/planner/repo"
+	recipeRepo "dietku-backend/cmd/recipe/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// candidateLimit caps how many foods and recipes the generator considers.
+const candidateLimit = 500
+
+type PlannerHandler struct {
+	repo       *repo.PlannerRepository
+	foodRepo   *foodRepo.FoodRepository
+	recipeRepo *recipeRepo.RecipeRepository
+	diaryRepo  *diaryRepo.DiaryRepository
+	userRepo   *userRepo.UserRepository
+}
+
+func NewPlannerApi(e *echo.Echo, db *mongo.Database) *PlannerHandler {
+	p := &PlannerHandler{
+		repo:       repo.NewPlannerRepository(db),
+		foodRepo:   foodRepo.NewFoodRepository(db),
+		recipeRepo: recipeRepo.NewRecipeRepository(db),
+		diaryRepo:  diaryRepo.NewDiaryRepository(db),
+		userRepo:   userRepo.NewUserRepository(db),
+	}
+	pGroup := e.Group("")
+	pGroup.Use(gear.IsLoggedIn(db))
+	{
+		pGroup.GET("/api/plans", p.Plans)
+		pGroup.GET("/api/plans/:id", p.Plan)
+
+		pGroup.POST("/api/plans/generate", p.Generate)
+		pGroup.POST("/api/plans", p.Create)
+		pGroup.POST("/api/plans/:id/apply", p.Apply)
+
+		pGroup.PUT("/api/plans/:id", p.Update)
+		pGroup.PUT("/api/plans/:id/days/:day", p.UpdateDay)
+
+		pGroup.DELETE("/api/plans/:id", p.Delete)
+	}
+	return p
+}
+
+func containsAny(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAll(tags []string, required []string) bool {
+	for _, r := range required {
+		found := false
+		for _, t := range tags {
+			if t == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// candidates loads the public recipes and foods the plan can draw from that
+// suit prefs and drops everything the user excluded. A required tag is met by
+// a recipe tag or a diet. prefs may be nil.
+func (h *PlannerHandler) candidates(opts repo.PlanOptions, prefs *foodRepo.Preferences) ([]planGear.Candidate, error) {
+	excluded := map[primitive.ObjectID]bool{}
+	for _, id := range opts.ExcludeIDs {
+		excluded[id] = true
+	}
+
+	recipes, err := h.recipeRepo.FindForPlanning(candidateLimit, prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []planGear.Candidate{}
+	for _, r := range *recipes {
+		tags := append(append([]string{}, r.Tags...), r.Diets...)
+		if excluded[r.ID] || !hasAll(tags, opts.RequireTags) || containsAny(r.Name, opts.ExcludeKeywords) {
+			continue
+		}
+		skip := false
+		for _, ing := range r.Ingredients {
+			if excluded[ing.FoodID] || containsAny(ing.Name, opts.ExcludeKeywords) {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		candidates = append(candidates, planGear.Candidate{
+			Kind:         repo.KindRecipe,
+			RefID:        r.ID,
+			Name:         r.Name,
+			ServingGrams: r.ServingGrams,
+			PerServing:   r.PerServing,
+		})
+	}
+
+	foods, err := h.foodRepo.FindForPlanning(candidateLimit, prefs)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range *foods {
+		if excluded[f.ID] || !hasAll(f.Diets, opts.RequireTags) || containsAny(f.Name, opts.ExcludeKeywords) {
+			continue
+		}
+		candidates = append(candidates, planGear.Candidate{
+			Kind:         repo.KindFood,
+			RefID:        f.ID,
+			Name:         f.Name,
+			ServingGrams: f.ServingSize,
+			PerServing:   f.Nutrients.Scale(f.ServingSize / 100),
+		})
+	}
+	return candidates, nil
+}
+
+// generate builds an unsaved plan for the logged in user.
+func (h *PlannerHandler) generate(c echo.Context, form *GeneratePlanForm) (*repo.MealPlan, error) {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	user, err := h.userRepo.FindOne(tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+
+	opts := form.Options()
+	candidates, err := h.candidates(opts, user.Preferences.FoodPreferences())
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipes.", c)
+	}
+	name := form.Name
+	if name == "" {
+		name = fmt.Sprintf("%d day plan", opts.Days)
+	}
+
+	target := user.NutritionTarget()
+	days, err := planGear.Generate(candidates, target, opts.Days, opts.Seed)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "No recipes or foods match the given restrictions", c)
+	}
+	return &repo.MealPlan{
+		ID:        primitive.NewObjectID(),
+		UserID:    tokenData.ID,
+		Name:      name,
+		Options:   opts,
+		Target:    target,
+		Days:      days,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (h *PlannerHandler) findPlan(c echo.Context) (*repo.MealPlan, error) {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid plan id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	plan, err := h.repo.FindOne(oId, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Plan not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting plan.", c)
+	}
+	return plan, nil
+}
+
+// Plans
+// @Tags Planner
+// @Summary Get My Meal Plans
+// @ID plan
+// @Router /api/plans [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) Plans(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	plans, err := h.repo.FindByUser(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting plans.", c)
+	}
+	return c.JSON(http.StatusOK, plans)
+}
+
+// Plan
+// @Tags Planner
+// @Summary Get Meal Plan
+// @ID plan-get
+// @Router /api/plans/{id} [get]
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) Plan(c echo.Context) error {
+	plan, err := h.findPlan(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, plan)
+}
+
+// Generate
+// @Tags Planner
+// @Summary Preview Meal Plan
+// @Description Generates a 1-7 day plan from public recipes and foods that suit the user's allergens, diet and dislikes and fits their calorie budget and macro split. The same seed and options always give the same plan. Nothing is saved.
+// @ID plan-generate
+// @Router /api/plans/generate [post]
+// @Accept json
+// @Param body body GeneratePlanForm true "generate body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) Generate(c echo.Context) error {
+	form, err := NewGeneratePlanForm(c)
+	if err != nil {
+		return err
+	}
+
+	plan, err := h.generate(c, form)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, plan)
+}
+
+// Create
+// @Tags Planner
+// @Summary Generate And Save Meal Plan
+// @Description Pass the seed of a previewed plan to save that exact plan.
+// @ID plan-create
+// @Router /api/plans [post]
+// @Accept json
+// @Param body body GeneratePlanForm true "generate body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) Create(c echo.Context) error {
+	form, err := NewGeneratePlanForm(c)
+	if err != nil {
+		return err
+	}
+
+	plan, err := h.generate(c, form)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.repo.InsertOne(plan)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while saving plan.", c)
+	}
+	return c.JSON(http.StatusOK, plan)
+}
+
+// Update
+// @Tags Planner
+// @Summary Rename Meal Plan
+// @ID plan-update
+// @Router /api/plans/{id} [put]
+// @Accept json
+// @Param id path string true "Plan ID"
+// @Param body body UpdatePlanForm true "plan body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) Update(c echo.Context) error {
+	plan, err := h.findPlan(c)
+	if err != nil {
+		return err
+	}
+
+	form, err := NewUpdatePlanForm(c)
+	if err != nil {
+		return err
+	}
+
+	plan.Name = form.Name
+	now := time.Now()
+	plan.UpdatedAt = &now
+
+	docs, err := h.repo.UpdateOne(plan)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating plan.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// UpdateDay
+// @Tags Planner
+// @Summary Edit Meal Plan Day
+// @Description Replaces the meals of one plan day. Nutrients are taken from the current foods and recipes.
+// @ID plan-update-day
+// @Router /api/plans/{id}/days/{day} [put]
+// @Accept json
+// @Param id path string true "Plan ID"
+// @Param day path int true "Day number, starting at 1"
+// @Param body body PlanDayForm true "day body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) UpdateDay(c echo.Context) error {
+	plan, err := h.findPlan(c)
+	if err != nil {
+		return err
+	}
+
+	day, err := strconv.Atoi(c.Param("day"))
+	if err != nil || day < 1 || day > len(plan.Days) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid day", c)
+	}
+
+	form, err := NewPlanDayForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	var foodIDs, recipeIDs []primitive.ObjectID
+	for _, m := range form.Meals {
+		for _, item := range m.Items {
+			if item.Kind == repo.KindRecipe {
+				recipeIDs = append(recipeIDs, item.RefID)
+			} else {
+				foodIDs = append(foodIDs, item.RefID)
+			}
+		}
+	}
+
+	candidates := map[primitive.ObjectID]planGear.Candidate{}
+	if len(foodIDs) > 0 {
+		foods, err := h.foodRepo.FindByIDs(foodIDs)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting foods.", c)
+		}
+		for _, f := range *foods {
+			candidates[f.ID] = planGear.Candidate{
+				Kind:         repo.KindFood,
+				RefID:        f.ID,
+				Name:         f.Name,
+				ServingGrams: f.ServingSize,
+				PerServing:   f.Nutrients.Scale(f.ServingSize / 100),
+			}
+		}
+	}
+	if len(recipeIDs) > 0 {
+		recipes, err := h.recipeRepo.FindByIDs(recipeIDs)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipes.", c)
+		}
+		for _, r := range *recipes {
+			if !r.IsVisibleTo(tokenData.ID) {
+				continue
+			}
+			candidates[r.ID] = planGear.Candidate{
+				Kind:         repo.KindRecipe,
+				RefID:        r.ID,
+				Name:         r.Name,
+				ServingGrams: r.ServingGrams,
+				PerServing:   r.PerServing,
+			}
+		}
+	}
+
+	planDay := repo.PlanDay{Day: day, Meals: []repo.PlanMeal{}}
+	for _, m := range form.Meals {
+		meal := repo.PlanMeal{Meal: m.Meal, Items: []repo.PlanItem{}}
+		for _, item := range m.Items {
+			cand, ok := candidates[item.RefID]
+			if !ok || cand.Kind != item.Kind {
+				return echo.NewHTTPError(http.StatusBadRequest, "Item "+item.RefID.Hex()+" not found!", c)
+			}
+			meal.Items = append(meal.Items, repo.PlanItem{
+				Kind:         cand.Kind,
+				RefID:        cand.RefID,
+				Name:         cand.Name,
+				Servings:     item.Servings,
+				ServingGrams: cand.ServingGrams,
+				Nutrients:    cand.PerServing.Scale(item.Servings),
+			})
+		}
+		planDay.Meals = append(planDay.Meals, meal)
+	}
+	planDay.Total()
+	planDay.WithinTolerance = planGear.WithinTolerance(planDay.Nutrients, plan.Target)
+
+	plan.Days[day-1] = planDay
+	now := time.Now()
+	plan.UpdatedAt = &now
+
+	docs, err := h.repo.UpdateOne(plan)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating plan.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// allergens looks up the current allergens of every food and recipe on plan,
+// by id.
+func (h *PlannerHandler) allergens(plan *repo.MealPlan) (map[primitive.ObjectID][]string, error) {
+	var foodIDs, recipeIDs []primitive.ObjectID
+	for _, day := range plan.Days {
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				if item.Kind == repo.KindRecipe {
+					recipeIDs = append(recipeIDs, item.RefID)
+				} else {
+					foodIDs = append(foodIDs, item.RefID)
+				}
+			}
+		}
+	}
+
+	allergens := map[primitive.ObjectID][]string{}
+	if len(foodIDs) > 0 {
+		foods, err := h.foodRepo.FindByIDs(foodIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range *foods {
+			allergens[f.ID] = f.Allergens
+		}
+	}
+	if len(recipeIDs) > 0 {
+		recipes, err := h.recipeRepo.FindByIDs(recipeIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range *recipes {
+			allergens[r.ID] = r.Allergens
+		}
+	}
+	return allergens, nil
+}
+
+// Apply
+// @Tags Planner
+// @Summary Apply Meal Plan To Diary
+// @Description Logs every planned item into the diary, plan day 1 on startDate and so on.
+// @ID plan-apply
+// @Router /api/plans/{id}/apply [post]
+// @Accept json
+// @Param id path string true "Plan ID"
+// @Param body body ApplyPlanForm true "apply body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) Apply(c echo.Context) error {
+	plan, err := h.findPlan(c)
+	if err != nil {
+		return err
+	}
+
+	form, err := NewApplyPlanForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	_, start, _, err := gear.ParseDay(form.StartDate, tokenData.Location())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid startDate, use YYYY-MM-DD", c)
+	}
+
+	allergens, err := h.allergens(plan)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting foods.", c)
+	}
+
+	now := time.Now()
+	entries := diaryRepo.Entries{}
+	dates := []string{}
+	for i, day := range plan.Days {
+		dayStart := start.AddDate(0, 0, i)
+		date := dayStart.Format(gear.DateLayout)
+		logged := len(entries)
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				entry := diaryRepo.Entry{
+					ID:          primitive.NewObjectID(),
+					UserID:      tokenData.ID,
+					Date:        date,
+					Meal:        meal.Meal,
+					Kind:        item.Kind,
+					Name:        item.Name,
+					Quantity:    item.Servings,
+					Unit:        foodRepo.UnitServing,
+					ServingSize: item.ServingGrams,
+					Grams:       item.Servings * item.ServingGrams,
+					Nutrients:   item.Nutrients,
+					Allergens:   allergens[item.RefID],
+					LoggedAt:    dayStart,
+					CreatedAt:   now,
+				}
+				refID := item.RefID
+				if item.Kind == repo.KindRecipe {
+					entry.RecipeID = &refID
+				} else {
+					entry.FoodID = &refID
+				}
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) > logged {
+			dates = append(dates, date)
+		}
+	}
+
+	if len(entries) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Nothing to apply", c)
+	}
+
+	_, err = h.diaryRepo.InsertMany(entries)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while logging plan.", c)
+	}
+	for _, date := range dates {
+		event.Publish(event.Event{Type: event.DiaryLogged, UserID: tokenData.ID, Date: date})
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+// Delete
+// @Tags Planner
+// @Summary Delete Meal Plan
+// @ID plan-delete
+// @Router /api/plans/{id} [delete]
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *PlannerHandler) Delete(c echo.Context) error {
+	plan, err := h.findPlan(c)
+	if err != nil {
+		return err
+	}
+
+	docs, err := h.repo.DeleteOne(plan.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting plan.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	foodRepo "dietku-backend/cmd/food/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	KindFood   = "food"
+	KindRecipe = "recipe"
+)
+
+type PlanItem struct {
+	Kind         string             `json:"kind" bson:"kind"`
+	RefID        primitive.ObjectID `json:"refId" bson:"refId"`
+	Name         string             `json:"name" bson:"name"`
+	Servings     float64            `json:"servings" bson:"servings"`
+	ServingGrams float64            `json:"servingGrams" bson:"servingGrams"`
+	Nutrients    foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+}
+
+type PlanMeal struct {
+	Meal      string             `json:"meal" bson:"meal"`
+	Items     []PlanItem         `json:"items" bson:"items"`
+	Nutrients foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+}
+
+type PlanDay struct {
+	Day             int                `json:"day" bson:"day"`
+	Meals           []PlanMeal         `json:"meals" bson:"meals"`
+	Nutrients       foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+	WithinTolerance bool               `json:"withinTolerance" bson:"withinTolerance"`
+}
+
+// Total recomputes the meal and day totals from the items.
+func (d *PlanDay) Total() {
+	d.Nutrients = foodRepo.Nutrients{}
+	for i := range d.Meals {
+		m := &d.Meals[i]
+		m.Nutrients = foodRepo.Nutrients{}
+		for _, item := range m.Items {
+			m.Nutrients = m.Nutrients.Add(item.Nutrients)
+		}
+		d.Nutrients = d.Nutrients.Add(m.Nutrients)
+	}
+}
+
+// PlanOptions are the inputs a plan was generated from, kept so the same plan
+// can be regenerated from its seed.
+type PlanOptions struct {
+	Days            int                  `json:"days" bson:"days"`
+	Seed            int64                `json:"seed" bson:"seed"`
+	RequireTags     []string             `json:"requireTags" bson:"requireTags"`
+	ExcludeKeywords []string             `json:"excludeKeywords" bson:"excludeKeywords"`
+	ExcludeIDs      []primitive.ObjectID `json:"excludeIds" bson:"excludeIds"`
+}
+
+type MealPlan struct {
+	ID        primitive.ObjectID       `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID       `json:"userId" bson:"userId"`
+	Name      string                   `json:"name" bson:"name"`
+	Options   PlanOptions              `json:"options" bson:"options"`
+	Target    userRepo.NutritionTarget `json:"target" bson:"target"`
+	Days      []PlanDay                `json:"days" bson:"days"`
+	CreatedAt time.Time                `json:"createdAt" bson:"createdAt"`
+	UpdatedAt *time.Time               `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	IsDeleted bool                     `json:"isDeleted" bson:"isDeleted"`
+}
+
+type MealPlans []MealPlan
+
+func DecodeAsMealPlans(cursor *mongo.Cursor) (*MealPlans, error) {
+	docs := MealPlans{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type PlannerRepository struct {
+	coll *mongo.Collection
+}
+
+func NewPlannerRepository(db *mongo.Database) *PlannerRepository {
+	return &PlannerRepository{
+		coll: db.Collection("meal_plans"),
+	}
+}
+
+func (r *PlannerRepository) FindByUser(userID primitive.ObjectID) (*MealPlans, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"userId": userID, "isDeleted": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsMealPlans(cursor)
+}
+
+func (r *PlannerRepository) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*MealPlan, error) {
+	var d = &MealPlan{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *PlannerRepository) InsertOne(newPlan *MealPlan) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newPlan)
+}
+
+func (r *PlannerRepository) UpdateOne(plan *MealPlan) (*MealPlan, error) {
+	filter := bson.M{"_id": plan.ID}
+
+	update := bson.M{
+		"$set": plan,
+	}
+
+	var d = &MealPlan{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *PlannerRepository) DeleteOne(id primitive.ObjectID) (*MealPlan, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &MealPlan{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/recipe/repo"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+)
+
+type IngredientForm struct {
+	FoodID   primitive.ObjectID `json:"foodId"`
+	Quantity float64            `json:"quantity"`
+	Unit     string             `json:"unit"`
+}
+
+type RecipeForm struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Servings    int              `json:"servings"`
+	Ingredients []IngredientForm `json:"ingredients"`
+	Steps       []string         `json:"steps"`
+	PrepTime    int              `json:"prepTime"`
+	CookTime    int              `json:"cookTime"`
+	Tags        []string         `json:"tags"`
+	Visibility  string           `json:"visibility"`
+}
+
+func NewRecipeForm(c echo.Context) (*RecipeForm, error) {
+	form := new(RecipeForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required.")
+	}
+
+	if form.Servings == 0 {
+		form.Servings = 1
+	}
+
+	if len(form.Ingredients) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "At least one ingredient is required.")
+	}
+
+	if form.Visibility == "" {
+		form.Visibility = repo.VisibilityPrivate
+	}
+
+	if err := validateRecipeForm(form); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+func NewUpdateRecipeForm(c echo.Context) (*RecipeForm, error) {
+	form := new(RecipeForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+	form.Name = strings.TrimSpace(form.Name)
+
+	if err := validateRecipeForm(form); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+func validateRecipeForm(form *RecipeForm) error {
+	if form.Servings < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Servings must be at least 1.")
+	}
+
+	if form.PrepTime < 0 || form.CookTime < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Prep and cook time must not be negative.")
+	}
+
+	if form.Visibility != "" && form.Visibility != repo.VisibilityPrivate && form.Visibility != repo.VisibilityPublic {
+		return echo.NewHTTPError(http.StatusBadRequest, "Visibility must be private or public.")
+	}
+
+	for i := range form.Ingredients {
+		ing := &form.Ingredients[i]
+		if ing.FoodID.IsZero() {
+			return echo.NewHTTPError(http.StatusBadRequest, "Every ingredient needs a foodId.")
+		}
+		if ing.Quantity <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Ingredient quantity must be greater than 0.")
+		}
+		if ing.Unit == "" {
+			ing.Unit = foodRepo.UnitGram
+		}
+		if ing.Unit != foodRepo.UnitGram && ing.Unit != foodRepo.UnitMilliliter && ing.Unit != foodRepo.UnitServing {
+			return echo.NewHTTPError(http.StatusBadRequest, "Ingredient unit must be g, ml or serving.")
+		}
+	}
+
+	steps := make([]string, 0, len(form.Steps))
+	for _, step := range form.Steps {
+		if step = strings.TrimSpace(step); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	form.Steps = steps
+
+	form.Tags = normalizeTags(form.Tags)
+	return nil
+}
+
+// normalizeTags lower-cases, trims and de-duplicates tags so filtering by tag
+// does not depend on how each author typed it.
+func normalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
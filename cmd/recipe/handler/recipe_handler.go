@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	foodGear "dietku-backend/cmd/food/gear"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/log"
+	"dietku-backend/cmd/recipe/repo"
+	userRepo "dietku-backend/cmd/user/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type RecipeHandler struct {
+	repo     *repo.RecipeRepository
+	foodRepo *foodRepo.FoodRepository
+	userRepo *userRepo.UserRepository
+}
+
+func NewRecipeApi(e *echo.Echo, db *mongo.Database) *RecipeHandler {
+	r := &RecipeHandler{
+		repo:     repo.NewRecipeRepository(db),
+		foodRepo: foodRepo.NewFoodRepository(db),
+		userRepo: userRepo.NewUserRepository(db),
+	}
+	if err := r.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create recipe indexes: %v", err)
+	}
+
+	rGroup := e.Group("")
+	{
+		rGroup.GET("/api/recipes", r.Recipes, gear.MaybeLoggedIn(db))
+		rGroup.GET("/api/recipes/mine", r.MyRecipes, gear.IsLoggedIn(db))
+		rGroup.GET("/api/recipes/:id", r.Recipe, gear.MaybeLoggedIn(db))
+
+		rGroup.POST("/api/recipes", r.Create, gear.IsLoggedIn(db))
+
+		rGroup.PUT("/api/recipes/:id", r.Update, gear.IsLoggedIn(db))
+
+		rGroup.DELETE("/api/recipes/:id", r.Delete, gear.IsLoggedIn(db))
+	}
+	return r
+}
+
+// buildIngredients resolves the foods of the submitted ingredients and
+// snapshots their nutrients for the requested amounts, along with their
+// allergen and diet tags.
+func (h *RecipeHandler) buildIngredients(c echo.Context, forms []IngredientForm) ([]repo.Ingredient, error) {
+	ids := make([]primitive.ObjectID, 0, len(forms))
+	for _, f := range forms {
+		ids = append(ids, f.FoodID)
+	}
+
+	foods, err := h.foodRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting foods.", c)
+	}
+
+	byID := map[primitive.ObjectID]*foodRepo.Food{}
+	for i := range *foods {
+		byID[(*foods)[i].ID] = &(*foods)[i]
+	}
+
+	ingredients := make([]repo.Ingredient, 0, len(forms))
+	for _, f := range forms {
+		food, ok := byID[f.FoodID]
+		if !ok {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Food "+f.FoodID.Hex()+" not found!", c)
+		}
+		grams := food.Grams(f.Quantity, f.Unit)
+		ingredients = append(ingredients, repo.Ingredient{
+			FoodID:    food.ID,
+			Name:      food.Name,
+			Quantity:  f.Quantity,
+			Unit:      f.Unit,
+			Grams:     grams,
+			Nutrients: food.Nutrients.Scale(grams / 100),
+			Allergens: food.Allergens,
+			Diets:     food.Diets,
+		})
+	}
+	return ingredients, nil
+}
+
+// Recipes
+// @Tags Recipe
+// @Summary Get Public Recipes
+// @Description When logged in, recipes conflicting with the user's diet, allergens, halal requirement or disliked ingredients are left out unless all=true.
+// @ID recipe
+// @Router /api/recipes [get]
+// @Produce json
+// @Param q query string false "Name"
+// @Param tag query string false "Tag"
+// @Param all query bool false "Ignore the user's preferences"
+// @Success 200
+func (h *RecipeHandler) Recipes(c echo.Context) error {
+	prefs, err := foodGear.Preferences(c, h.userRepo)
+	if err != nil {
+		return err
+	}
+
+	recipes, err := h.repo.FindPublic(c.QueryParam("q"), strings.ToLower(strings.TrimSpace(c.QueryParam("tag"))), prefs)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipes.", c)
+	}
+	return c.JSON(http.StatusOK, recipes)
+}
+
+// MyRecipes
+// @Tags Recipe
+// @Summary Get My Recipes
+// @ID recipe-mine
+// @Router /api/recipes/mine [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *RecipeHandler) MyRecipes(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	recipes, err := h.repo.FindByUser(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipes.", c)
+	}
+	return c.JSON(http.StatusOK, recipes)
+}
+
+// Recipe
+// @Tags Recipe
+// @Summary Get Recipe
+// @Description Public recipes are visible to everyone, private ones only to their author.
+// @ID recipe-get
+// @Router /api/recipes/{id} [get]
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *RecipeHandler) Recipe(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe id", c)
+	}
+
+	recipe, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Recipe not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipe.", c)
+	}
+
+	var userID primitive.ObjectID
+	if me, ok := c.Get("me").(*gear.UserClaims); ok {
+		userID = me.ID
+	}
+	if !recipe.IsVisibleTo(userID) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Recipe not found!", c)
+	}
+	return c.JSON(http.StatusOK, recipe)
+}
+
+// Create
+// @Tags Recipe
+// @Summary Create Recipe
+// @Description Nutrition totals and per serving values are calculated from the ingredients.
+// @ID recipe-create
+// @Router /api/recipes [post]
+// @Accept json
+// @Param body body RecipeForm true "recipe body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *RecipeHandler) Create(c echo.Context) error {
+	form, err := NewRecipeForm(c)
+	if err != nil {
+		return err
+	}
+
+	ingredients, err := h.buildIngredients(c, form.Ingredients)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	r := &repo.Recipe{
+		ID:          primitive.NewObjectID(),
+		Name:        form.Name,
+		Description: strings.TrimSpace(form.Description),
+		Servings:    form.Servings,
+		Ingredients: ingredients,
+		Steps:       form.Steps,
+		PrepTime:    form.PrepTime,
+		CookTime:    form.CookTime,
+		Tags:        form.Tags,
+		Visibility:  form.Visibility,
+		CreatedBy: repo.By{
+			ID:       tokenData.ID,
+			Email:    tokenData.Email,
+			FullName: tokenData.FirstName + " " + tokenData.LastName,
+			At:       time.Now(),
+		},
+	}
+	r.Recalculate()
+
+	_, err = h.repo.InsertOne(r)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating recipe.", c)
+	}
+
+	docs, err := h.repo.FindOne(r.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipe.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Update
+// @Tags Recipe
+// @Summary Update Recipe
+// @Description Sending ingredients replaces the whole ingredient list.
+// @ID recipe-update
+// @Router /api/recipes/{id} [put]
+// @Accept json
+// @Param id path string true "Recipe ID"
+// @Param body body RecipeForm true "recipe body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *RecipeHandler) Update(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe id", c)
+	}
+
+	recipe, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Recipe not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipe.", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	if tokenData.ID != recipe.CreatedBy.ID {
+		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to update this recipe", c)
+	}
+
+	form, err := NewUpdateRecipeForm(c)
+	if err != nil {
+		return err
+	}
+
+	if form.Name != "" {
+		recipe.Name = form.Name
+	}
+	if form.Description != "" {
+		recipe.Description = strings.TrimSpace(form.Description)
+	}
+	if form.Servings > 0 {
+		recipe.Servings = form.Servings
+	}
+	if len(form.Ingredients) > 0 {
+		recipe.Ingredients, err = h.buildIngredients(c, form.Ingredients)
+		if err != nil {
+			return err
+		}
+	}
+	if len(form.Steps) > 0 {
+		recipe.Steps = form.Steps
+	}
+	if form.PrepTime > 0 {
+		recipe.PrepTime = form.PrepTime
+	}
+	if form.CookTime > 0 {
+		recipe.CookTime = form.CookTime
+	}
+	if len(form.Tags) > 0 {
+		recipe.Tags = form.Tags
+	}
+	if form.Visibility != "" {
+		recipe.Visibility = form.Visibility
+	}
+	recipe.Recalculate()
+
+	now := time.Now()
+	recipe.UpdatedAt = &now
+
+	docs, err := h.repo.UpdateOne(recipe)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating recipe.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Delete
+// @Tags Recipe
+// @Summary Delete Recipe
+// @ID recipe-delete
+// @Router /api/recipes/{id} [delete]
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *RecipeHandler) Delete(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe id", c)
+	}
+
+	recipe, err := h.repo.FindOne(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Recipe not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipe.", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	if tokenData.ID != recipe.CreatedBy.ID {
+		return echo.NewHTTPError(http.StatusUnauthorized, "You are not authorized to delete this recipe", c)
+	}
+
+	docs, err := h.repo.DeleteOne(recipe.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting recipe.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
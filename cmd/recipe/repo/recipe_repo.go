@@ -0,0 +1,256 @@
+package repo
+
+import (
+	"context"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
+)
+
+const (
+	VisibilityPrivate = "private"
+	VisibilityPublic  = "public"
+)
+
+type By struct {
+	ID       primitive.ObjectID `json:"_id" bson:"_id"`
+	Email    string             `json:"email" bson:"email"`
+	FullName string             `json:"fullname" bson:"fullname"`
+	At       time.Time          `json:"at" bson:"at"`
+}
+
+// Ingredient keeps the food's name and the nutrients of the used amount so a
+// recipe still renders if the food is removed later.
+type Ingredient struct {
+	FoodID    primitive.ObjectID `json:"foodId" bson:"foodId"`
+	Name      string             `json:"name" bson:"name"`
+	Quantity  float64            `json:"quantity" bson:"quantity"`
+	Unit      string             `json:"unit" bson:"unit"`
+	Grams     float64            `json:"grams" bson:"grams"`
+	Nutrients foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+	Allergens []string           `json:"allergens,omitempty" bson:"allergens,omitempty"`
+	Diets     []string           `json:"diets,omitempty" bson:"diets,omitempty"`
+}
+
+type Recipe struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	Name         string             `json:"name" bson:"name"`
+	Description  string             `json:"description" bson:"description"`
+	Servings     int                `json:"servings" bson:"servings"`
+	Ingredients  []Ingredient       `json:"ingredients" bson:"ingredients"`
+	Steps        []string           `json:"steps" bson:"steps"`
+	PrepTime     int                `json:"prepTime" bson:"prepTime"`
+	CookTime     int                `json:"cookTime" bson:"cookTime"`
+	Tags         []string           `json:"tags" bson:"tags"`
+	Visibility   string             `json:"visibility" bson:"visibility"`
+	Nutrients    foodRepo.Nutrients `json:"nutrients" bson:"nutrients"`
+	PerServing   foodRepo.Nutrients `json:"perServing" bson:"perServing"`
+	ServingGrams float64            `json:"servingGrams" bson:"servingGrams"`
+	Allergens    []string           `json:"allergens" bson:"allergens"`
+	Diets        []string           `json:"diets" bson:"diets"`
+	CreatedBy    By                 `json:"createdBy" bson:"createdBy"`
+	UpdatedAt    *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	IsDeleted    bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+// Recalculate derives the recipe totals, per serving values and tags from its
+// ingredients. It must be called whenever ingredients or servings change. A
+// recipe contains every allergen of its ingredients and only suits the diets
+// all of them suit.
+func (r *Recipe) Recalculate() {
+	total := foodRepo.Nutrients{}
+	grams := 0.0
+	allergens := []string{}
+	dietCount := map[string]int{}
+	for _, ing := range r.Ingredients {
+		total = total.Add(ing.Nutrients)
+		grams += ing.Grams
+		allergens = append(allergens, ing.Allergens...)
+		for _, d := range ing.Diets {
+			dietCount[d]++
+		}
+	}
+
+	diets := []string{}
+	for d, n := range dietCount {
+		if n == len(r.Ingredients) {
+			diets = append(diets, d)
+		}
+	}
+	r.Allergens = foodRepo.NormalizeAllergens(allergens)
+	r.Diets = foodRepo.NormalizeDiets(diets)
+
+	servings := float64(r.Servings)
+	if servings < 1 {
+		servings = 1
+	}
+	r.Nutrients = total
+	r.PerServing = total.Scale(1 / servings)
+	r.ServingGrams = grams / servings
+}
+
+// ApplyFood refreshes every ingredient made from food and recalculates the
+// recipe. It reports whether any ingredient used the food.
+func (r *Recipe) ApplyFood(food *foodRepo.Food) bool {
+	changed := false
+	for i := range r.Ingredients {
+		ing := &r.Ingredients[i]
+		if ing.FoodID != food.ID {
+			continue
+		}
+		ing.Name = food.Name
+		ing.Grams = food.Grams(ing.Quantity, ing.Unit)
+		ing.Nutrients = food.Nutrients.Scale(ing.Grams / 100)
+		ing.Allergens = food.Allergens
+		ing.Diets = food.Diets
+		changed = true
+	}
+	if changed {
+		r.Recalculate()
+	}
+	return changed
+}
+
+// IsVisibleTo reports whether userID may read the recipe. A zero userID stands
+// for an anonymous request.
+func (r *Recipe) IsVisibleTo(userID primitive.ObjectID) bool {
+	return r.Visibility == VisibilityPublic || (!userID.IsZero() && r.CreatedBy.ID == userID)
+}
+
+type Recipes []Recipe
+
+func DecodeAsRecipes(cursor *mongo.Cursor) (*Recipes, error) {
+	docs := Recipes{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type RecipeRepository struct {
+	coll *mongo.Collection
+}
+
+func NewRecipeRepository(db *mongo.Database) *RecipeRepository {
+	return &RecipeRepository{
+		coll: db.Collection("recipes"),
+	}
+}
+
+func (r *RecipeRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "ingredients.foodId", Value: 1}}},
+		{Keys: bson.D{{Key: "visibility", Value: 1}, {Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "createdBy._id", Value: 1}}},
+	})
+	return err
+}
+
+// FindPublic lists public recipes, optionally narrowed by a name search, a tag
+// and the user's prefs. prefs may be nil.
+func (r *RecipeRepository) FindPublic(query string, tag string, prefs *foodRepo.Preferences) (*Recipes, error) {
+	filter := bson.M{"visibility": VisibilityPublic, "isDeleted": bson.M{"$ne": true}}
+	if query != "" {
+		filter["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	}
+	if tag != "" {
+		filter["tags"] = tag
+	}
+	prefs.Apply(filter, "name", "ingredients.name")
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdBy.at", Value: -1}}).SetLimit(50)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsRecipes(cursor)
+}
+
+// FindForPlanning returns public recipes matching prefs in a stable order, so
+// the meal planner sees the same candidates for the same data. prefs may be
+// nil.
+func (r *RecipeRepository) FindForPlanning(limit int64, prefs *foodRepo.Preferences) (*Recipes, error) {
+	filter := bson.M{"visibility": VisibilityPublic, "perServing.calories": bson.M{"$gt": 0}, "isDeleted": bson.M{"$ne": true}}
+	prefs.Apply(filter, "name", "ingredients.name")
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsRecipes(cursor)
+}
+
+func (r *RecipeRepository) FindByIDs(ids []primitive.ObjectID) (*Recipes, error) {
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"_id": bson.M{"$in": ids}, "isDeleted": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsRecipes(cursor)
+}
+
+func (r *RecipeRepository) FindByUser(userID primitive.ObjectID) (*Recipes, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdBy.at", Value: -1}})
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"createdBy._id": userID, "isDeleted": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsRecipes(cursor)
+}
+
+func (r *RecipeRepository) FindByFood(foodID primitive.ObjectID) (*Recipes, error) {
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"ingredients.foodId": foodID, "isDeleted": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsRecipes(cursor)
+}
+
+func (r *RecipeRepository) FindOne(id primitive.ObjectID) (*Recipe, error) {
+	var d = &Recipe{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *RecipeRepository) InsertOne(newRecipe *Recipe) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newRecipe)
+}
+
+func (r *RecipeRepository) UpdateOne(recipe *Recipe) (*Recipe, error) {
+	filter := bson.M{"_id": recipe.ID}
+
+	update := bson.M{
+		"$set": recipe,
+	}
+
+	var d = &Recipe{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *RecipeRepository) DeleteOne(id primitive.ObjectID) (*Recipe, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &Recipe{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
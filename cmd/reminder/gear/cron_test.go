@@ -0,0 +1,144 @@
+package gear
+
+import (
+	"dietku-backend/cmd/reminder/repo"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 9-17/4 * * 1-5",
+		"5/20 0,12 1,15 */3 0",
+		"59 23 31 12 7",
+		"  0   7  *  *  *  ",
+	}
+	for _, spec := range valid {
+		if _, err := ParseCron(spec); err != nil {
+			t.Errorf("ParseCron(%q): %v", spec, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1-2-3 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	}
+	for _, spec := range invalid {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	utc := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+	}
+	// 2026-01-01 is a Thursday
+	tests := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{name: "every minute", spec: "* * * * *", after: utc(2026, 1, 1, 10, 7), want: utc(2026, 1, 1, 10, 8)},
+		{name: "strictly after", spec: "0 10 * * *", after: utc(2026, 1, 1, 10, 0), want: utc(2026, 1, 2, 10, 0)},
+		{name: "seconds are dropped", spec: "*/15 * * * *", after: utc(2026, 1, 1, 10, 15).Add(30 * time.Second), want: utc(2026, 1, 1, 10, 30)},
+		{name: "step", spec: "*/15 * * * *", after: utc(2026, 1, 1, 10, 7), want: utc(2026, 1, 1, 10, 15)},
+		{name: "step from a value", spec: "5/20 * * * *", after: utc(2026, 1, 1, 10, 6), want: utc(2026, 1, 1, 10, 25)},
+		{name: "range with step", spec: "0 9-17/4 * * *", after: utc(2026, 1, 1, 10, 0), want: utc(2026, 1, 1, 13, 0)},
+		{name: "range with step wraps to next day", spec: "0 9-17/4 * * *", after: utc(2026, 1, 1, 17, 0), want: utc(2026, 1, 2, 9, 0)},
+		{name: "list", spec: "0 7,19 * * *", after: utc(2026, 1, 1, 8, 0), want: utc(2026, 1, 1, 19, 0)},
+		{name: "weekdays", spec: "30 7 * * 1,3", after: utc(2026, 1, 1, 8, 0), want: utc(2026, 1, 5, 7, 30)},
+		{name: "weekday range", spec: "0 8 * * 1-5", after: utc(2026, 1, 2, 9, 0), want: utc(2026, 1, 5, 8, 0)},
+		{name: "sunday as 7", spec: "0 0 * * 7", after: utc(2026, 1, 1, 0, 0), want: utc(2026, 1, 4, 0, 0)},
+		{name: "sunday as 0", spec: "0 0 * * 0", after: utc(2026, 1, 1, 0, 0), want: utc(2026, 1, 4, 0, 0)},
+		{name: "day of month only", spec: "0 0 13 * *", after: utc(2026, 1, 1, 0, 0), want: utc(2026, 1, 13, 0, 0)},
+		{name: "day of week only", spec: "0 0 * * 5", after: utc(2026, 1, 3, 0, 0), want: utc(2026, 1, 9, 0, 0)},
+		{name: "both days restricted: either matches, friday first", spec: "0 0 13 * 5", after: utc(2026, 1, 1, 0, 0), want: utc(2026, 1, 2, 0, 0)},
+		{name: "both days restricted: either matches, 13th first", spec: "0 0 13 * 5", after: utc(2026, 1, 9, 0, 0), want: utc(2026, 1, 13, 0, 0)},
+		{name: "day of month with star day of week needs the date", spec: "0 0 13 * *", after: utc(2026, 1, 2, 0, 0), want: utc(2026, 1, 13, 0, 0)},
+		{name: "month step", spec: "0 12 1 */3 *", after: utc(2026, 1, 1, 12, 0), want: utc(2026, 4, 1, 12, 0)},
+		{name: "year end", spec: "0 0 1 1 *", after: utc(2026, 6, 1, 0, 0), want: utc(2027, 1, 1, 0, 0)},
+		{name: "31st skips short months", spec: "0 0 31 * *", after: utc(2026, 1, 31, 0, 0), want: utc(2026, 3, 31, 0, 0)},
+		{name: "leap day", spec: "0 0 29 2 *", after: utc(2026, 1, 1, 0, 0), want: utc(2028, 2, 29, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := cron.Next(tt.after)
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("Next(%q, %v) = %v, %v; want %v", tt.spec, tt.after, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextInLocation(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	cron, _ := ParseCron("30 7 * * *")
+
+	// 07:00 in Jakarta is midnight UTC
+	got, err := cron.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).In(jakarta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC); !got.Equal(want) || got.Location() != jakarta {
+		t.Errorf("Next = %v, want %v in Asia/Jakarta", got, want)
+	}
+}
+
+func TestCronNeverFires(t *testing.T) {
+	for _, spec := range []string{"0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		cron, err := ParseCron(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cron.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrNeverFires) {
+			t.Errorf("Next(%q): err = %v, want ErrNeverFires", spec, err)
+		}
+	}
+}
+
+func TestCronSpec(t *testing.T) {
+	tests := []struct {
+		schedule repo.Schedule
+		want     string
+		err      bool
+	}{
+		{schedule: repo.Schedule{Type: repo.ScheduleDaily, Time: "07:30"}, want: "30 7 * * *"},
+		{schedule: repo.Schedule{Type: repo.ScheduleDaily, Time: "21:05", Days: []int{1, 3}}, want: "5 21 * * 1,3"},
+		{schedule: repo.Schedule{Type: repo.ScheduleCron, Cron: "*/10 * * * *"}, want: "*/10 * * * *"},
+		{schedule: repo.Schedule{Type: repo.ScheduleDaily, Time: "7.30"}, err: true},
+	}
+	for _, tt := range tests {
+		got, err := CronSpec(tt.schedule)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("CronSpec(%+v) = %q, %v; want %q", tt.schedule, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gear
+
+import (
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/log"
+	notificationGear "dietku-backend/cmd/notification/gear"
+	notificationRepo "dietku-backend/cmd/notification/repo"
+	"dietku-backend/cmd/reminder/repo"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
+)
+
+const TimeLayout = "15:04"
+
+// Titles are used when a reminder is created without one.
+var Titles = map[string]string{
+	repo.KindMeal:    "Time to log your meal",
+	repo.KindWater:   "Time for a glass of water",
+	repo.KindWeighIn: "Time to weigh in",
+	repo.KindCustom:  "Reminder",
+}
+
+// CronSpec turns schedule into a cron expression. A daily schedule at 07:30 on
+// Monday and Wednesday becomes "30 7 * * 1,3".
+func CronSpec(schedule repo.Schedule) (string, error) {
+	if schedule.Type == repo.ScheduleCron {
+		return schedule.Cron, nil
+	}
+
+	at, err := time.Parse(TimeLayout, schedule.Time)
+	if err != nil {
+		return "", fmt.Errorf("time must be in HH:MM format")
+	}
+
+	dow := "*"
+	if len(schedule.Days) > 0 {
+		days := make([]string, len(schedule.Days))
+		for i, d := range schedule.Days {
+			days[i] = fmt.Sprint(d)
+		}
+		dow = strings.Join(days, ",")
+	}
+	return fmt.Sprintf("%d %d * * %s", at.Minute(), at.Hour(), dow), nil
+}
+
+// Next returns when reminder should fire after now, in the reminder's time
+// zone.
+func Next(reminder *repo.Reminder, now time.Time) (time.Time, error) {
+	spec, err := CronSpec(reminder.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	cron, err := ParseCron(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cron.Next(now.In(authGear.LoadLocation(reminder.Timezone)))
+}
+
+// Store is the part of the reminder collection the worker uses.
+type Store interface {
+	FindDue(now time.Time, limit int64) (*repo.Reminders, error)
+	Fire(id primitive.ObjectID, due time.Time, next *time.Time, now time.Time) (bool, error)
+}
+
+// FireDue dispatches up to limit reminders due at now and moves each on to its
+// next run. Reminders missed while no instance was running fire once, not once
+// per missed run.
+func FireDue(store Store, dispatcher Dispatcher, now time.Time, limit int64) error {
+	due, err := store.FindDue(now, limit)
+	if err != nil {
+		return err
+	}
+
+	for i := range *due {
+		reminder := &(*due)[i]
+
+		var next *time.Time
+		if at, err := Next(reminder, now); err == nil {
+			next = &at
+		} else {
+			log.Errorf("failed to schedule reminder %s: %v", reminder.ID.Hex(), err)
+		}
+
+		fired, err := store.Fire(reminder.ID, *reminder.NextRunAt, next, now)
+		if err != nil {
+			return err
+		}
+		if fired {
+			dispatch(dispatcher, reminder, now)
+		}
+	}
+	return nil
+}
+
+// dispatch delivers one reminder, so a failing or panicking delivery does not
+// hold up the others.
+func dispatch(dispatcher Dispatcher, reminder *repo.Reminder, at time.Time) {
+	defer log.RecoverWithTrace()
+
+	if err := dispatcher.Dispatch(reminder, at); err != nil {
+		log.Errorf("failed to dispatch reminder %s: %v", reminder.ID.Hex(), err)
+	}
+}
+
+// Dispatcher delivers a fired reminder to the user.
+type Dispatcher interface {
+	Dispatch(reminder *repo.Reminder, at time.Time) error
+}
+
+// NotificationDispatcher delivers reminders to the in-app notification centre.
+type NotificationDispatcher struct {
+	notifier *notificationGear.Notifier
+}
+
+func NewNotificationDispatcher(notifier *notificationGear.Notifier) *NotificationDispatcher {
+	return &NotificationDispatcher{notifier: notifier}
+}
+
+func (d *NotificationDispatcher) Dispatch(reminder *repo.Reminder, at time.Time) error {
+	_, err := d.notifier.Notify(reminder.UserID, notificationRepo.TypeReminder, reminder.Title, reminder.Message, map[string]interface{}{
+		"reminderId": reminder.ID,
+		"kind":       reminder.Kind,
+		"firedAt":    at,
+	})
+	return err
+}
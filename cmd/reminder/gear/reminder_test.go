@@ -0,0 +1,217 @@
+package gear
+
+import (
+	"dietku-backend/cmd/log"
+	"dietku-backend/cmd/reminder/repo"
+	"dietku-backend/cmd/worker"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetLogger(echo.New())
+	os.Exit(m.Run())
+}
+
+// memoryStore keeps reminders the way the collection does for FindDue and
+// Fire, including Fire only matching a reminder still due at the same time.
+type memoryStore struct {
+	mu        sync.Mutex
+	reminders map[primitive.ObjectID]*repo.Reminder
+}
+
+func (s *memoryStore) FindDue(now time.Time, limit int64) (*repo.Reminders, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := repo.Reminders{}
+	for _, r := range s.reminders {
+		if r.Enabled && r.NextRunAt != nil && !r.NextRunAt.After(now) {
+			due = append(due, *r)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRunAt.Before(*due[j].NextRunAt) })
+	if int64(len(due)) > limit {
+		due = due[:limit]
+	}
+	return &due, nil
+}
+
+func (s *memoryStore) Fire(id primitive.ObjectID, due time.Time, next *time.Time, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reminders[id]
+	if !ok || !r.Enabled || r.NextRunAt == nil || !r.NextRunAt.Equal(due) {
+		return false, nil
+	}
+	r.NextRunAt = next
+	r.SnoozedUntil = nil
+	r.LastFiredAt = &now
+	return true, nil
+}
+
+type fired struct {
+	id primitive.ObjectID
+	at time.Time
+}
+
+// stubDispatcher records deliveries and panics for the reminders in panics.
+type stubDispatcher struct {
+	fired  []fired
+	panics map[primitive.ObjectID]bool
+}
+
+func (d *stubDispatcher) Dispatch(reminder *repo.Reminder, at time.Time) error {
+	if d.panics[reminder.ID] {
+		panic("dispatch failed")
+	}
+	d.fired = append(d.fired, fired{id: reminder.ID, at: at})
+	return nil
+}
+
+// start is 07:00 in Jakarta.
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// dailyAt adds a reminder firing every day at hhmm Jakarta time.
+func (s *memoryStore) dailyAt(t *testing.T, hhmm string, now time.Time) *repo.Reminder {
+	r := &repo.Reminder{
+		ID:       primitive.NewObjectID(),
+		Schedule: repo.Schedule{Type: repo.ScheduleDaily, Time: hhmm},
+		Timezone: "Asia/Jakarta",
+		Enabled:  true,
+	}
+	next, err := Next(r, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.NextRunAt = &next
+	s.reminders[r.ID] = r
+	return r
+}
+
+func newReminderWorker(clock worker.Clock, lock worker.Leaser, store Store, d Dispatcher) *worker.Worker {
+	return &worker.Worker{
+		Name:     "reminders",
+		Interval: 30 * time.Second,
+		Clock:    clock,
+		Lock:     lock,
+		Job: func(now time.Time) error {
+			return FireDue(store, d, now, 500)
+		},
+	}
+}
+
+func TestFireDueOnTheLeaderOnly(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Jakarta"); err != nil {
+		t.Skip("no time zone data")
+	}
+
+	clock := worker.NewFakeClock(start)
+	store := &memoryStore{reminders: map[primitive.ObjectID]*repo.Reminder{}}
+	r := store.dailyAt(t, "07:30", clock.Now())
+
+	locks := worker.NewMemoryLocks()
+	aDispatcher, bDispatcher := &stubDispatcher{}, &stubDispatcher{}
+	a := newReminderWorker(clock, locks.For("a"), store, aDispatcher)
+	b := newReminderWorker(clock, locks.For("b"), store, bDispatcher)
+
+	// an hour of ticks on two instances
+	for i := 0; i < 120; i++ {
+		a.Tick()
+		b.Tick()
+		clock.Advance(a.Interval)
+	}
+
+	if len(aDispatcher.fired) != 1 || len(bDispatcher.fired) != 0 {
+		t.Fatalf("fired a=%d b=%d times, want once on the leader", len(aDispatcher.fired), len(bDispatcher.fired))
+	}
+	if at, want := aDispatcher.fired[0].at, time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC); !at.Equal(want) {
+		t.Errorf("fired at %v, want %v", at, want)
+	}
+	if want := time.Date(2026, 1, 2, 0, 30, 0, 0, time.UTC); !store.reminders[r.ID].NextRunAt.Equal(want) {
+		t.Errorf("next run %v, want %v", store.reminders[r.ID].NextRunAt, want)
+	}
+}
+
+func TestFireDueAfterMissedTicks(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Jakarta"); err != nil {
+		t.Skip("no time zone data")
+	}
+
+	clock := worker.NewFakeClock(start)
+	store := &memoryStore{reminders: map[primitive.ObjectID]*repo.Reminder{}}
+	r := store.dailyAt(t, "07:30", clock.Now())
+	dispatcher := &stubDispatcher{}
+	w := newReminderWorker(clock, worker.NewMemoryLocks().For("a"), store, dispatcher)
+
+	// no instance ran for three days and a few hours
+	clock.Advance(3*24*time.Hour + 4*time.Hour)
+	w.Tick()
+	w.Tick()
+
+	if len(dispatcher.fired) != 1 {
+		t.Fatalf("fired %d times, want the three missed runs to fire once", len(dispatcher.fired))
+	}
+	if !dispatcher.fired[0].at.Equal(clock.Now()) {
+		t.Errorf("fired at %v, want now %v", dispatcher.fired[0].at, clock.Now())
+	}
+	// the next run is the next one after now, not one of the missed ones
+	if want := time.Date(2026, 1, 5, 0, 30, 0, 0, time.UTC); !store.reminders[r.ID].NextRunAt.Equal(want) {
+		t.Errorf("next run %v, want %v", store.reminders[r.ID].NextRunAt, want)
+	}
+}
+
+func TestFireDueSkipsDisabledAndClaimed(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Jakarta"); err != nil {
+		t.Skip("no time zone data")
+	}
+
+	store := &memoryStore{reminders: map[primitive.ObjectID]*repo.Reminder{}}
+	disabled := store.dailyAt(t, "07:30", start)
+	disabled.Enabled = false
+	store.dailyAt(t, "07:15", start)
+	dispatcher := &stubDispatcher{}
+
+	now := start.Add(time.Hour)
+	due, _ := store.FindDue(now, 500)
+	// another instance fires everything between FindDue and Fire
+	if err := FireDue(store, &stubDispatcher{}, now, 500); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range *due {
+		if fired, _ := store.Fire(r.ID, *r.NextRunAt, nil, now); fired {
+			t.Errorf("reminder %s fired twice", r.ID.Hex())
+		}
+	}
+
+	if err := FireDue(store, dispatcher, now, 500); err != nil {
+		t.Fatal(err)
+	}
+	if len(dispatcher.fired) != 0 {
+		t.Errorf("fired %d reminders that were disabled or already fired", len(dispatcher.fired))
+	}
+}
+
+func TestFireDueSurvivesPanickingDispatch(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Jakarta"); err != nil {
+		t.Skip("no time zone data")
+	}
+
+	store := &memoryStore{reminders: map[primitive.ObjectID]*repo.Reminder{}}
+	bad := store.dailyAt(t, "07:10", start)
+	good := store.dailyAt(t, "07:20", start)
+	dispatcher := &stubDispatcher{panics: map[primitive.ObjectID]bool{bad.ID: true}}
+
+	if err := FireDue(store, dispatcher, start.Add(time.Hour), 500); err != nil {
+		t.Fatal(err)
+	}
+	if len(dispatcher.fired) != 1 || dispatcher.fired[0].id != good.ID {
+		t.Fatalf("fired %+v, want the reminder after the panicking one", dispatcher.fired)
+	}
+}
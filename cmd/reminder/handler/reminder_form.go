@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"dietku-backend/cmd/reminder/gear"
+	"dietku-backend/cmd/reminder/repo"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	maxTitleLength   = 100
+	maxMessageLength = 500
+	// maxSnoozeMinutes is a day; longer breaks should disable the reminder
+	maxSnoozeMinutes     = 24 * 60
+	defaultSnoozeMinutes = 10
+)
+
+type ReminderForm struct {
+	Kind     string        `json:"kind"`
+	Title    string        `json:"title"`
+	Message  string        `json:"message"`
+	Schedule repo.Schedule `json:"schedule"`
+	Enabled  *bool         `json:"enabled"`
+}
+
+func NewReminderForm(c echo.Context) (*ReminderForm, error) {
+	form := new(ReminderForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Kind = strings.ToLower(strings.TrimSpace(form.Kind))
+	if !repo.IsValidKind(form.Kind) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Kind must be one of meal, water, weigh-in or custom.")
+	}
+
+	form.Title = strings.TrimSpace(form.Title)
+	if form.Title == "" {
+		form.Title = gear.Titles[form.Kind]
+	}
+	if len(form.Title) > maxTitleLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Title must not exceed 100 characters.")
+	}
+
+	form.Message = strings.TrimSpace(form.Message)
+	if len(form.Message) > maxMessageLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Message must not exceed 500 characters.")
+	}
+
+	if err := validateSchedule(&form.Schedule); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// validateSchedule normalizes schedule and checks that it fires at all.
+func validateSchedule(schedule *repo.Schedule) error {
+	schedule.Type = strings.ToLower(strings.TrimSpace(schedule.Type))
+	if schedule.Type == "" {
+		schedule.Type = repo.ScheduleDaily
+	}
+
+	switch schedule.Type {
+	case repo.ScheduleDaily:
+		schedule.Cron = ""
+		seen := map[int]bool{}
+		days := []int{}
+		for _, d := range schedule.Days {
+			if d < 0 || d > 6 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Days must be weekdays from 0 (Sunday) to 6 (Saturday).")
+			}
+			if !seen[d] {
+				seen[d] = true
+				days = append(days, d)
+			}
+		}
+		schedule.Days = days
+	case repo.ScheduleCron:
+		schedule.Time = ""
+		schedule.Days = nil
+		schedule.Cron = strings.Join(strings.Fields(schedule.Cron), " ")
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Schedule type must be daily or cron.")
+	}
+
+	spec, err := gear.CronSpec(*schedule)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Schedule "+err.Error()+".")
+	}
+	cron, err := gear.ParseCron(spec)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid cron: "+err.Error())
+	}
+	if _, err := cron.Next(time.Now()); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Schedule never fires.")
+	}
+	return nil
+}
+
+type SnoozeForm struct {
+	Minutes int `json:"minutes"`
+}
+
+func NewSnoozeForm(c echo.Context) (*SnoozeForm, error) {
+	form := new(SnoozeForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.Minutes == 0 {
+		form.Minutes = defaultSnoozeMinutes
+	}
+	if form.Minutes < 1 || form.Minutes > maxSnoozeMinutes {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Minutes must be between 1 and 1440.")
+	}
+	return form, nil
+}
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"context"
+	authGear "dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/log"
+	"dietku-backend/cmd/reminder/gear"
+	"dietku-backend/cmd/reminder/repo"
+	"dietku-backend/cmd/worker"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const (
+	workerName     = "reminders"
+	workerInterval = 30 * time.Second
+	// dueBatch caps how many reminders one tick fires; the rest wait for the
+	// next tick.
+	dueBatch = 500
+)
+
+type ReminderHandler struct {
+	repo       *repo.ReminderRepository
+	dispatcher gear.Dispatcher
+	clock      worker.Clock
+}
+
+func NewReminderApi(e *echo.Echo, db *mongo.Database, dispatcher gear.Dispatcher) *ReminderHandler {
+	r := &ReminderHandler{
+		repo:       repo.NewReminderRepository(db),
+		dispatcher: dispatcher,
+		clock:      worker.RealClock{},
+	}
+	if err := r.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create reminder indexes: %v", err)
+	}
+
+	w := &worker.Worker{
+		Name:     workerName,
+		Interval: workerInterval,
+		Clock:    r.clock,
+		Lock:     worker.NewLock(db),
+		Job:      r.FireDue,
+	}
+	w.Start(context.Background())
+
+	rGroup := e.Group("")
+	rGroup.Use(authGear.IsLoggedIn(db))
+	{
+		rGroup.GET("/api/reminders", r.List)
+
+		rGroup.POST("/api/reminders", r.Create)
+		rGroup.POST("/api/reminders/:id/snooze", r.Snooze)
+		rGroup.POST("/api/reminders/:id/disable", r.Disable)
+		rGroup.POST("/api/reminders/:id/enable", r.Enable)
+
+		rGroup.PUT("/api/reminders/:id", r.Update)
+
+		rGroup.DELETE("/api/reminders/:id", r.Delete)
+	}
+	return r
+}
+
+// FireDue is the job of the reminder worker.
+func (h *ReminderHandler) FireDue(now time.Time) error {
+	return gear.FireDue(h.repo, h.dispatcher, now, dueBatch)
+}
+
+// schedule sets the next run of reminder from now, or clears it when the
+// reminder is disabled.
+func (h *ReminderHandler) schedule(reminder *repo.Reminder, now time.Time) error {
+	reminder.SnoozedUntil = nil
+	if !reminder.Enabled {
+		reminder.NextRunAt = nil
+		return nil
+	}
+
+	next, err := gear.Next(reminder, now)
+	if err != nil {
+		return err
+	}
+	reminder.NextRunAt = &next
+	return nil
+}
+
+// List
+// @Tags Reminder
+// @Summary List Reminders
+// @ID reminder-list
+// @Router /api/reminders [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ReminderHandler) List(c echo.Context) error {
+	tokenData := c.Get("me").(*authGear.UserClaims)
+
+	docs, err := h.repo.FindByUser(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting reminders.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
+
+// Create
+// @Tags Reminder
+// @Summary Create Reminder
+// @Description A daily schedule fires at time (HH:MM) on days (0 = Sunday, empty = every day); a cron schedule uses a five-field expression. Both are read in the user's time zone.
+// @ID reminder-create
+// @Router /api/reminders [post]
+// @Accept json
+// @Param body body ReminderForm true "reminder body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ReminderHandler) Create(c echo.Context) error {
+	form, err := NewReminderForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*authGear.UserClaims)
+	now := h.clock.Now()
+
+	reminder := &repo.Reminder{
+		ID:        primitive.NewObjectID(),
+		UserID:    tokenData.ID,
+		Kind:      form.Kind,
+		Title:     form.Title,
+		Message:   form.Message,
+		Schedule:  form.Schedule,
+		Timezone:  tokenData.Location().String(),
+		Enabled:   form.Enabled == nil || *form.Enabled,
+		CreatedAt: now,
+	}
+	if err := h.schedule(reminder, now); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Schedule never fires.", c)
+	}
+
+	_, err = h.repo.InsertOne(reminder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating reminder.", c)
+	}
+	return c.JSON(http.StatusOK, reminder)
+}
+
+// Update
+// @Tags Reminder
+// @Summary Update Reminder
+// @Description Replaces the reminder and reschedules it in the user's current time zone.
+// @ID reminder-update
+// @Router /api/reminders/{id} [put]
+// @Accept json
+// @Param id path string true "Reminder ID"
+// @Param body body ReminderForm true "reminder body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ReminderHandler) Update(c echo.Context) error {
+	form, err := NewReminderForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*authGear.UserClaims)
+	reminder, err := h.findReminder(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	now := h.clock.Now()
+	reminder.Kind = form.Kind
+	reminder.Title = form.Title
+	reminder.Message = form.Message
+	reminder.Schedule = form.Schedule
+	reminder.Timezone = tokenData.Location().String()
+	if form.Enabled != nil {
+		reminder.Enabled = *form.Enabled
+	}
+	reminder.UpdatedAt = &now
+	if err := h.schedule(reminder, now); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Schedule never fires.", c)
+	}
+
+	result, err := h.repo.UpdateOne(reminder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating reminder.", c)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// Snooze
+// @Tags Reminder
+// @Summary Snooze Reminder
+// @Description Fires the reminder again after the given minutes (default 10), then returns to its schedule.
+// @ID reminder-snooze
+// @Router /api/reminders/{id}/snooze [post]
+// @Accept json
+// @Param id path string true "Reminder ID"
+// @Param body body SnoozeForm true "snooze body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ReminderHandler) Snooze(c echo.Context) error {
+	form, err := NewSnoozeForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*authGear.UserClaims)
+	reminder, err := h.findReminder(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	if !reminder.Enabled {
+		return echo.NewHTTPError(http.StatusBadRequest, "Reminder is disabled.", c)
+	}
+
+	now := h.clock.Now()
+	until := now.Add(time.Duration(form.Minutes) * time.Minute)
+	reminder.NextRunAt = &until
+	reminder.SnoozedUntil = &until
+	reminder.UpdatedAt = &now
+
+	result, err := h.repo.UpdateOne(reminder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating reminder.", c)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// Disable
+// @Tags Reminder
+// @Summary Disable Reminder
+// @ID reminder-disable
+// @Router /api/reminders/{id}/disable [post]
+// @Param id path string true "Reminder ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ReminderHandler) Disable(c echo.Context) error {
+	return h.setEnabled(c, false)
+}
+
+// Enable
+// @Tags Reminder
+// @Summary Enable Reminder
+// @ID reminder-enable
+// @Router /api/reminders/{id}/enable [post]
+// @Param id path string true "Reminder ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ReminderHandler) Enable(c echo.Context) error {
+	return h.setEnabled(c, true)
+}
+
+func (h *ReminderHandler) setEnabled(c echo.Context, enabled bool) error {
+	tokenData := c.Get("me").(*authGear.UserClaims)
+	reminder, err := h.findReminder(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	now := h.clock.Now()
+	reminder.Enabled = enabled
+	reminder.UpdatedAt = &now
+	if err := h.schedule(reminder, now); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Schedule never fires.", c)
+	}
+
+	result, err := h.repo.UpdateOne(reminder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating reminder.", c)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// Delete
+// @Tags Reminder
+// @Summary Delete Reminder
+// @ID reminder-delete
+// @Router /api/reminders/{id} [delete]
+// @Param id path string true "Reminder ID"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ReminderHandler) Delete(c echo.Context) error {
+	tokenData := c.Get("me").(*authGear.UserClaims)
+	reminder, err := h.findReminder(c, tokenData)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.repo.DeleteOne(reminder.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting reminder.", c)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *ReminderHandler) findReminder(c echo.Context, tokenData *authGear.UserClaims) (*repo.Reminder, error) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid reminder id", c)
+	}
+
+	reminder, err := h.repo.FindOne(id, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Reminder not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting reminder.", c)
+	}
+	return reminder, nil
+}
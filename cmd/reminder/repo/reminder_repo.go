@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	KindMeal    = "meal"
+	KindWater   = "water"
+	KindWeighIn = "weigh-in"
+	KindCustom  = "custom"
+
+	ScheduleDaily = "daily"
+	ScheduleCron  = "cron"
+)
+
+var Kinds = []string{KindMeal, KindWater, KindWeighIn, KindCustom}
+
+func IsValidKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule is either a daily time (HH:MM) on some weekdays or a five-field
+// cron expression, both read in the reminder's time zone.
+type Schedule struct {
+	Type string `json:"type" bson:"type"`
+	Time string `json:"time,omitempty" bson:"time,omitempty"`
+	Days []int  `json:"days,omitempty" bson:"days,omitempty"`
+	Cron string `json:"cron,omitempty" bson:"cron,omitempty"`
+}
+
+// Reminder is a user's rule. NextRunAt is when the worker fires it next and is
+// empty while the reminder is disabled.
+type Reminder struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID       primitive.ObjectID `json:"userId" bson:"userId"`
+	Kind         string             `json:"kind" bson:"kind"`
+	Title        string             `json:"title" bson:"title"`
+	Message      string             `json:"message,omitempty" bson:"message,omitempty"`
+	Schedule     Schedule           `json:"schedule" bson:"schedule"`
+	Timezone     string             `json:"timezone" bson:"timezone"`
+	Enabled      bool               `json:"enabled" bson:"enabled"`
+	NextRunAt    *time.Time         `json:"nextRunAt,omitempty" bson:"nextRunAt"`
+	SnoozedUntil *time.Time         `json:"snoozedUntil,omitempty" bson:"snoozedUntil"`
+	LastFiredAt  *time.Time         `json:"lastFiredAt,omitempty" bson:"lastFiredAt"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	IsDeleted    bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+type Reminders []Reminder
+
+func DecodeAsReminders(cursor *mongo.Cursor) (*Reminders, error) {
+	docs := Reminders{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type ReminderRepository struct {
+	coll *mongo.Collection
+}
+
+func NewReminderRepository(db *mongo.Database) *ReminderRepository {
+	return &ReminderRepository{
+		coll: db.Collection("reminders"),
+	}
+}
+
+func (r *ReminderRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "enabled", Value: 1}, {Key: "nextRunAt", Value: 1}}},
+	})
+	return err
+}
+
+func (r *ReminderRepository) FindByUser(userID primitive.ObjectID) (*Reminders, error) {
+	filter := bson.M{"userId": userID, "isDeleted": bson.M{"$ne": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsReminders(cursor)
+}
+
+func (r *ReminderRepository) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*Reminder, error) {
+	var d = &Reminder{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// FindDue returns up to limit enabled reminders that should have fired by now,
+// oldest first.
+func (r *ReminderRepository) FindDue(now time.Time, limit int64) (*Reminders, error) {
+	filter := bson.M{
+		"enabled":   true,
+		"nextRunAt": bson.M{"$lte": now},
+		"isDeleted": bson.M{"$ne": true},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "nextRunAt", Value: 1}}).SetLimit(limit)
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsReminders(cursor)
+}
+
+func (r *ReminderRepository) InsertOne(newReminder *Reminder) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newReminder)
+}
+
+func (r *ReminderRepository) UpdateOne(reminder *Reminder) (*Reminder, error) {
+	filter := bson.M{"_id": reminder.ID}
+
+	update := bson.M{
+		"$set": reminder,
+	}
+
+	var d = &Reminder{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Fire moves a due reminder on to next. It only matches while nextRunAt is
+// still due, so a reminder claimed by another run is not fired twice; in that
+// case it reports false.
+func (r *ReminderRepository) Fire(id primitive.ObjectID, due time.Time, next *time.Time, now time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "enabled": true, "nextRunAt": due}
+
+	update := bson.M{
+		"$set": bson.M{"nextRunAt": next, "snoozedUntil": nil, "lastFiredAt": now},
+	}
+
+	result, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *ReminderRepository) DeleteOne(id primitive.ObjectID) (*Reminder, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true, "enabled": false, "nextRunAt": nil},
+	}
+
+	var d = &Reminder{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
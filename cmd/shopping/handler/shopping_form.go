@@ -0,0 +1,99 @@
+package handler
+
+import (
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/shopping/repo"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+)
+
+type ShoppingListForm struct {
+	Name    string              `json:"name"`
+	PlanID  *primitive.ObjectID `json:"planId"`
+	Recipes []repo.RecipeRef    `json:"recipes"`
+}
+
+func NewShoppingListForm(c echo.Context) (*ShoppingListForm, error) {
+	form := new(ShoppingListForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	if form.PlanID != nil && form.PlanID.IsZero() {
+		form.PlanID = nil
+	}
+
+	if form.PlanID == nil && len(form.Recipes) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Either planId or recipes is required.")
+	}
+
+	for i := range form.Recipes {
+		r := &form.Recipes[i]
+		if r.RecipeID.IsZero() {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Every recipe needs a recipeId.")
+		}
+		if r.Servings < 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Servings must be greater than 0.")
+		}
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		form.Name = "Shopping list"
+	}
+	return form, nil
+}
+
+type ManualItemForm struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	Category string  `json:"category"`
+}
+
+func NewManualItemForm(c echo.Context) (*ManualItemForm, error) {
+	form := new(ManualItemForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required.")
+	}
+
+	if form.Quantity < 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Quantity must not be negative.")
+	}
+
+	form.Unit = strings.TrimSpace(form.Unit)
+
+	form.Category = strings.ToLower(strings.TrimSpace(form.Category))
+	if form.Category == "" {
+		form.Category = foodRepo.CategoryOther
+	}
+	valid := false
+	for _, c := range foodRepo.Categories {
+		if form.Category == c {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Category must be one of "+strings.Join(foodRepo.Categories, ", ")+".")
+	}
+	return form, nil
+}
+
+type CheckItemForm struct {
+	Checked bool `json:"checked"`
+}
+
+func NewCheckItemForm(c echo.Context) (*CheckItemForm, error) {
+	form := new(CheckItemForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+	return form, nil
+}
@@ -0,0 +1,592 @@
+package handler
+
+import (
+	"crypto/rand"
+	"dietku-backend/cmd/auth/gear"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/log"
+	planRepo "dietku-backend/cmd/planner/repo"
+	recipeRepo "dietku-backend/cmd/recipe/repo"
+	"dietku-backend/cmd/shopping/repo"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// foodFinder, recipeFinder and planFinder are the lookups Create needs from the
+// other features' repositories.
+type foodFinder interface {
+	FindByIDs(ids []primitive.ObjectID) (*foodRepo.Foods, error)
+}
+
+type recipeFinder interface {
+	FindByIDs(ids []primitive.ObjectID) (*recipeRepo.Recipes, error)
+}
+
+type planFinder interface {
+	FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*planRepo.MealPlan, error)
+}
+
+type ShoppingHandler struct {
+	repo       *repo.ShoppingRepository
+	foodRepo   foodFinder
+	recipeRepo recipeFinder
+	planRepo   planFinder
+}
+
+func NewShoppingApi(e *echo.Echo, db *mongo.Database) *ShoppingHandler {
+	s := &ShoppingHandler{
+		repo:       repo.NewShoppingRepository(db),
+		foodRepo:   foodRepo.NewFoodRepository(db),
+		recipeRepo: recipeRepo.NewRecipeRepository(db),
+		planRepo:   planRepo.NewPlannerRepository(db),
+	}
+	if err := s.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create shopping list indexes: %v", err)
+	}
+
+	sGroup := e.Group("")
+	{
+		sGroup.GET("/api/shopping-lists/shared/:token", s.Shared)
+
+		sGroup.GET("/api/shopping-lists", s.Lists, gear.IsLoggedIn(db))
+		sGroup.GET("/api/shopping-lists/:id", s.List, gear.IsLoggedIn(db))
+
+		sGroup.POST("/api/shopping-lists", s.Create, gear.IsLoggedIn(db))
+		sGroup.POST("/api/shopping-lists/:id/items", s.AddItem, gear.IsLoggedIn(db))
+		sGroup.POST("/api/shopping-lists/:id/share", s.Share, gear.IsLoggedIn(db))
+
+		sGroup.PUT("/api/shopping-lists/:id/items/:itemId", s.CheckItem, gear.IsLoggedIn(db))
+
+		sGroup.DELETE("/api/shopping-lists/:id", s.Delete, gear.IsLoggedIn(db))
+		sGroup.DELETE("/api/shopping-lists/:id/items/:itemId", s.DeleteItem, gear.IsLoggedIn(db))
+		sGroup.DELETE("/api/shopping-lists/:id/share", s.Unshare, gear.IsLoggedIn(db))
+	}
+	return s
+}
+
+type ViewItem struct {
+	repo.ListItem
+	Display string `json:"display"`
+}
+
+type Aisle struct {
+	Category string     `json:"category"`
+	Items    []ViewItem `json:"items"`
+}
+
+type ShoppingListView struct {
+	*repo.ShoppingList
+	Aisles []Aisle `json:"aisles"`
+}
+
+// SharedListView is what a share link shows: the items, without anything
+// about the owner or where the list came from.
+type SharedListView struct {
+	Name   string  `json:"name"`
+	Aisles []Aisle `json:"aisles"`
+}
+
+// formatAmount renders a quantity in the largest sensible unit, e.g. 1500 g as
+// "1.5 kg".
+func formatAmount(quantity float64, unit string) string {
+	switch {
+	case quantity == 0:
+		return ""
+	case unit == foodRepo.UnitGram && quantity >= 1000:
+		return strconv.FormatFloat(quantity/1000, 'f', -1, 64) + " kg"
+	case unit == foodRepo.UnitMilliliter && quantity >= 1000:
+		return strconv.FormatFloat(quantity/1000, 'f', -1, 64) + " l"
+	case unit == "":
+		return strconv.FormatFloat(quantity, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(quantity, 'f', -1, 64) + " " + unit
+}
+
+// view groups the list items by aisle in store walking order.
+func view(list *repo.ShoppingList) *ShoppingListView {
+	return &ShoppingListView{ShoppingList: list, Aisles: aisles(list.Items)}
+}
+
+func aisles(items []repo.ListItem) []Aisle {
+	byCategory := map[string][]ViewItem{}
+	for _, item := range items {
+		byCategory[item.Category] = append(byCategory[item.Category], ViewItem{
+			ListItem: item,
+			Display:  formatAmount(item.Quantity, item.Unit),
+		})
+	}
+
+	aisles := []Aisle{}
+	for _, category := range foodRepo.Categories {
+		if items, ok := byCategory[category]; ok {
+			aisles = append(aisles, Aisle{Category: category, Items: items})
+		}
+	}
+	return aisles
+}
+
+// aggregator merges ingredient amounts per food, keeping first-seen order.
+type aggregator struct {
+	grams map[primitive.ObjectID]float64
+	names map[primitive.ObjectID]string
+	order []primitive.ObjectID
+}
+
+func newAggregator() *aggregator {
+	return &aggregator{
+		grams: map[primitive.ObjectID]float64{},
+		names: map[primitive.ObjectID]string{},
+	}
+}
+
+func (a *aggregator) add(foodID primitive.ObjectID, name string, grams float64) {
+	if _, ok := a.grams[foodID]; !ok {
+		a.order = append(a.order, foodID)
+		a.names[foodID] = name
+	}
+	a.grams[foodID] += grams
+}
+
+func (a *aggregator) addRecipe(recipe *recipeRepo.Recipe, servings float64) {
+	factor := servings / float64(recipe.Servings)
+	if recipe.Servings < 1 {
+		factor = servings
+	}
+	for _, ing := range recipe.Ingredients {
+		a.add(ing.FoodID, ing.Name, ing.Grams*factor)
+	}
+}
+
+// items turns the merged amounts into list items, using the current foods for
+// the aisle and whether the amount is in grams or millilitres.
+func (a *aggregator) items(foods *foodRepo.Foods) []repo.ListItem {
+	byID := map[primitive.ObjectID]foodRepo.Food{}
+	for _, f := range *foods {
+		byID[f.ID] = f
+	}
+
+	items := make([]repo.ListItem, 0, len(a.order))
+	for _, id := range a.order {
+		foodID := id
+		item := repo.ListItem{
+			ID:       primitive.NewObjectID(),
+			FoodID:   &foodID,
+			Name:     a.names[id],
+			Category: foodRepo.CategoryOther,
+			Quantity: math.Ceil(a.grams[id]),
+			Unit:     foodRepo.UnitGram,
+		}
+		if f, ok := byID[id]; ok {
+			item.Name = f.Name
+			if f.Category != "" {
+				item.Category = f.Category
+			}
+			if f.ServingUnit == foodRepo.UnitMilliliter {
+				item.Unit = foodRepo.UnitMilliliter
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (h *ShoppingHandler) findList(c echo.Context) (*repo.ShoppingList, error) {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid shopping list id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	list, err := h.repo.FindOne(oId, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Shopping list not found!", c)
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting shopping list.", c)
+	}
+	return list, nil
+}
+
+func (h *ShoppingHandler) save(c echo.Context, list *repo.ShoppingList) error {
+	now := time.Now()
+	list.UpdatedAt = &now
+
+	docs, err := h.repo.UpdateOne(list)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating shopping list.", c)
+	}
+	return c.JSON(http.StatusOK, view(docs))
+}
+
+// Lists
+// @Tags Shopping
+// @Summary Get My Shopping Lists
+// @ID shopping
+// @Router /api/shopping-lists [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) Lists(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	lists, err := h.repo.FindByUser(tokenData.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting shopping lists.", c)
+	}
+	return c.JSON(http.StatusOK, lists)
+}
+
+// List
+// @Tags Shopping
+// @Summary Get Shopping List
+// @ID shopping-get
+// @Router /api/shopping-lists/{id} [get]
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) List(c echo.Context) error {
+	list, err := h.findList(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, view(list))
+}
+
+// Shared
+// @Tags Shopping
+// @Summary Get Shared Shopping List
+// @Description Read-only access through the link created by the share endpoint.
+// @ID shopping-shared
+// @Router /api/shopping-lists/shared/{token} [get]
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200
+func (h *ShoppingHandler) Shared(c echo.Context) error {
+	list, err := h.repo.FindByShareToken(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Shopping list not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting shopping list.", c)
+	}
+	return c.JSON(http.StatusOK, &SharedListView{Name: list.Name, Aisles: aisles(list.Items)})
+}
+
+// collect merges the ingredients of the form's plan and recipes into list
+// items.
+func (h *ShoppingHandler) collect(c echo.Context, userID primitive.ObjectID, form *ShoppingListForm) ([]repo.ListItem, error) {
+	agg := newAggregator()
+	recipeServings := map[primitive.ObjectID]float64{}
+	// whole counts the references that asked for the whole recipe, whose size
+	// is only known once the recipe is loaded
+	whole := map[primitive.ObjectID]int{}
+	var recipeOrder []primitive.ObjectID
+	addRecipe := func(id primitive.ObjectID, servings float64) {
+		if _, ok := recipeServings[id]; !ok {
+			recipeOrder = append(recipeOrder, id)
+		}
+		recipeServings[id] += servings
+		if servings == 0 {
+			whole[id]++
+		}
+	}
+
+	if form.PlanID != nil {
+		plan, err := h.planRepo.FindOne(*form.PlanID, userID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Plan not found!", c)
+			}
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting plan.", c)
+		}
+		for _, day := range plan.Days {
+			for _, meal := range day.Meals {
+				for _, item := range meal.Items {
+					if item.Kind == planRepo.KindRecipe {
+						addRecipe(item.RefID, item.Servings)
+					} else {
+						agg.add(item.RefID, item.Name, item.Servings*item.ServingGrams)
+					}
+				}
+			}
+		}
+	}
+	for _, ref := range form.Recipes {
+		addRecipe(ref.RecipeID, ref.Servings)
+	}
+
+	if len(recipeOrder) > 0 {
+		recipes, err := h.recipeRepo.FindByIDs(recipeOrder)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting recipes.", c)
+		}
+		byID := map[primitive.ObjectID]*recipeRepo.Recipe{}
+		for i := range *recipes {
+			byID[(*recipes)[i].ID] = &(*recipes)[i]
+		}
+		for _, id := range recipeOrder {
+			recipe, ok := byID[id]
+			if !ok || !recipe.IsVisibleTo(userID) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Recipe "+id.Hex()+" not found!", c)
+			}
+			size := float64(recipe.Servings)
+			if size < 1 {
+				size = 1
+			}
+			agg.addRecipe(recipe, recipeServings[id]+float64(whole[id])*size)
+		}
+	}
+
+	if len(agg.order) == 0 {
+		return []repo.ListItem{}, nil
+	}
+	foods, err := h.foodRepo.FindByIDs(agg.order)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting foods.", c)
+	}
+	return agg.items(foods), nil
+}
+
+// Create
+// @Tags Shopping
+// @Summary Create Shopping List
+// @Description Aggregates the ingredients of a saved meal plan or a selection of recipes, merging identical foods. Recipe servings default to the whole recipe.
+// @ID shopping-create
+// @Router /api/shopping-lists [post]
+// @Accept json
+// @Param body body ShoppingListForm true "shopping list body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) Create(c echo.Context) error {
+	form, err := NewShoppingListForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	items, err := h.collect(c, tokenData.ID, form)
+	if err != nil {
+		return err
+	}
+
+	list := &repo.ShoppingList{
+		ID:        primitive.NewObjectID(),
+		UserID:    tokenData.ID,
+		Name:      form.Name,
+		PlanID:    form.PlanID,
+		Recipes:   form.Recipes,
+		Items:     items,
+		CreatedAt: time.Now(),
+	}
+
+	_, err = h.repo.InsertOne(list)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while creating shopping list.", c)
+	}
+	return c.JSON(http.StatusOK, view(list))
+}
+
+// AddItem
+// @Tags Shopping
+// @Summary Add Manual Item
+// @ID shopping-add-item
+// @Router /api/shopping-lists/{id}/items [post]
+// @Accept json
+// @Param id path string true "Shopping list ID"
+// @Param body body ManualItemForm true "item body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) AddItem(c echo.Context) error {
+	list, err := h.findList(c)
+	if err != nil {
+		return err
+	}
+
+	form, err := NewManualItemForm(c)
+	if err != nil {
+		return err
+	}
+
+	list.Items = append(list.Items, repo.ListItem{
+		ID:       primitive.NewObjectID(),
+		Name:     form.Name,
+		Category: form.Category,
+		Quantity: form.Quantity,
+		Unit:     form.Unit,
+		Manual:   true,
+	})
+	return h.save(c, list)
+}
+
+// CheckItem
+// @Tags Shopping
+// @Summary Check Off Item
+// @ID shopping-check-item
+// @Router /api/shopping-lists/{id}/items/{itemId} [put]
+// @Accept json
+// @Param id path string true "Shopping list ID"
+// @Param itemId path string true "Item ID"
+// @Param body body CheckItemForm true "check body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) CheckItem(c echo.Context) error {
+	list, err := h.findList(c)
+	if err != nil {
+		return err
+	}
+
+	form, err := NewCheckItemForm(c)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := primitive.ObjectIDFromHex(c.Param("itemId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid item id", c)
+	}
+
+	found := false
+	for i := range list.Items {
+		if list.Items[i].ID == itemID {
+			list.Items[i].Checked = form.Checked
+			found = true
+		}
+	}
+	if !found {
+		return echo.NewHTTPError(http.StatusBadRequest, "Item not found!", c)
+	}
+	return h.save(c, list)
+}
+
+// DeleteItem
+// @Tags Shopping
+// @Summary Remove Item
+// @ID shopping-delete-item
+// @Router /api/shopping-lists/{id}/items/{itemId} [delete]
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Param itemId path string true "Item ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) DeleteItem(c echo.Context) error {
+	list, err := h.findList(c)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := primitive.ObjectIDFromHex(c.Param("itemId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid item id", c)
+	}
+
+	items := make([]repo.ListItem, 0, len(list.Items))
+	for _, item := range list.Items {
+		if item.ID != itemID {
+			items = append(items, item)
+		}
+	}
+	if len(items) == len(list.Items) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Item not found!", c)
+	}
+	list.Items = items
+	return h.save(c, list)
+}
+
+// Share
+// @Tags Shopping
+// @Summary Share Shopping List
+// @Description Creates (or returns the existing) unguessable read-only link.
+// @ID shopping-share
+// @Router /api/shopping-lists/{id}/share [post]
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) Share(c echo.Context) error {
+	list, err := h.findList(c)
+	if err != nil {
+		return err
+	}
+
+	if list.ShareToken == "" {
+		token, err := newShareToken()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while sharing shopping list.", c)
+		}
+		list.ShareToken = token
+
+		now := time.Now()
+		list.UpdatedAt = &now
+		if _, err := h.repo.UpdateOne(list); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while sharing shopping list.", c)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"token": list.ShareToken,
+		"path":  fmt.Sprintf("/api/shopping-lists/shared/%s", list.ShareToken),
+	})
+}
+
+// Unshare
+// @Tags Shopping
+// @Summary Revoke Shared Link
+// @ID shopping-unshare
+// @Router /api/shopping-lists/{id}/share [delete]
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) Unshare(c echo.Context) error {
+	list, err := h.findList(c)
+	if err != nil {
+		return err
+	}
+
+	list.ShareToken = ""
+	return h.save(c, list)
+}
+
+// Delete
+// @Tags Shopping
+// @Summary Delete Shopping List
+// @ID shopping-delete
+// @Router /api/shopping-lists/{id} [delete]
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *ShoppingHandler) Delete(c echo.Context) error {
+	list, err := h.findList(c)
+	if err != nil {
+		return err
+	}
+
+	docs, err := h.repo.DeleteOne(list.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting shopping list.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	foodRepo "dietku-backend/cmd/food/repo"
+	planRepo "dietku-backend/cmd/planner/repo"
+	recipeRepo "dietku-backend/cmd/recipe/repo"
+	"dietku-backend/cmd/shopping/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// errEmptyIn stands in for the error Mongo returns for {$in: null}.
+var errEmptyIn = errors.New("$in needs an array")
+
+type stubFoods struct {
+	foods foodRepo.Foods
+	calls int
+}
+
+func (s *stubFoods) FindByIDs(ids []primitive.ObjectID) (*foodRepo.Foods, error) {
+	s.calls++
+	if len(ids) == 0 {
+		return nil, errEmptyIn
+	}
+	return &s.foods, nil
+}
+
+type stubRecipes struct {
+	recipes recipeRepo.Recipes
+	calls   int
+}
+
+func (s *stubRecipes) FindByIDs(ids []primitive.ObjectID) (*recipeRepo.Recipes, error) {
+	s.calls++
+	if len(ids) == 0 {
+		return nil, errEmptyIn
+	}
+	return &s.recipes, nil
+}
+
+type stubPlans map[primitive.ObjectID]*planRepo.MealPlan
+
+func (s stubPlans) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*planRepo.MealPlan, error) {
+	plan, ok := s[id]
+	if !ok || plan.UserID != userID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return plan, nil
+}
+
+func newTestHandler(foods *stubFoods, recipes *stubRecipes, plans stubPlans) *ShoppingHandler {
+	return &ShoppingHandler{foodRepo: foods, recipeRepo: recipes, planRepo: plans}
+}
+
+func newTestContext() echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/api/shopping-lists", nil)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestCollectEmptyPlan(t *testing.T) {
+	userID := primitive.NewObjectID()
+	planID := primitive.NewObjectID()
+	plans := stubPlans{planID: {ID: planID, UserID: userID}}
+	foods, recipes := &stubFoods{}, &stubRecipes{}
+
+	h := newTestHandler(foods, recipes, plans)
+	items, err := h.collect(newTestContext(), userID, &ShoppingListForm{PlanID: &planID})
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if items == nil || len(items) != 0 {
+		t.Errorf("items = %v, want an empty list", items)
+	}
+	if foods.calls != 0 || recipes.calls != 0 {
+		t.Errorf("looked up %d foods and %d recipes for an empty plan", foods.calls, recipes.calls)
+	}
+}
+
+func TestCollectWholeRecipe(t *testing.T) {
+	userID := primitive.NewObjectID()
+	flour := foodRepo.Food{ID: primitive.NewObjectID(), Name: "Flour", Category: "bakery", ServingUnit: foodRepo.UnitGram}
+	recipe := recipeRepo.Recipe{
+		ID:          primitive.NewObjectID(),
+		Name:        "Bread",
+		Servings:    4,
+		Visibility:  recipeRepo.VisibilityPublic,
+		Ingredients: []recipeRepo.Ingredient{{FoodID: flour.ID, Name: "flour", Grams: 400}},
+	}
+
+	planID := primitive.NewObjectID()
+	plans := stubPlans{planID: {
+		ID:     planID,
+		UserID: userID,
+		Days: []planRepo.PlanDay{{Day: 1, Meals: []planRepo.PlanMeal{{
+			Meal:  "breakfast",
+			Items: []planRepo.PlanItem{{Kind: planRepo.KindRecipe, RefID: recipe.ID, Name: recipe.Name, Servings: 2}},
+		}}}},
+	}}
+
+	tests := []struct {
+		name  string
+		form  ShoppingListForm
+		grams float64
+	}{
+		{name: "whole recipe", form: ShoppingListForm{Recipes: []repo.RecipeRef{{RecipeID: recipe.ID}}}, grams: 400},
+		{name: "servings", form: ShoppingListForm{Recipes: []repo.RecipeRef{{RecipeID: recipe.ID, Servings: 1}}}, grams: 100},
+		{name: "whole recipe twice", form: ShoppingListForm{Recipes: []repo.RecipeRef{{RecipeID: recipe.ID}, {RecipeID: recipe.ID}}}, grams: 800},
+		{name: "plan and whole recipe", form: ShoppingListForm{PlanID: &planID, Recipes: []repo.RecipeRef{{RecipeID: recipe.ID}}}, grams: 600},
+		{name: "plan and servings", form: ShoppingListForm{PlanID: &planID, Recipes: []repo.RecipeRef{{RecipeID: recipe.ID, Servings: 1}}}, grams: 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			foods := &stubFoods{foods: foodRepo.Foods{flour}}
+			recipes := &stubRecipes{recipes: recipeRepo.Recipes{recipe}}
+
+			h := newTestHandler(foods, recipes, plans)
+			items, err := h.collect(newTestContext(), userID, &tt.form)
+			if err != nil {
+				t.Fatalf("collect: %v", err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("got %d items, want 1", len(items))
+			}
+			if items[0].Quantity != tt.grams || items[0].Name != flour.Name || items[0].Category != flour.Category {
+				t.Errorf("item = %+v, want %v g of %s", items[0], tt.grams, flour.Name)
+			}
+		})
+	}
+}
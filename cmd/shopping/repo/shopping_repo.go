@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type ListItem struct {
+	ID       primitive.ObjectID  `json:"_id" bson:"_id"`
+	FoodID   *primitive.ObjectID `json:"foodId,omitempty" bson:"foodId,omitempty"`
+	Name     string              `json:"name" bson:"name"`
+	Category string              `json:"category" bson:"category"`
+	Quantity float64             `json:"quantity" bson:"quantity"`
+	Unit     string              `json:"unit" bson:"unit"`
+	Manual   bool                `json:"manual" bson:"manual"`
+	Checked  bool                `json:"checked" bson:"checked"`
+}
+
+type RecipeRef struct {
+	RecipeID primitive.ObjectID `json:"recipeId" bson:"recipeId"`
+	Servings float64            `json:"servings" bson:"servings"`
+}
+
+type ShoppingList struct {
+	ID         primitive.ObjectID  `json:"_id" bson:"_id"`
+	UserID     primitive.ObjectID  `json:"userId" bson:"userId"`
+	Name       string              `json:"name" bson:"name"`
+	PlanID     *primitive.ObjectID `json:"planId,omitempty" bson:"planId,omitempty"`
+	Recipes    []RecipeRef         `json:"recipes,omitempty" bson:"recipes,omitempty"`
+	Items      []ListItem          `json:"items" bson:"items"`
+	ShareToken string              `json:"shareToken,omitempty" bson:"shareToken,omitempty"`
+	CreatedAt  time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  *time.Time          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	IsDeleted  bool                `json:"isDeleted" bson:"isDeleted"`
+}
+
+type ShoppingLists []ShoppingList
+
+func DecodeAsShoppingLists(cursor *mongo.Cursor) (*ShoppingLists, error) {
+	docs := ShoppingLists{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type ShoppingRepository struct {
+	coll *mongo.Collection
+}
+
+func NewShoppingRepository(db *mongo.Database) *ShoppingRepository {
+	return &ShoppingRepository{
+		coll: db.Collection("shopping_lists"),
+	}
+}
+
+func (r *ShoppingRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{
+			Keys: bson.D{{Key: "shareToken", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"shareToken": bson.M{"$exists": true},
+			}),
+		},
+	})
+	return err
+}
+
+func (r *ShoppingRepository) FindByUser(userID primitive.ObjectID) (*ShoppingLists, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.coll.Find(context.TODO(), bson.M{"userId": userID, "isDeleted": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsShoppingLists(cursor)
+}
+
+func (r *ShoppingRepository) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*ShoppingList, error) {
+	var d = &ShoppingList{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *ShoppingRepository) FindByShareToken(token string) (*ShoppingList, error) {
+	var d = &ShoppingList{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"shareToken": token, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *ShoppingRepository) InsertOne(newList *ShoppingList) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newList)
+}
+
+func (r *ShoppingRepository) UpdateOne(list *ShoppingList) (*ShoppingList, error) {
+	filter := bson.M{"_id": list.ID}
+
+	update := bson.M{
+		"$set": list,
+	}
+	// an empty token is omitted from $set, so revoking has to unset it
+	if list.ShareToken == "" {
+		update["$unset"] = bson.M{"shareToken": ""}
+	}
+
+	var d = &ShoppingList{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *ShoppingRepository) DeleteOne(id primitive.ObjectID) (*ShoppingList, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set":   bson.M{"isDeleted": true},
+		"$unset": bson.M{"shareToken": ""},
+	}
+
+	var d = &ShoppingList{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
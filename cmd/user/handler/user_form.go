@@ -1,16 +1,33 @@
 package handler
 
 import (
+	"dietku-backend/cmd/auth/gear"
+	foodRepo "dietku-backend/cmd/food/repo"
+	"dietku-backend/cmd/user/repo"
 	"github.com/asaskevich/govalidator"
 	"github.com/labstack/echo/v4"
+	"math"
 	"net/http"
+	"strings"
 )
 
+const maxDislikes = 50
+
 type UserUpdateForm struct {
 	Email     string `form:"email" json:"email"`
 	Password  string `form:"password" json:"password"`
 	FirstName string `form:"firstName" json:"firstName"`
 	LastName  string `form:"lastName" json:"lastName"`
+	Timezone  string `form:"timezone" json:"timezone"`
+
+	Target      *repo.NutritionTarget `form:"target" json:"target"`
+	Preferences *repo.DietPreferences `form:"preferences" json:"preferences"`
+
+	Weight        float64  `form:"weight" json:"weight"`
+	Height        float64  `form:"height" json:"height"`
+	Sex           string   `form:"sex" json:"sex"`
+	ActivityLevel string   `form:"activityLevel" json:"activityLevel"`
+	WaterTarget   *float64 `form:"waterTarget" json:"waterTarget"`
 }
 
 func NewUserUpdateForm(c echo.Context) (*UserUpdateForm, error) {
@@ -26,5 +43,76 @@ func NewUserUpdateForm(c echo.Context) (*UserUpdateForm, error) {
 	if len(form.Password) > 0 && len(form.Password) < 6 {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Password must be at least 6 characters")
 	}
+
+	if len(form.Timezone) > 0 && !gear.IsValidTimezone(form.Timezone) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid timezone, use an IANA name such as Asia/Jakarta.")
+	}
+
+	if form.Weight < 0 || form.Weight > 500 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Weight must be between 0 and 500 kg.")
+	}
+
+	if form.Height < 0 || form.Height > 300 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Height must be between 0 and 300 cm.")
+	}
+
+	form.Sex = strings.ToLower(strings.TrimSpace(form.Sex))
+	if len(form.Sex) > 0 && !repo.IsValidSex(form.Sex) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Sex must be male or female.")
+	}
+
+	if len(form.ActivityLevel) > 0 && !repo.IsValidActivityLevel(form.ActivityLevel) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Activity level must be one of sedentary, light, moderate, active or very_active.")
+	}
+
+	if form.WaterTarget != nil && (*form.WaterTarget < 0 || *form.WaterTarget > 10000) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Water target must be between 0 and 10000 ml.")
+	}
+
+	if t := form.Target; t != nil {
+		if t.Calories < 800 || t.Calories > 10000 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Target calories must be between 800 and 10000.")
+		}
+		if t.ProteinPercent < 0 || t.CarbohydratePercent < 0 || t.FatPercent < 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Macro percentages must not be negative.")
+		}
+		if math.Abs(t.ProteinPercent+t.CarbohydratePercent+t.FatPercent-100) > 0.5 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Macro percentages must add up to 100.")
+		}
+	}
+
+	if p := form.Preferences; p != nil {
+		if err := validatePreferences(p); err != nil {
+			return nil, err
+		}
+	}
 	return form, nil
 }
+
+func validatePreferences(p *repo.DietPreferences) error {
+	p.Diet = strings.ToLower(strings.TrimSpace(p.Diet))
+	if p.Diet != "" && (p.Diet == foodRepo.DietHalal || !foodRepo.IsValidDiet(p.Diet)) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Diet must be one of vegan, vegetarian, pescatarian or gluten-free.")
+	}
+
+	for i, a := range p.Allergens {
+		p.Allergens[i] = strings.ToLower(strings.TrimSpace(a))
+		if !foodRepo.IsValidAllergen(p.Allergens[i]) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Allergens must be among "+strings.Join(foodRepo.Allergens, ", ")+".")
+		}
+	}
+	p.Allergens = foodRepo.NormalizeAllergens(p.Allergens)
+
+	dislikes := []string{}
+	for _, d := range p.Dislikes {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" {
+			dislikes = append(dislikes, d)
+		}
+	}
+	if len(dislikes) > maxDislikes {
+		return echo.NewHTTPError(http.StatusBadRequest, "At most 50 disliked ingredients are allowed.")
+	}
+	p.Dislikes = dislikes
+	return nil
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/event"
 	"dietku-backend/cmd/user/repo"
 	"errors"
 	"github.com/labstack/echo/v4"
@@ -82,6 +83,32 @@ func (h *UserHandler) UpdateMe(c echo.Context) error {
 	if updateParam.Password != "" {
 		meData.Password = updateParam.Password
 	}
+	if updateParam.Timezone != "" {
+		meData.Timezone = updateParam.Timezone
+	}
+	if updateParam.Target != nil {
+		meData.Target = updateParam.Target
+	}
+	if updateParam.Preferences != nil {
+		meData.Preferences = updateParam.Preferences
+	}
+	previousWeight := meData.Weight
+	if updateParam.Weight > 0 {
+		meData.Weight = updateParam.Weight
+	}
+	if updateParam.Height > 0 {
+		meData.Height = updateParam.Height
+	}
+	if updateParam.Sex != "" {
+		meData.Sex = updateParam.Sex
+	}
+	if updateParam.ActivityLevel != "" {
+		meData.ActivityLevel = updateParam.ActivityLevel
+	}
+	// 0 clears the manual override and falls back to the calculated target
+	if updateParam.WaterTarget != nil {
+		meData.WaterTarget = *updateParam.WaterTarget
+	}
 
 	checkEmail, err := h.repo.FindOneByEmail(meData.Email)
 	if err == nil && checkEmail.ID != meData.ID {
@@ -92,5 +119,8 @@ func (h *UserHandler) UpdateMe(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while updating user.", c)
 	}
+	if meData.Weight != previousWeight {
+		event.Publish(event.Event{Type: event.WeightLogged, UserID: meData.ID, Value: previousWeight})
+	}
 	return c.JSON(http.StatusOK, result)
 }
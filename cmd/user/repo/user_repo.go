@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	foodRepo "dietku-backend/cmd/food/repo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -10,15 +11,174 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `json:"_id" bson:"_id"`
-	Email     string             `json:"email" bson:"email"`
-	FirstName string             `json:"firstName" bson:"firstName"`
-	LastName  string             `json:"lastName" bson:"lastName"`
-	BirthDay  string             `json:"birthDay" bson:"birthDay"`
-	Phone     string             `json:"phone" bson:"phone"`
-	Password  string             `json:"password" bson:"password"`
-	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
-	IsDeleted bool               `json:"isDeleted" bson:"isDeleted"`
+	ID            primitive.ObjectID `json:"_id" bson:"_id"`
+	Email         string             `json:"email" bson:"email"`
+	FirstName     string             `json:"firstName" bson:"firstName"`
+	LastName      string             `json:"lastName" bson:"lastName"`
+	BirthDay      string             `json:"birthDay" bson:"birthDay"`
+	Phone         string             `json:"phone" bson:"phone"`
+	Password      string             `json:"password" bson:"password"`
+	Timezone      string             `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	Target        *NutritionTarget   `json:"target,omitempty" bson:"target,omitempty"`
+	Weight        float64            `json:"weight,omitempty" bson:"weight,omitempty"`
+	Height        float64            `json:"height,omitempty" bson:"height,omitempty"`
+	Sex           string             `json:"sex,omitempty" bson:"sex,omitempty"`
+	ActivityLevel string             `json:"activityLevel,omitempty" bson:"activityLevel,omitempty"`
+	WaterTarget   float64            `json:"waterTarget,omitempty" bson:"waterTarget"`
+	Preferences   *DietPreferences   `json:"preferences,omitempty" bson:"preferences,omitempty"`
+	Role          string             `json:"role,omitempty" bson:"role,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	IsDeleted     bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+// Roles are granted in the database; users cannot change their own.
+const (
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// IsModerator reports whether u may moderate content of other users. Admins
+// are moderators too.
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+const (
+	SexMale   = "male"
+	SexFemale = "female"
+)
+
+func IsValidSex(sex string) bool {
+	return sex == SexMale || sex == SexFemale
+}
+
+// Age returns the user's age in whole years at now. ok is false when the birth
+// day is missing or not in YYYY-MM-DD form.
+func (u *User) Age(now time.Time) (age int, ok bool) {
+	born, err := time.Parse("2006-01-02", u.BirthDay)
+	if err != nil || born.After(now) {
+		return 0, false
+	}
+	age = now.Year() - born.Year()
+	if now.Month() < born.Month() || (now.Month() == born.Month() && now.Day() < born.Day()) {
+		age--
+	}
+	return age, true
+}
+
+// DietPreferences describe what the user eats. Diet is one of the food diet
+// tags other than halal, which has its own flag; Dislikes are ingredient names.
+type DietPreferences struct {
+	Diet      string   `json:"diet,omitempty" bson:"diet,omitempty"`
+	Allergens []string `json:"allergens" bson:"allergens"`
+	Dislikes  []string `json:"dislikes" bson:"dislikes"`
+	Halal     bool     `json:"halal" bson:"halal"`
+}
+
+// RequiredDiets lists the diet tags a food must carry for these preferences.
+func (p *DietPreferences) RequiredDiets() []string {
+	diets := []string{}
+	if p.Diet != "" {
+		diets = append(diets, p.Diet)
+	}
+	if p.Halal {
+		diets = append(diets, foodRepo.DietHalal)
+	}
+	return diets
+}
+
+// FoodPreferences turns the preferences into a listing filter. It returns nil
+// when there is nothing to filter by.
+func (p *DietPreferences) FoodPreferences() *foodRepo.Preferences {
+	if p == nil {
+		return nil
+	}
+	return &foodRepo.Preferences{
+		Allergens: p.Allergens,
+		Diets:     p.RequiredDiets(),
+		Dislikes:  p.Dislikes,
+	}
+}
+
+// NutritionTarget is the user's daily energy budget and how it should be split
+// across macros, as percentages of calories.
+type NutritionTarget struct {
+	Calories            float64 `json:"calories" bson:"calories"`
+	ProteinPercent      float64 `json:"proteinPercent" bson:"proteinPercent"`
+	CarbohydratePercent float64 `json:"carbohydratePercent" bson:"carbohydratePercent"`
+	FatPercent          float64 `json:"fatPercent" bson:"fatPercent"`
+}
+
+var DefaultNutritionTarget = NutritionTarget{
+	Calories:            2000,
+	ProteinPercent:      20,
+	CarbohydratePercent: 50,
+	FatPercent:          30,
+}
+
+// ProteinGrams converts the protein share of the budget into grams (4 kcal/g).
+func (t NutritionTarget) ProteinGrams() float64 {
+	return t.Calories * t.ProteinPercent / 100 / 4
+}
+
+// CarbohydrateGrams converts the carbohydrate share into grams (4 kcal/g).
+func (t NutritionTarget) CarbohydrateGrams() float64 {
+	return t.Calories * t.CarbohydratePercent / 100 / 4
+}
+
+// FatGrams converts the fat share into grams (9 kcal/g).
+func (t NutritionTarget) FatGrams() float64 {
+	return t.Calories * t.FatPercent / 100 / 9
+}
+
+// NutritionTarget returns the user's target or DefaultNutritionTarget when the
+// user has not set one.
+func (u *User) NutritionTarget() NutritionTarget {
+	if u.Target == nil {
+		return DefaultNutritionTarget
+	}
+	return *u.Target
+}
+
+const (
+	ActivitySedentary  = "sedentary"
+	ActivityLight      = "light"
+	ActivityModerate   = "moderate"
+	ActivityActive     = "active"
+	ActivityVeryActive = "very_active"
+)
+
+// activityWater is the extra water in ml per activity level on top of the
+// weight based baseline.
+var activityWater = map[string]float64{
+	ActivitySedentary:  0,
+	ActivityLight:      250,
+	ActivityModerate:   500,
+	ActivityActive:     750,
+	ActivityVeryActive: 1000,
+}
+
+func IsValidActivityLevel(level string) bool {
+	_, ok := activityWater[level]
+	return ok
+}
+
+// DailyWaterTarget returns the user's daily water goal in ml and whether it was
+// set manually. Otherwise it is 35 ml per kg of body weight (2000 ml when the
+// weight is unknown) plus an allowance for the activity level.
+func (u *User) DailyWaterTarget() (float64, bool) {
+	if u.WaterTarget > 0 {
+		return u.WaterTarget, true
+	}
+	target := 2000.0
+	if u.Weight > 0 {
+		target = u.Weight * 35
+	}
+	return target + activityWater[u.ActivityLevel], false
 }
 
 type Users []User
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// unitMl is how many ml one of each accepted unit holds.
+var unitMl = map[string]float64{
+	"ml":    1,
+	"l":     1000,
+	"oz":    29.5735,
+	"glass": 250,
+}
+
+type WaterForm struct {
+	Quantity float64    `json:"quantity"`
+	Unit     string     `json:"unit"`
+	LoggedAt *time.Time `json:"loggedAt"`
+}
+
+func NewWaterForm(c echo.Context) (*WaterForm, error) {
+	form := new(WaterForm)
+	if err := c.Bind(form); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form: "+err.Error())
+	}
+
+	form.Unit = strings.ToLower(strings.TrimSpace(form.Unit))
+	if form.Unit == "" {
+		form.Unit = "ml"
+	}
+	if _, ok := unitMl[form.Unit]; !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unit must be one of ml, l, oz or glass.")
+	}
+
+	if form.Quantity <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Quantity must be greater than 0.")
+	}
+
+	if form.Quantity*unitMl[form.Unit] > 5000 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "A single log cannot exceed 5 l.")
+	}
+
+	if form.LoggedAt != nil && form.LoggedAt.After(time.Now().Add(time.Minute)) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "LoggedAt cannot be in the future.")
+	}
+	return form, nil
+}
+
+// Amount returns the logged quantity in ml.
+func (f *WaterForm) Amount() float64 {
+	return f.Quantity * unitMl[f.Unit]
+}
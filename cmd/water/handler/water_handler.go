@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"dietku-backend/cmd/auth/gear"
+	"dietku-backend/cmd/log"
+	userRepo "dietku-backend/cmd/user/repo"
+	"dietku-backend/cmd/water/repo"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHistoryDays = 30
+	maxHistoryDays     = 366
+	// streakWindow is how far back streaks are looked for.
+	streakWindow = 365
+)
+
+type WaterHandler struct {
+	repo     *repo.WaterRepository
+	userRepo *userRepo.UserRepository
+}
+
+func NewWaterApi(e *echo.Echo, db *mongo.Database) *WaterHandler {
+	w := &WaterHandler{
+		repo:     repo.NewWaterRepository(db),
+		userRepo: userRepo.NewUserRepository(db),
+	}
+	if err := w.repo.EnsureIndexes(); err != nil {
+		log.Errorf("failed to create water indexes: %v", err)
+	}
+
+	wGroup := e.Group("")
+	wGroup.Use(gear.IsLoggedIn(db))
+	{
+		wGroup.GET("/api/water/today", w.Today)
+		wGroup.GET("/api/water/history", w.History)
+
+		wGroup.POST("/api/water", w.Create)
+
+		wGroup.DELETE("/api/water/:id", w.Delete)
+	}
+	return w
+}
+
+type WaterTarget struct {
+	Amount float64 `json:"amount"`
+	Manual bool    `json:"manual"`
+}
+
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+type WaterToday struct {
+	Date      string          `json:"date"`
+	Timezone  string          `json:"timezone"`
+	Target    WaterTarget     `json:"target"`
+	Total     float64         `json:"total"`
+	Remaining float64         `json:"remaining"`
+	Percent   float64         `json:"percent"`
+	Logs      *repo.WaterLogs `json:"logs"`
+	Streak    Streak          `json:"streak"`
+}
+
+type WaterDay struct {
+	repo.DayTotal
+	Met bool `json:"met"`
+}
+
+type WaterHistory struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	Timezone string      `json:"timezone"`
+	Target   WaterTarget `json:"target"`
+	Days     []WaterDay  `json:"days"`
+	Streak   Streak      `json:"streak"`
+}
+
+// streak counts consecutive days reaching target. The current streak may end
+// yesterday, since today is not over yet; longest is the best run from start.
+func streak(totals []repo.DayTotal, target float64, start time.Time, today time.Time) Streak {
+	met := map[string]bool{}
+	for _, t := range totals {
+		met[t.Date] = t.Amount >= target
+	}
+
+	s := Streak{}
+	run := 0
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		if met[day.Format(gear.DateLayout)] {
+			run++
+			if run > s.Longest {
+				s.Longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	day := today
+	if !met[day.Format(gear.DateLayout)] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for ; !day.Before(start) && met[day.Format(gear.DateLayout)]; day = day.AddDate(0, 0, -1) {
+		s.Current++
+	}
+	return s
+}
+
+func (h *WaterHandler) target(c echo.Context, userID primitive.ObjectID) (WaterTarget, error) {
+	user, err := h.userRepo.FindOne(userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return WaterTarget{}, echo.NewHTTPError(http.StatusBadRequest, "User not found!", c)
+		}
+		return WaterTarget{}, echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting user.", c)
+	}
+	amount, manual := user.DailyWaterTarget()
+	return WaterTarget{Amount: amount, Manual: manual}, nil
+}
+
+// Today
+// @Tags Water
+// @Summary Get Today's Water Intake
+// @Description Today's logs and progress towards the daily target, in the user's time zone. The target is derived from body weight and activity level unless set manually.
+// @ID water-today
+// @Router /api/water/today [get]
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *WaterHandler) Today(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	date, start, _, _ := gear.ParseDay("today", loc)
+
+	target, err := h.target(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	logs, err := h.repo.FindByDate(tokenData.ID, date)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting water logs.", c)
+	}
+
+	windowStart := start.AddDate(0, 0, -streakWindow)
+	totals, err := h.repo.DailyTotals(tokenData.ID, windowStart.Format(gear.DateLayout), date)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting water logs.", c)
+	}
+
+	today := &WaterToday{
+		Date:     date,
+		Timezone: loc.String(),
+		Target:   target,
+		Logs:     logs,
+		Streak:   streak(totals, target.Amount, windowStart, start),
+	}
+	for _, l := range *logs {
+		today.Total += l.Amount
+	}
+	today.Remaining = target.Amount - today.Total
+	if today.Remaining < 0 {
+		today.Remaining = 0
+	}
+	if target.Amount > 0 {
+		today.Percent = today.Total / target.Amount * 100
+	}
+	return c.JSON(http.StatusOK, today)
+}
+
+// History
+// @Tags Water
+// @Summary Get Water History
+// @Description Daily totals between from and to (default the last 30 days) and whether the target was met.
+// @ID water-history
+// @Router /api/water/history [get]
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *WaterHandler) History(c echo.Context) error {
+	tokenData := c.Get("me").(*gear.UserClaims)
+	loc := tokenData.Location()
+
+	r, err := gear.ParseRange(c, loc, defaultHistoryDays, maxHistoryDays)
+	if err != nil {
+		return err
+	}
+	from, to := r.From, r.To
+
+	target, err := h.target(c, tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	totals, err := h.repo.DailyTotals(tokenData.ID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting water logs.", c)
+	}
+
+	byDate := map[string]repo.DayTotal{}
+	for _, t := range totals {
+		byDate[t.Date] = t
+	}
+
+	history := &WaterHistory{
+		From:     from,
+		To:       to,
+		Timezone: loc.String(),
+		Target:   target,
+		Days:     []WaterDay{},
+		Streak:   streak(totals, target.Amount, r.FromStart, r.ToStart),
+	}
+	for day := r.FromStart; !day.After(r.ToStart); day = day.AddDate(0, 0, 1) {
+		date := day.Format(gear.DateLayout)
+		t, ok := byDate[date]
+		if !ok {
+			t = repo.DayTotal{Date: date}
+		}
+		history.Days = append(history.Days, WaterDay{DayTotal: t, Met: t.Amount >= target.Amount})
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+// Create
+// @Tags Water
+// @Summary Log Water
+// @Description Accepts ml, l, oz or glass (250 ml). The day is taken from loggedAt (default now) in the user's time zone.
+// @ID water-create
+// @Router /api/water [post]
+// @Accept json
+// @Param body body WaterForm true "water body"
+// @Produce json
+// @Success 200
+// @Security ApiKeyAuth
+func (h *WaterHandler) Create(c echo.Context) error {
+	form, err := NewWaterForm(c)
+	if err != nil {
+		return err
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	at := time.Now()
+	if form.LoggedAt != nil {
+		at = *form.LoggedAt
+	}
+
+	w := &repo.WaterLog{
+		ID:        primitive.NewObjectID(),
+		UserID:    tokenData.ID,
+		Date:      at.In(tokenData.Location()).Format(gear.DateLayout),
+		Amount:    form.Amount(),
+		Quantity:  form.Quantity,
+		Unit:      form.Unit,
+		LoggedAt:  at,
+		CreatedAt: time.Now(),
+	}
+
+	_, err = h.repo.InsertOne(w)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while logging water.", c)
+	}
+	return c.JSON(http.StatusOK, w)
+}
+
+// Delete
+// @Tags Water
+// @Summary Delete Water Log
+// @ID water-delete
+// @Router /api/water/{id} [delete]
+// @Produce json
+// @Param id path string true "Water log ID"
+// @Success 200
+// @Security ApiKeyAuth
+func (h *WaterHandler) Delete(c echo.Context) error {
+	oId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid water log id", c)
+	}
+
+	tokenData := c.Get("me").(*gear.UserClaims)
+
+	w, err := h.repo.FindOne(oId, tokenData.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Water log not found!", c)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while getting water log.", c)
+	}
+
+	docs, err := h.repo.DeleteOne(w.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "An error occurred while deleting water log.", c)
+	}
+	return c.JSON(http.StatusOK, docs)
+}
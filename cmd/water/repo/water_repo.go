@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// WaterLog is one drink. Amount is always in ml; Quantity and Unit keep what
+// the user entered.
+type WaterLog struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Date      string             `json:"date" bson:"date"`
+	Amount    float64            `json:"amount" bson:"amount"`
+	Quantity  float64            `json:"quantity" bson:"quantity"`
+	Unit      string             `json:"unit" bson:"unit"`
+	LoggedAt  time.Time          `json:"loggedAt" bson:"loggedAt"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	IsDeleted bool               `json:"isDeleted" bson:"isDeleted"`
+}
+
+type WaterLogs []WaterLog
+
+func DecodeAsWaterLogs(cursor *mongo.Cursor) (*WaterLogs, error) {
+	docs := WaterLogs{}
+	err := cursor.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+type DayTotal struct {
+	Date   string  `json:"date" bson:"date"`
+	Amount float64 `json:"amount" bson:"amount"`
+	Count  int     `json:"count" bson:"count"`
+}
+
+type WaterRepository struct {
+	coll *mongo.Collection
+}
+
+func NewWaterRepository(db *mongo.Database) *WaterRepository {
+	return &WaterRepository{
+		coll: db.Collection("water_logs"),
+	}
+}
+
+func (r *WaterRepository) EnsureIndexes() error {
+	_, err := r.coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}},
+	})
+	return err
+}
+
+func (r *WaterRepository) FindByDate(userID primitive.ObjectID, date string) (*WaterLogs, error) {
+	filter := bson.M{"userId": userID, "date": date, "isDeleted": bson.M{"$ne": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "loggedAt", Value: 1}})
+	cursor, err := r.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAsWaterLogs(cursor)
+}
+
+func (r *WaterRepository) FindOne(id primitive.ObjectID, userID primitive.ObjectID) (*WaterLog, error) {
+	var d = &WaterLog{}
+	err := r.coll.FindOne(context.TODO(), bson.M{"_id": id, "userId": userID, "isDeleted": bson.M{"$ne": true}}).Decode(d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DailyTotals sums the water drunk per day between from and to (inclusive).
+// Days without logs are not returned.
+func (r *WaterRepository) DailyTotals(userID primitive.ObjectID, from string, to string) ([]DayTotal, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{
+			"userId":    userID,
+			"date":      bson.M{"$gte": from, "$lte": to},
+			"isDeleted": bson.M{"$ne": true},
+		}},
+		bson.M{"$group": bson.M{"_id": "$date", "amount": bson.M{"$sum": "$amount"}, "count": bson.M{"$sum": 1}}},
+		bson.M{"$project": bson.M{"_id": 0, "date": "$_id", "amount": 1, "count": 1}},
+		bson.M{"$sort": bson.M{"date": 1}},
+	}
+
+	cursor, err := r.coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []DayTotal{}
+	if err := cursor.All(context.TODO(), &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *WaterRepository) InsertOne(newLog *WaterLog) (*mongo.InsertOneResult, error) {
+	return r.coll.InsertOne(context.TODO(), newLog)
+}
+
+func (r *WaterRepository) DeleteOne(id primitive.ObjectID) (*WaterLog, error) {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{"isDeleted": true},
+	}
+
+	var d = &WaterLog{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.coll.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"sync"
+	"time"
+)
+
+// Lock is a lease kept in the "locks" collection. Whoever holds the document
+// for a name is the leader for it until the lease expires, so only one
+// instance runs a worker even when several are deployed.
+type Lock struct {
+	coll  *mongo.Collection
+	owner string
+}
+
+func NewLock(db *mongo.Database) *Lock {
+	return &Lock{
+		coll:  db.Collection("locks"),
+		owner: owner(),
+	}
+}
+
+// owner identifies this process, e.g. "api-7d9f-12-3fa1c2d4".
+func owner() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// Acquire takes or renews the lease on name until now+ttl. It reports false
+// when another owner holds a lease that has not expired.
+func (l *Lock) Acquire(name string, now time.Time, ttl time.Duration) (bool, error) {
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": l.owner},
+			bson.M{"expiresAt": bson.M{"$lte": now}},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"owner": l.owner, "expiresAt": now.Add(ttl)},
+	}
+
+	// a lease held by someone else does not match the filter, so the upsert
+	// tries to insert a second document with the same _id and fails
+	_, err := l.coll.UpdateOne(context.TODO(), filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release gives up the lease on name if this process holds it.
+func (l *Lock) Release(name string) error {
+	_, err := l.coll.DeleteOne(context.TODO(), bson.M{"_id": name, "owner": l.owner})
+	return err
+}
+
+// MemoryLocks keeps leases in memory, for running several contenders within
+// one process as tests do. Each contender gets its own Leaser from For.
+type MemoryLocks struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+}
+
+type memoryLease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+func NewMemoryLocks() *MemoryLocks {
+	return &MemoryLocks{leases: map[string]memoryLease{}}
+}
+
+// For returns the Leaser of owner.
+func (m *MemoryLocks) For(owner string) Leaser {
+	return &memoryLock{locks: m, owner: owner}
+}
+
+type memoryLock struct {
+	locks *MemoryLocks
+	owner string
+}
+
+func (l *memoryLock) Acquire(name string, now time.Time, ttl time.Duration) (bool, error) {
+	l.locks.mu.Lock()
+	defer l.locks.mu.Unlock()
+
+	if lease, ok := l.locks.leases[name]; ok && lease.owner != l.owner && lease.expiresAt.After(now) {
+		return false, nil
+	}
+	l.locks.leases[name] = memoryLease{owner: l.owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (l *memoryLock) Release(name string) error {
+	l.locks.mu.Lock()
+	defer l.locks.mu.Unlock()
+
+	if l.locks.leases[name].owner == l.owner {
+		delete(l.locks.leases, name)
+	}
+	return nil
+}
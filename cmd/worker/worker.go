@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"dietku-backend/cmd/log"
+	"time"
+)
+
+// Leaser hands out the leadership of a worker. Lock is the one shared by all
+// instances; tests may use an in-memory one.
+type Leaser interface {
+	Acquire(name string, now time.Time, ttl time.Duration) (bool, error)
+	Release(name string) error
+}
+
+// Worker runs a job every Interval on the instance holding its lock.
+type Worker struct {
+	Name     string
+	Interval time.Duration
+	Clock    Clock
+	Lock     Leaser
+	Job      func(now time.Time) error
+}
+
+// Start runs the worker until ctx is done. It returns immediately; the loop
+// runs in the background and outlives a tick that panics.
+func (w *Worker) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+
+		for {
+			w.Tick()
+			select {
+			case <-ctx.Done():
+				if err := w.Lock.Release(w.Name); err != nil {
+					log.Errorf("failed to release %s lock: %v", w.Name, err)
+				}
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Tick runs the job once if this instance is, or becomes, the leader. The
+// lease outlives a few intervals so a missed tick does not hand it over, while
+// a crashed leader is replaced soon after. A panic is logged and ends only
+// this tick.
+func (w *Worker) Tick() {
+	defer log.RecoverWithTrace()
+
+	now := w.Clock.Now()
+
+	leader, err := w.Lock.Acquire(w.Name, now, 3*w.Interval)
+	if err != nil {
+		log.Errorf("failed to acquire %s lock: %v", w.Name, err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	if err := w.Job(now); err != nil {
+		log.Errorf("%s worker failed: %v", w.Name, err)
+	}
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"dietku-backend/cmd/log"
+	"github.com/labstack/echo/v4"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetLogger(echo.New())
+	os.Exit(m.Run())
+}
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newWorker(clock Clock, lock Leaser, runs *int) *Worker {
+	return &Worker{
+		Name:     "test",
+		Interval: 30 * time.Second,
+		Clock:    clock,
+		Lock:     lock,
+		Job: func(time.Time) error {
+			*runs++
+			return nil
+		},
+	}
+}
+
+func TestTickRunsOnlyOnTheLeader(t *testing.T) {
+	clock := NewFakeClock(start)
+	locks := NewMemoryLocks()
+	var aRuns, bRuns int
+	a := newWorker(clock, locks.For("a"), &aRuns)
+	b := newWorker(clock, locks.For("b"), &bRuns)
+
+	// both tick every interval: a got the lease first and keeps renewing it
+	for i := 0; i < 10; i++ {
+		a.Tick()
+		b.Tick()
+		clock.Advance(a.Interval)
+	}
+	if aRuns != 10 || bRuns != 0 {
+		t.Fatalf("runs a=%d b=%d, want 10 and 0", aRuns, bRuns)
+	}
+
+	// a stops; b takes over once the lease of three intervals has run out
+	renewed := clock.Now().Add(-a.Interval)
+	for clock.Now().Before(renewed.Add(3 * a.Interval)) {
+		b.Tick()
+		if bRuns != 0 {
+			t.Fatalf("b ran at %v while a's lease from %v was valid", clock.Now(), renewed)
+		}
+		clock.Advance(a.Interval)
+	}
+	b.Tick()
+	if bRuns != 1 {
+		t.Fatalf("b did not take over an expired lease")
+	}
+
+	// and a, coming back, has to wait
+	a.Tick()
+	if aRuns != 10 {
+		t.Errorf("a ran while b held the lease")
+	}
+}
+
+func TestReleaseHandsOverAtOnce(t *testing.T) {
+	clock := NewFakeClock(start)
+	locks := NewMemoryLocks()
+	var aRuns, bRuns int
+	a := newWorker(clock, locks.For("a"), &aRuns)
+	b := newWorker(clock, locks.For("b"), &bRuns)
+
+	a.Tick()
+	if err := b.Lock.Release(b.Name); err != nil {
+		t.Fatal(err)
+	}
+	b.Tick()
+	if bRuns != 0 {
+		t.Fatalf("b released a lease it did not hold")
+	}
+
+	if err := a.Lock.Release(a.Name); err != nil {
+		t.Fatal(err)
+	}
+	b.Tick()
+	if bRuns != 1 {
+		t.Fatalf("b did not get the released lease")
+	}
+}
+
+func TestTickSurvivesPanic(t *testing.T) {
+	clock := NewFakeClock(start)
+	runs := 0
+	w := newWorker(clock, NewMemoryLocks().For("a"), &runs)
+	job := w.Job
+	w.Job = func(now time.Time) error {
+		if runs == 0 {
+			runs++
+			panic("job failed")
+		}
+		return job(now)
+	}
+
+	w.Tick()
+	w.Tick()
+	if runs != 2 {
+		t.Fatalf("runs = %d, want the tick after the panic to run", runs)
+	}
+}
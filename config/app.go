@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/joho/godotenv"
 	"os"
+	"strings"
 )
 
 // Config is a struct to store configuration from .env file
@@ -18,6 +19,11 @@ type Config struct {
 	StateString        string `mapstructure:"OAUTH_STATE_STRING"`
 	GoogleClientID     string `mapstructure:"GOOGLE_CLIENT_ID"`
 	GoogleClientSecret string `mapstructure:"GOOGLE_CLIENT_SECRET"`
+	FoodResolverURL    string `mapstructure:"FOOD_RESOLVER_URL"`
+	ExerciseAddBack    bool   `mapstructure:"EXERCISE_ADD_BACK"`
+	StorageDir         string `mapstructure:"STORAGE_DIR"`
+	SiteURL            string `mapstructure:"SITE_URL"`
+	APIURL             string `mapstructure:"API_URL"`
 }
 
 // InitConfigApp loads configuration from .env file
@@ -39,6 +45,17 @@ func InitConfigApp(path string) (*Config, error) {
 	config.StateString = os.Getenv("OAUTH_STATE_STRING")
 	config.GoogleClientID = os.Getenv("GOOGLE_CLIENT_ID")
 	config.GoogleClientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
+	config.FoodResolverURL = os.Getenv("FOOD_RESOLVER_URL")
+	config.ExerciseAddBack = os.Getenv("EXERCISE_ADD_BACK") == "true"
+	config.StorageDir = os.Getenv("STORAGE_DIR")
+	if config.StorageDir == "" {
+		config.StorageDir = "uploads"
+	}
+	config.SiteURL = strings.TrimSuffix(os.Getenv("SITE_URL"), "/")
+	config.APIURL = strings.TrimSuffix(os.Getenv("API_URL"), "/")
+	if config.APIURL == "" && config.SwaggerHost != "" {
+		config.APIURL = "https://" + config.SwaggerHost
+	}
 
 	if config.DBUrl == "" {
 		return &Config{}, errors.New("please check your database setting")
@@ -1,10 +1,29 @@
 package main
 
 import (
+	handlerAchievement "dietku-backend/cmd/achievement/handler"
 	handlerAuth "dietku-backend/cmd/auth/handler"
 	handlerBlog "dietku-backend/cmd/blog/handler"
+	handlerBody "dietku-backend/cmd/body/handler"
+	handlerComment "dietku-backend/cmd/comment/handler"
+	handlerDiary "dietku-backend/cmd/diary/handler"
+	handlerExercise "dietku-backend/cmd/exercise/handler"
+	handlerFasting "dietku-backend/cmd/fasting/handler"
+	handlerFeed "dietku-backend/cmd/feed/handler"
+	handlerFood "dietku-backend/cmd/food/handler"
+	"dietku-backend/cmd/food/resolver"
 	"dietku-backend/cmd/log"
+	handlerMedia "dietku-backend/cmd/media/handler"
+	notificationGear "dietku-backend/cmd/notification/gear"
+	handlerNotification "dietku-backend/cmd/notification/handler"
+	handlerPlanner "dietku-backend/cmd/planner/handler"
+	handlerRecipe "dietku-backend/cmd/recipe/handler"
+	reminderGear "dietku-backend/cmd/reminder/gear"
+	handlerReminder "dietku-backend/cmd/reminder/handler"
+	handlerShopping "dietku-backend/cmd/shopping/handler"
+	"dietku-backend/cmd/storage"
 	handlerUser "dietku-backend/cmd/user/handler"
+	handlerWater "dietku-backend/cmd/water/handler"
 	"dietku-backend/config"
 	"dietku-backend/docs"
 	"dietku-backend/version"
@@ -77,6 +96,28 @@ func main() {
 	handlerAuth.NewAuthHandler(e, db, conf)
 	handlerUser.NewUserApi(e, db)
 	handlerBlog.NewBlogApi(e, db)
+	handlerComment.NewCommentApi(e, db)
+	handlerFeed.NewFeedApi(e, db, conf)
+
+	var foodResolver resolver.Resolver
+	if conf.FoodResolverURL != "" {
+		foodResolver = resolver.NewOpenFoodFacts(conf.FoodResolverURL)
+	}
+	handlerFood.NewFoodApi(e, db, foodResolver)
+	handlerRecipe.NewRecipeApi(e, db)
+	handlerDiary.NewDiaryApi(e, db, conf)
+	handlerPlanner.NewPlannerApi(e, db)
+	handlerShopping.NewShoppingApi(e, db)
+	handlerWater.NewWaterApi(e, db)
+	handlerExercise.NewExerciseApi(e, db)
+	handlerFasting.NewFastingApi(e, db)
+
+	store := storage.NewLocal(conf.StorageDir)
+	handlerBody.NewBodyApi(e, db, store)
+	handlerMedia.NewMediaApi(e, db, store)
+	handlerAchievement.NewAchievementApi(e, db)
+	handlerNotification.NewNotificationApi(e, db)
+	handlerReminder.NewReminderApi(e, db, reminderGear.NewNotificationDispatcher(notificationGear.NewNotifier(db)))
 
 	server := fmt.Sprintf("%v:3000", conf.AppHost)
 	if conf.AppPort != "" {